@@ -39,8 +39,14 @@ trap cleanup EXIT
 # ref: https://jonalmeida.com/posts/2013/05/26/different-ways-to-implement-flags-in-bash/
 # ref: http://tldp.org/LDP/abs/html/comparison-ops.html
 
+# directory of the manifests applied by this script
+export KUBEDB_DEPLOY_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)
+
 export KUBEDB_NAMESPACE=kube-system
 export KUBEDB_SERVICE_ACCOUNT=default
+# service account of KubeDB operator, in KUBEDB_NAMESPACE. kubedb-server allows only it to write the objects
+# KubeDB operator manages, eg: the status and reserved labels of databases, and the PVCs of live databases.
+export KUBEDB_OPERATOR_SERVICE_ACCOUNT=kubedb-operator
 export KUBEDB_ENABLE_RBAC=false
 export KUBEDB_UNINSTALL=0
 
@@ -51,7 +57,8 @@ show_help() {
     echo " "
     echo "options:"
     echo "-h, --help                         show brief help"
-    echo "-n, --namespace=NAMESPACE          specify namespace (default: kube-system)"
+    echo "-n, --namespace=NAMESPACE          specify namespace of kubedb-server and KubeDB operator (default: kube-system)"
+    echo "    --operator-service-account=NAME  specify service account of KubeDB operator (default: kubedb-operator)"
     echo "    --rbac                         create RBAC roles and bindings"
     echo "    --uninstall                    uninstall kubedb"
 }
@@ -76,6 +83,10 @@ while test $# -gt 0; do
             export KUBEDB_NAMESPACE=`echo $1 | sed -e 's/^[^=]*=//g'`
             shift
             ;;
+        --operator-service-account*)
+            export KUBEDB_OPERATOR_SERVICE_ACCOUNT=`echo $1 | sed -e 's/^[^=]*=//g'`
+            shift
+            ;;
         --rbac)
             export KUBEDB_SERVICE_ACCOUNT=kubedb-server
             export KUBEDB_ENABLE_RBAC=true
@@ -123,12 +134,14 @@ export TLS_SERVING_KEY=$(cat server.key | $ONESSL base64)
 export KUBE_CA=$($ONESSL get kube-ca | $ONESSL base64)
 rm -rf $ONESSL ca.crt ca.key server.crt server.key
 
-curl -fsSL https://raw.githubusercontent.com/kubedb/apiserver/master/hack/deploy/operator.yaml | $ONESSL envsubst | kubectl apply -f -
+# exempt the namespaces of the control plane and of kubedb-server from the Secret and ConfigMap webhooks
+kubectl label namespace kube-system admission.kubedb.com/ignore-offshoots=true --overwrite
+kubectl label namespace $KUBEDB_NAMESPACE admission.kubedb.com/ignore-offshoots=true --overwrite
+
+cat $KUBEDB_DEPLOY_DIR/operator.yaml | $ONESSL envsubst | kubectl apply -f -
 
 if [ "$KUBEDB_ENABLE_RBAC" = true ]; then
     kubectl create serviceaccount $KUBEDB_SERVICE_ACCOUNT --namespace $KUBEDB_NAMESPACE
     kubectl label serviceaccount $KUBEDB_SERVICE_ACCOUNT app=kubedb --namespace $KUBEDB_NAMESPACE
-    curl -fsSL https://raw.githubusercontent.com/kubedb/apiserver/master/hack/deploy/rbac-list.yaml | $ONESSL envsubst | kubectl auth reconcile -f -
+    cat $KUBEDB_DEPLOY_DIR/rbac-list.yaml | $ONESSL envsubst | kubectl auth reconcile -f -
 fi
-
-curl -fsSL https://raw.githubusercontent.com/kubedb/apiserver/master/hack/deploy/admission.yaml | $ONESSL envsubst | kubectl apply -f -
@@ -0,0 +1,218 @@
+package bucket
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graymeta/stow"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/apimachinery/pkg/storage"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// name of the object written to the bucket by write probes
+const probeObject = ".kubedb"
+
+// Prober checks whether KubeDB can access the bucket of a snapshot storage.
+//
+// Results are cached per provider, bucket, prefix and resourceVersion of the storage secret, so editing
+// the secret invalidates the cached result. Failures are cached for a shorter time than successes, so that a
+// fixed bucket is accepted soon. Expired results are evicted whenever a probe completes. Concurrent checks of the
+// same bucket share one probe. A probe that runs past the deadline fails the check, but its result is still
+// cached when it completes. A probe that runs past config.BucketProbeTimeout is failed, so that a bucket that never
+// answers is probed again once the failure expires.
+type Prober struct {
+	config *config.Config
+	dial   func(kind string, cfg stow.Config) (stow.Location, error)
+	now    func() time.Time
+
+	lock     sync.Mutex
+	cache    map[probeKey]probeResult
+	inflight map[probeKey]*probeCall
+}
+
+type probeKey struct {
+	provider        string
+	endpoint        string
+	bucket          string
+	prefix          string
+	secret          string
+	resourceVersion string
+	readOnly        bool
+}
+
+type probeResult struct {
+	err    error
+	expiry time.Time
+}
+
+type probeCall struct {
+	done chan struct{}
+	err  error
+}
+
+// New returns a Prober configured by c. Admission hooks share one Prober, so that they share its cache.
+func New(c *config.Config) *Prober {
+	return &Prober{
+		config:   c,
+		dial:     stow.Dial,
+		now:      time.Now,
+		cache:    map[probeKey]probeResult{},
+		inflight: map[probeKey]*probeCall{},
+	}
+}
+
+// CheckBucketAccess checks that the bucket of a snapshot storage can be accessed with the credentials in the
+// storage secret. deadline is shared by all the checks of an admission request, see config.BucketProbeTimeout.
+func (p *Prober) CheckBucketAccess(client kubernetes.Interface, spec api.SnapshotStorageSpec, namespace string, deadline time.Time) error {
+	key, err := p.newKey(client, spec, namespace)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	if result, found := p.cache[key]; found && p.now().Before(result.expiry) {
+		p.lock.Unlock()
+		return result.err
+	}
+	call, found := p.inflight[key]
+	if !found {
+		call = &probeCall{done: make(chan struct{})}
+		p.inflight[key] = call
+		go p.run(client, spec, namespace, key, call)
+	}
+	p.lock.Unlock()
+
+	// earlier checks of the request may have used up some, or all, of the time until deadline
+	timeout := deadline.Sub(p.now())
+	if timeout < 0 {
+		timeout = 0
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-call.done:
+		return call.err
+	case <-timer.C:
+		return fmt.Errorf(`timed out after %v while checking access to bucket "%v"`, timeout.Round(time.Millisecond), key.bucket)
+	}
+}
+
+func (p *Prober) run(client kubernetes.Interface, spec api.SnapshotStorageSpec, namespace string, key probeKey, call *probeCall) {
+	// stow calls can't be cancelled, so a probe that hangs is failed, and evicted, when it runs past the timeout
+	timeout := p.config.BucketProbeTimeout()
+	timer := time.AfterFunc(timeout, func() {
+		p.finish(key, call, fmt.Errorf(`timed out after %v while checking access to bucket "%v"`, timeout, key.bucket))
+	})
+	defer timer.Stop()
+
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf(`failed to check access to bucket "%v": %v`, key.bucket, r)
+		}
+		p.finish(key, call, err)
+	}()
+	err = p.probe(client, spec, namespace, key)
+}
+
+// finish caches the result of call, and ends it. It does nothing if call has already ended, eg: when a hung probe
+// completes after it timed out.
+func (p *Prober) finish(key probeKey, call *probeCall, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.inflight[key] != call {
+		return
+	}
+	ttl := p.config.BucketProbeTTL
+	if err != nil {
+		ttl = p.config.BucketProbeFailureTTL
+	}
+	p.evictExpired()
+	p.cache[key] = probeResult{err: err, expiry: p.now().Add(ttl)}
+	delete(p.inflight, key)
+	call.err = err
+	close(call.done)
+}
+
+func (p *Prober) probe(client kubernetes.Interface, spec api.SnapshotStorageSpec, namespace string, key probeKey) error {
+	cfg, err := storage.NewOSMContext(client, spec, namespace)
+	if err != nil {
+		return err
+	}
+	loc, err := p.dial(cfg.Provider, cfg.Config)
+	if err != nil {
+		return err
+	}
+	defer loc.Close()
+
+	container, err := loc.Container(key.bucket)
+	if err != nil {
+		return err
+	}
+	if key.readOnly {
+		_, _, err = container.Items(key.prefix, stow.CursorStart, 1)
+		return err
+	}
+
+	r := bytes.NewReader([]byte("CheckBucketAccess"))
+	item, err := container.Put(path.Join(key.prefix, probeObject), r, r.Size(), nil)
+	if err != nil {
+		return err
+	}
+	return container.RemoveItem(item.ID())
+}
+
+// evictExpired removes the expired results from the cache, eg: the results for old versions of a storage secret.
+// p.lock must be held.
+func (p *Prober) evictExpired() {
+	now := p.now()
+	for key, result := range p.cache {
+		if !now.Before(result.expiry) {
+			delete(p.cache, key)
+		}
+	}
+}
+
+func (p *Prober) newKey(client kubernetes.Interface, spec api.SnapshotStorageSpec, namespace string) (probeKey, error) {
+	location, err := spec.Location()
+	if err != nil {
+		return probeKey{}, err
+	}
+	bucket, err := spec.Container()
+	if err != nil {
+		return probeKey{}, err
+	}
+	key := probeKey{
+		provider: strings.SplitN(location, ":", 2)[0],
+		bucket:   bucket,
+		readOnly: p.config.BucketProbeMode != config.BucketProbeModeWrite,
+	}
+	switch {
+	case spec.S3 != nil:
+		key.endpoint = spec.S3.Endpoint
+		key.prefix = spec.S3.Prefix
+	case spec.GCS != nil:
+		key.prefix = spec.GCS.Prefix
+	case spec.Azure != nil:
+		key.prefix = spec.Azure.Prefix
+	case spec.Swift != nil:
+		key.prefix = spec.Swift.Prefix
+	}
+
+	if spec.StorageSecretName != "" {
+		secret, err := client.CoreV1().Secrets(namespace).Get(spec.StorageSecretName, metav1.GetOptions{})
+		if err != nil {
+			return probeKey{}, err
+		}
+		key.secret = namespace + "/" + secret.Name
+		key.resourceVersion = secret.ResourceVersion
+	}
+	return key, nil
+}
@@ -0,0 +1,363 @@
+package bucket
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graymeta/stow"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestProber_CheckBucketAccess(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			cfg := config.New()
+			cfg.BucketProbeMode = c.mode
+			cfg.WebhookTimeout = 200 * time.Millisecond
+
+			store := &fakeStore{delay: c.delay, err: c.storeErr}
+			prober := New(cfg)
+			prober.dial = store.dial
+
+			client := fake.NewSimpleClientset(sampleSecret())
+			for i := 0; i < c.calls; i++ {
+				if c.editSecret {
+					secret := sampleSecret()
+					secret.ResourceVersion = string(rune('1' + i))
+					client = fake.NewSimpleClientset(secret)
+				}
+				err := prober.CheckBucketAccess(client, sampleStorageSpec(), "default", time.Now().Add(cfg.BucketProbeTimeout()))
+				if c.result != (err == nil) {
+					t.Fatalf("expected success: %v, but got error: %v", c.result, err)
+				}
+			}
+
+			if probes := store.count("items") + store.count("put"); probes != c.probes {
+				t.Errorf("expected %v probes, but got %v", c.probes, probes)
+			}
+			if writes := store.count("put"); writes != c.writes {
+				t.Errorf("expected %v writes, but got %v", c.writes, writes)
+			}
+			if store.count("put") != store.count("remove") {
+				t.Errorf("probe object is not removed from bucket")
+			}
+		})
+	}
+}
+
+func TestProber_CheckBucketAccessExpiry(t *testing.T) {
+	cfg := config.New()
+	store := &fakeStore{}
+	prober := New(cfg)
+	prober.dial = store.dial
+
+	now := time.Now()
+	prober.now = func() time.Time { return now }
+
+	client := fake.NewSimpleClientset(sampleSecret())
+	for _, elapsed := range []time.Duration{0, cfg.BucketProbeTTL / 2, cfg.BucketProbeTTL + time.Second} {
+		now = now.Add(elapsed)
+		if err := prober.CheckBucketAccess(client, sampleStorageSpec(), "default", now.Add(cfg.BucketProbeTimeout())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if probes := store.count("items"); probes != 2 {
+		t.Errorf("expected 2 probes, but got %v", probes)
+	}
+}
+
+func TestProber_CheckBucketAccessFailureExpiry(t *testing.T) {
+	cfg := config.New()
+	store := &fakeStore{err: errors.New("access denied")}
+	prober := New(cfg)
+	prober.dial = store.dial
+
+	now := time.Now()
+	prober.now = func() time.Time { return now }
+
+	client := fake.NewSimpleClientset(sampleSecret())
+	for _, elapsed := range []time.Duration{0, cfg.BucketProbeFailureTTL / 2, cfg.BucketProbeFailureTTL + time.Second} {
+		now = now.Add(elapsed)
+		if err := prober.CheckBucketAccess(client, sampleStorageSpec(), "default", now.Add(cfg.BucketProbeTimeout())); err == nil {
+			t.Fatal("expected bucket access check to fail")
+		}
+	}
+	if probes := store.count("items"); probes != 2 {
+		t.Errorf("expected 2 probes, but got %v", probes)
+	}
+}
+
+func TestProber_CheckBucketAccessEviction(t *testing.T) {
+	cfg := config.New()
+	store := &fakeStore{}
+	prober := New(cfg)
+	prober.dial = store.dial
+
+	now := time.Now()
+	prober.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		secret := sampleSecret()
+		secret.ResourceVersion = string(rune('1' + i))
+		client := fake.NewSimpleClientset(secret)
+		if err := prober.CheckBucketAccess(client, sampleStorageSpec(), "default", now.Add(cfg.BucketProbeTimeout())); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(cfg.BucketProbeTTL)
+	}
+	if size := len(prober.cache); size != 1 {
+		t.Errorf("expected results for old versions of storage secret to be evicted, but cache has %v results", size)
+	}
+}
+
+func TestProber_CheckBucketAccessDeadline(t *testing.T) {
+	cfg := config.New()
+	cfg.WebhookTimeout = 200 * time.Millisecond
+	store := &fakeStore{delay: time.Second}
+	prober := New(cfg)
+	prober.dial = store.dial
+
+	// all the checks of an admission request share one deadline
+	client := fake.NewSimpleClientset(sampleSecret())
+	start := time.Now()
+	deadline := start.Add(cfg.BucketProbeTimeout())
+	for i, bucket := range []string{"kubedb", "kubedb-wal", "kubedb-archive"} {
+		spec := sampleStorageSpec()
+		spec.S3.Bucket = bucket
+		err := prober.CheckBucketAccess(client, spec, "default", deadline)
+		if err == nil {
+			t.Fatal("expected bucket access check to time out")
+		}
+		// the first check uses up the time until deadline
+		if i > 0 && !strings.Contains(err.Error(), "timed out after 0s") {
+			t.Errorf("expected error to report the time left until deadline, but got: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*cfg.BucketProbeTimeout() {
+		t.Errorf("expected checks to time out after %v together, but took %v", cfg.BucketProbeTimeout(), elapsed)
+	}
+}
+
+func TestProber_CheckBucketAccessHungProbe(t *testing.T) {
+	cfg := config.New()
+	cfg.WebhookTimeout = 200 * time.Millisecond
+	cfg.BucketProbeFailureTTL = 50 * time.Millisecond
+	store := &fakeStore{block: make(chan struct{})}
+	defer close(store.block)
+	prober := New(cfg)
+	prober.dial = store.dial
+
+	client := fake.NewSimpleClientset(sampleSecret())
+	check := func() error {
+		return prober.CheckBucketAccess(client, sampleStorageSpec(), "default", time.Now().Add(cfg.BucketProbeTimeout()))
+	}
+	if err := check(); err == nil {
+		t.Fatal("expected bucket access check to time out")
+	}
+
+	// the probe that never completes is failed and evicted
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		prober.lock.Lock()
+		inflight := len(prober.inflight)
+		prober.lock.Unlock()
+		if inflight == 0 {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("expected hung probe to be evicted")
+		}
+	}
+
+	// the failure is cached, then the bucket is probed again
+	start := time.Now()
+	if err := check(); err == nil || time.Since(start) > cfg.BucketProbeTimeout()/2 {
+		t.Errorf("expected cached failure, but got error %v after %v", err, time.Since(start))
+	}
+	time.Sleep(cfg.BucketProbeFailureTTL)
+	if err := check(); err == nil {
+		t.Fatal("expected bucket access check to time out")
+	}
+	if probes := store.count("items"); probes != 2 {
+		t.Errorf("expected 2 probes, but got %v", probes)
+	}
+}
+
+var cases = []struct {
+	testName   string
+	mode       string
+	delay      time.Duration
+	storeErr   error
+	editSecret bool
+	calls      int
+	probes     int
+	writes     int
+	result     bool
+}{
+	{"Read-only probe",
+		config.BucketProbeModeReadOnly,
+		0,
+		nil,
+		false,
+		1,
+		1,
+		0,
+		true,
+	},
+	{"Write probe",
+		config.BucketProbeModeWrite,
+		0,
+		nil,
+		false,
+		1,
+		1,
+		1,
+		true,
+	},
+	{"Cached probe",
+		config.BucketProbeModeWrite,
+		0,
+		nil,
+		false,
+		3,
+		1,
+		1,
+		true,
+	},
+	{"Cached failure",
+		config.BucketProbeModeReadOnly,
+		0,
+		errors.New("access denied"),
+		false,
+		3,
+		1,
+		0,
+		false,
+	},
+	{"Probe after editing storage secret",
+		config.BucketProbeModeReadOnly,
+		0,
+		nil,
+		true,
+		3,
+		3,
+		0,
+		true,
+	},
+	{"Slow bucket",
+		config.BucketProbeModeReadOnly,
+		time.Second,
+		nil,
+		false,
+		2,
+		1,
+		0,
+		false,
+	},
+}
+
+func sampleSecret() *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            "s3-secret",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{
+			api.AWS_ACCESS_KEY_ID:     []byte("id"),
+			api.AWS_SECRET_ACCESS_KEY: []byte("key"),
+		},
+	}
+}
+
+func sampleStorageSpec() api.SnapshotStorageSpec {
+	return api.SnapshotStorageSpec{
+		StorageSecretName: "s3-secret",
+		S3: &api.S3Spec{
+			Endpoint: "http://minio.default.svc:9000",
+			Bucket:   "kubedb",
+			Prefix:   "demo",
+		},
+	}
+}
+
+// fakeStore is a stow.Location that records the operations done on its containers.
+type fakeStore struct {
+	stow.Location
+
+	delay time.Duration
+	err   error
+	// block, if not nil, makes every operation wait until it is closed
+	block chan struct{}
+
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+type fakeItem struct {
+	stow.Item
+	name string
+}
+
+func (i *fakeItem) ID() string { return i.name }
+
+func (s *fakeStore) dial(kind string, cfg stow.Config) (stow.Location, error) {
+	return s, nil
+}
+
+func (s *fakeStore) record(op string) error {
+	s.lock.Lock()
+	if s.calls == nil {
+		s.calls = map[string]int{}
+	}
+	s.calls[op]++
+	s.lock.Unlock()
+
+	if s.block != nil {
+		<-s.block
+	}
+	time.Sleep(s.delay)
+	return s.err
+}
+
+func (s *fakeStore) count(op string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[op]
+}
+
+func (s *fakeStore) Close() error { return nil }
+
+func (s *fakeStore) Container(id string) (stow.Container, error) {
+	return &fakeContainer{store: s}, nil
+}
+
+type fakeContainer struct {
+	stow.Container
+	store *fakeStore
+}
+
+func (s *fakeContainer) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+	return nil, "", s.store.record("items")
+}
+
+func (s *fakeContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	if !strings.HasPrefix(name, "demo/") {
+		return nil, errors.New("probe object is written outside of prefix")
+	}
+	if err := s.store.record("put"); err != nil {
+		return nil, err
+	}
+	return &fakeItem{name: name}, nil
+}
+
+func (s *fakeContainer) RemoveItem(id string) error {
+	return s.store.record("remove")
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Config holds the policy knobs shared by KubeDB admission hooks.
+type Config struct {
+	// OperatorServiceAccount is the username KubeDB operator uses to talk to the kube-apiserver.
+	OperatorServiceAccount string
+	// ServerServiceAccount is the username kubedb-server uses to talk to the kube-apiserver.
+	ServerServiceAccount string
+	// BreakGlassGroups are the groups whose members may bypass the protection of KubeDB managed objects.
+	BreakGlassGroups []string
+	// MinBackupInterval is the minimum time allowed between two scheduled backups of a database.
+	MinBackupInterval time.Duration
+	// BucketProbeMode is either "read-only" or "write". In write mode, a probe object is written to and removed from the bucket.
+	BucketProbeMode string
+	// BucketProbeTTL is the duration for which the result of a successful bucket access check is cached.
+	BucketProbeTTL time.Duration
+	// BucketProbeFailureTTL is the duration for which the result of a failed bucket access check is cached.
+	BucketProbeFailureTTL time.Duration
+	// WebhookTimeout is the time kube-apiserver waits for a response from admission webhooks.
+	WebhookTimeout time.Duration
+	// SchedulingPolicy is either "warn" or "deny". It decides what happens when no Node can run the database pods.
+	SchedulingPolicy string
+	// CapacityPolicy is either "warn" or "deny". It decides what happens when the Nodes don't have enough
+	// allocatable resources to run all the database pods.
+	CapacityPolicy string
+	// CertificateExpiryWarning is the remaining validity of a database certificate below which admission warns.
+	CertificateExpiryWarning time.Duration
+	// CertificateExpiryPolicy is either "warn" or "deny". It decides what happens when a database certificate
+	// expires within CertificateExpiryWarning.
+	CertificateExpiryPolicy string
+	// MemcachedDefaultMemory is the memory limit given to Memcached pods that have none.
+	MemcachedDefaultMemory resource.Quantity
+	// MemcachedMemoryOverhead is the memory a Memcached pod uses outside of its cache, for the process and
+	// the buffers of client connections.
+	MemcachedMemoryOverhead resource.Quantity
+	// MemcachedMinCacheSize is the minimum cache size of a Memcached pod, ie: memory limit minus overhead.
+	MemcachedMinCacheSize resource.Quantity
+	// SnapshotOwnerReference makes new Snapshots owned by their database, so that they are garbage collected
+	// when the database is deleted. It is off by default: a deleted database is paused into a DormantDatabase,
+	// and its Snapshots are still needed to resume it or to initialize other databases from them.
+	SnapshotOwnerReference bool
+}
+
+const (
+	BucketProbeModeReadOnly = "read-only"
+	BucketProbeModeWrite    = "write"
+
+	PolicyWarn = "warn"
+	PolicyDeny = "deny"
+)
+
+// New returns the Config with the default values of the flags. Admission hooks are given the Config when they are
+// constructed.
+func New() *Config {
+	return &Config{
+		OperatorServiceAccount:   "system:serviceaccount:kube-system:kubedb-operator",
+		ServerServiceAccount:     "system:serviceaccount:kube-system:kubedb-server",
+		MinBackupInterval:        5 * time.Minute,
+		BucketProbeMode:          BucketProbeModeReadOnly,
+		BucketProbeTTL:           5 * time.Minute,
+		BucketProbeFailureTTL:    15 * time.Second,
+		WebhookTimeout:           30 * time.Second,
+		SchedulingPolicy:         PolicyWarn,
+		CapacityPolicy:           PolicyWarn,
+		CertificateExpiryWarning: 30 * 24 * time.Hour,
+		CertificateExpiryPolicy:  PolicyWarn,
+		MemcachedDefaultMemory:   resource.MustParse("128Mi"),
+		MemcachedMemoryOverhead:  resource.MustParse("32Mi"),
+		MemcachedMinCacheSize:    resource.MustParse("64Mi"),
+	}
+}
+
+func (c *Config) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&c.OperatorServiceAccount, "operator-service-account", c.OperatorServiceAccount, "Username of KubeDB operator, eg: system:serviceaccount:<namespace>:<name>")
+	fs.StringVar(&c.ServerServiceAccount, "server-service-account", c.ServerServiceAccount, "Username of kubedb-server, eg: system:serviceaccount:<namespace>:<name>")
+	fs.StringSliceVar(&c.BreakGlassGroups, "break-glass-groups", c.BreakGlassGroups, "Groups allowed to modify objects managed by KubeDB operator")
+	fs.DurationVar(&c.MinBackupInterval, "min-backup-interval", c.MinBackupInterval, "Minimum interval between two scheduled backups of a database")
+	fs.StringVar(&c.BucketProbeMode, "bucket-probe-mode", c.BucketProbeMode, "How access to backup buckets is checked, one of read-only or write")
+	fs.DurationVar(&c.BucketProbeTTL, "bucket-probe-ttl", c.BucketProbeTTL, "Duration for which the result of a successful bucket access check is cached")
+	fs.DurationVar(&c.BucketProbeFailureTTL, "bucket-probe-failure-ttl", c.BucketProbeFailureTTL, "Duration for which the result of a failed bucket access check is cached")
+	fs.DurationVar(&c.WebhookTimeout, "webhook-timeout", c.WebhookTimeout, "Time kube-apiserver waits for a response from admission webhooks")
+	fs.StringVar(&c.SchedulingPolicy, "scheduling-policy", c.SchedulingPolicy, "What to do when no node can run the database pods, one of warn or deny")
+	fs.StringVar(&c.CapacityPolicy, "capacity-policy", c.CapacityPolicy, "What to do when nodes don't have enough allocatable resources for the database pods, one of warn or deny")
+	fs.DurationVar(&c.CertificateExpiryWarning, "certificate-expiry-warning", c.CertificateExpiryWarning, "Warn when a database certificate expires within this duration")
+	fs.StringVar(&c.CertificateExpiryPolicy, "certificate-expiry-policy", c.CertificateExpiryPolicy, "What to do when a database certificate expires within --certificate-expiry-warning, one of warn or deny")
+	fs.Var((*quantityValue)(&c.MemcachedDefaultMemory), "memcached-default-memory", "Memory limit given to Memcached pods that have none")
+	fs.Var((*quantityValue)(&c.MemcachedMemoryOverhead), "memcached-memory-overhead", "Memory a Memcached pod uses outside of its cache")
+	fs.Var((*quantityValue)(&c.MemcachedMinCacheSize), "memcached-min-cache-size", "Minimum cache size of a Memcached pod, ie: memory limit minus overhead")
+	fs.BoolVar(&c.SnapshotOwnerReference, "snapshot-owner-reference", c.SnapshotOwnerReference, "If true, new Snapshots are owned by their database and garbage collected with it")
+}
+
+// Validate checks the values given to the flags of Config.
+func (c *Config) Validate() error {
+	if c.BucketProbeMode != BucketProbeModeReadOnly && c.BucketProbeMode != BucketProbeModeWrite {
+		return fmt.Errorf(`invalid --bucket-probe-mode "%s", must be one of %s or %s`, c.BucketProbeMode, BucketProbeModeReadOnly, BucketProbeModeWrite)
+	}
+	if c.SchedulingPolicy != PolicyWarn && c.SchedulingPolicy != PolicyDeny {
+		return fmt.Errorf(`invalid --scheduling-policy "%s", must be one of %s or %s`, c.SchedulingPolicy, PolicyWarn, PolicyDeny)
+	}
+	if c.CapacityPolicy != PolicyWarn && c.CapacityPolicy != PolicyDeny {
+		return fmt.Errorf(`invalid --capacity-policy "%s", must be one of %s or %s`, c.CapacityPolicy, PolicyWarn, PolicyDeny)
+	}
+	if c.CertificateExpiryPolicy != PolicyWarn && c.CertificateExpiryPolicy != PolicyDeny {
+		return fmt.Errorf(`invalid --certificate-expiry-policy "%s", must be one of %s or %s`, c.CertificateExpiryPolicy, PolicyWarn, PolicyDeny)
+	}
+	return nil
+}
+
+// quantityValue is a pflag.Value for resource quantities, eg: 64Mi
+type quantityValue resource.Quantity
+
+func (q *quantityValue) String() string {
+	return (*resource.Quantity)(q).String()
+}
+
+func (q *quantityValue) Set(s string) error {
+	v, err := resource.ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = quantityValue(v)
+	return nil
+}
+
+func (q *quantityValue) Type() string {
+	return "quantity"
+}
+
+// IsOperator returns true if the request was made by KubeDB operator.
+func (c *Config) IsOperator(user authenticationv1.UserInfo) bool {
+	return c.OperatorServiceAccount != "" && user.Username == c.OperatorServiceAccount
+}
+
+// IsServer returns true if the request was made by kubedb-server itself, eg: from an admission hook.
+func (c *Config) IsServer(user authenticationv1.UserInfo) bool {
+	return c.ServerServiceAccount != "" && user.Username == c.ServerServiceAccount
+}
+
+// IsPrivileged returns true if the request was made by KubeDB operator or a member of a break-glass group.
+func (c *Config) IsPrivileged(user authenticationv1.UserInfo) bool {
+	if c.IsOperator(user) {
+		return true
+	}
+	for _, group := range user.Groups {
+		for _, g := range c.BreakGlassGroups {
+			if group == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BucketProbeTimeout returns the time all the bucket access checks of an admission request may take together.
+// It is half of the webhook timeout, so that a slow storage endpoint leaves enough time for the rest of the
+// admission chain.
+func (c *Config) BucketProbeTimeout() time.Duration {
+	return c.WebhookTimeout / 2
+}
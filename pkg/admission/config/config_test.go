@@ -0,0 +1,102 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			config := New()
+			config.BucketProbeMode = c.bucketProbeMode
+			config.SchedulingPolicy = c.schedulingPolicy
+			config.CapacityPolicy = c.capacityPolicy
+			config.CertificateExpiryPolicy = c.certificateExpiryPolicy
+
+			err := config.Validate()
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var cases = []struct {
+	testName                string
+	bucketProbeMode         string
+	schedulingPolicy        string
+	capacityPolicy          string
+	certificateExpiryPolicy string
+	result                  bool
+}{
+	{"Default policies",
+		BucketProbeModeReadOnly,
+		PolicyWarn,
+		PolicyWarn,
+		PolicyWarn,
+		true,
+	},
+	{"Deny scheduling policy",
+		BucketProbeModeReadOnly,
+		PolicyDeny,
+		PolicyWarn,
+		PolicyWarn,
+		true,
+	},
+	{"Capitalized scheduling policy",
+		BucketProbeModeReadOnly,
+		"Deny",
+		PolicyWarn,
+		PolicyWarn,
+		false,
+	},
+	{"Empty scheduling policy",
+		BucketProbeModeReadOnly,
+		"",
+		PolicyWarn,
+		PolicyWarn,
+		false,
+	},
+	{"Deny capacity policy",
+		BucketProbeModeReadOnly,
+		PolicyWarn,
+		PolicyDeny,
+		PolicyWarn,
+		true,
+	},
+	{"Capitalized capacity policy",
+		BucketProbeModeReadOnly,
+		PolicyWarn,
+		"Deny",
+		PolicyWarn,
+		false,
+	},
+	{"Write bucket probe mode",
+		BucketProbeModeWrite,
+		PolicyWarn,
+		PolicyWarn,
+		PolicyWarn,
+		true,
+	},
+	{"Unknown bucket probe mode",
+		"readonly",
+		PolicyWarn,
+		PolicyWarn,
+		PolicyWarn,
+		false,
+	},
+	{"Deny certificate expiry policy",
+		BucketProbeModeReadOnly,
+		PolicyWarn,
+		PolicyWarn,
+		PolicyDeny,
+		true,
+	},
+	{"Unknown certificate expiry policy",
+		BucketProbeModeReadOnly,
+		PolicyWarn,
+		PolicyWarn,
+		"error",
+		false,
+	},
+}
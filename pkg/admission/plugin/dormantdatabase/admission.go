@@ -0,0 +1,80 @@
+package dormantdatabase
+
+import (
+	"sync"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/apimachinery/pkg/admission/dormantdatabase"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+// DormantDatabaseValidator extends the DormantDatabase validator of kubedb/apimachinery to allow only KubeDB
+// operator to write the reserved labels and annotations of a DormantDatabase. KubeDB operator wipes out the
+// objects of the database named by the kind label of a DormantDatabase.
+type DormantDatabaseValidator struct {
+	dormantdatabase.DormantDatabaseValidator
+
+	config      *config.Config
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &DormantDatabaseValidator{}
+
+// NewDormantDatabaseValidator returns the DormantDatabaseValidator that allows the operator named by c.
+func NewDormantDatabaseValidator(c *config.Config) *DormantDatabaseValidator {
+	return &DormantDatabaseValidator{config: c}
+}
+
+func (a *DormantDatabaseValidator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	if err := a.DormantDatabaseValidator.Initialize(config, stopCh); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *DormantDatabaseValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if (req.Operation == admission.Create || req.Operation == admission.Update) &&
+		len(req.SubResource) == 0 &&
+		req.Kind.Group == api.SchemeGroupVersion.Group &&
+		req.Kind.Kind == api.ResourceKindDormantDatabase {
+		a.lock.RLock()
+		defer a.lock.RUnlock()
+		if !a.initialized {
+			return hookapi.StatusUninitialized()
+		}
+		obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+		if err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+		var oldObject runtime.Object
+		if req.Operation == admission.Update {
+			if oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion); err != nil {
+				return hookapi.StatusBadRequest(err)
+			}
+		}
+		// only KubeDB operator may set the labels and annotations reserved for it
+		if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+	}
+	return a.DormantDatabaseValidator.Admit(req)
+}
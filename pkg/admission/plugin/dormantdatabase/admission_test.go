@@ -0,0 +1,163 @@
+package dormantdatabase
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+}
+
+var requestKind = metaV1.GroupVersionKind{
+	Group:   api.SchemeGroupVersion.Group,
+	Version: api.SchemeGroupVersion.Version,
+	Kind:    api.ResourceKindDormantDatabase,
+}
+
+var (
+	operator = authenticationV1.UserInfo{Username: config.New().OperatorServiceAccount}
+	user     = authenticationV1.UserInfo{Username: "alice"}
+)
+
+func TestDormantDatabaseValidator_Admit(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			validator := NewDormantDatabaseValidator(config.New())
+			// the clients are created without connecting, and the ones used by the test are replaced by fakes
+			if err := validator.Initialize(&rest.Config{Host: "http://127.0.0.1:1"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			validator.extClient = extFake.NewSimpleClientset()
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = c.operation
+			req.UserInfo = c.user
+			req.Object.Raw = objJS
+			if c.operation == admission.Update {
+				oldObjJS, err := meta.MarshalToJson(&c.oldObject, api.SchemeGroupVersion)
+				if err != nil {
+					panic(err)
+				}
+				req.OldObject.Raw = oldObjJS
+			}
+
+			response := validator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+var cases = []struct {
+	testName  string
+	operation admission.Operation
+	user      authenticationV1.UserInfo
+	object    api.DormantDatabase
+	oldObject api.DormantDatabase
+	result    bool
+}{
+	{"Create DormantDatabase",
+		admission.Create,
+		operator,
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.DormantDatabase{},
+		true,
+	},
+	{"Create DormantDatabase with kind label of other kind",
+		admission.Create,
+		user,
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		api.DormantDatabase{},
+		false,
+	},
+	{"Edit other labels",
+		admission.Update,
+		user,
+		editLabel(sampleDormantDatabase(api.ResourceKindPostgres), "app", "web"),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		true,
+	},
+	{"Change kind label",
+		admission.Update,
+		user,
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		false,
+	},
+	{"Remove kind label",
+		admission.Update,
+		user,
+		editLabel(sampleDormantDatabase(api.ResourceKindPostgres), api.LabelDatabaseKind, ""),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		false,
+	},
+}
+
+// sampleDormantDatabase returns the DormantDatabase of a paused Postgres, labeled with kind.
+func sampleDormantDatabase(kind string) api.DormantDatabase {
+	return api.DormantDatabase{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindDormantDatabase,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Postgres: &api.PostgresSpec{
+						Version: "9.6",
+					},
+				},
+			},
+		},
+	}
+}
+
+// editLabel sets the label key to value, or removes it if value is empty.
+func editLabel(old api.DormantDatabase, key, value string) api.DormantDatabase {
+	labels := map[string]string{}
+	for k, v := range old.Labels {
+		labels[k] = v
+	}
+	if value == "" {
+		delete(labels, key)
+	} else {
+		labels[key] = value
+	}
+	old.Labels = labels
+	return old
+}
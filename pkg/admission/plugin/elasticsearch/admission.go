@@ -3,22 +3,28 @@ package elasticsearch
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
 	meta_util "github.com/appscode/kutil/meta"
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
-	esv "github.com/kubedb/elasticsearch/pkg/validator"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
 	admission "k8s.io/api/admission/v1beta1"
 	kerr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type ElasticsearchValidator struct {
+	config      *config.Config
+	prober      *bucket.Prober
 	client      kubernetes.Interface
 	extClient   cs.Interface
 	lock        sync.RWMutex
@@ -27,6 +33,12 @@ type ElasticsearchValidator struct {
 
 var _ hookapi.AdmissionHook = &ElasticsearchValidator{}
 
+// NewElasticsearchValidator returns the validator of Elasticsearch configured by c, that checks the access to backup buckets with
+// prober.
+func NewElasticsearchValidator(c *config.Config, prober *bucket.Prober) *ElasticsearchValidator {
+	return &ElasticsearchValidator{config: c, prober: prober}
+}
+
 func (a *ElasticsearchValidator) Resource() (plural schema.GroupVersionResource, singular string) {
 	return schema.GroupVersionResource{
 			Group:    "admission.kubedb.com",
@@ -83,9 +95,10 @@ func (a *ElasticsearchValidator) Admit(req *admission.AdmissionRequest) *admissi
 		if err != nil {
 			return hookapi.StatusBadRequest(err)
 		}
+		var oldObject runtime.Object
 		if req.Operation == admission.Update {
 			// validate changes made by user
-			oldObject, err := meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
+			oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
 			if err != nil {
 				return hookapi.StatusBadRequest(err)
 			}
@@ -93,8 +106,55 @@ func (a *ElasticsearchValidator) Admit(req *admission.AdmissionRequest) *admissi
 				return hookapi.StatusBadRequest(fmt.Errorf("%v", err))
 			}
 		}
+		// only KubeDB operator may set the labels and annotations reserved for it
+		if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// a database resuming a DormantDatabase is checked against the paused database, as on an update
+		previous := oldObject
+		if req.Operation == admission.Create {
+			if previous, err = util.GetPausedDatabase(a.extClient.KubedbV1alpha1(), req.Kind.Kind, obj); err != nil {
+				return hookapi.StatusInternalServerError(err)
+			}
+		}
+		deadline := time.Now().Add(a.config.BucketProbeTimeout())
 		// validate database specs
-		if err = esv.ValidateElasticsearch(a.client, a.extClient.KubedbV1alpha1(), obj.(*api.Elasticsearch)); err != nil {
+		if err = ValidateElasticsearch(a.client, a.extClient.KubedbV1alpha1(), a.prober, obj.(*api.Elasticsearch), oldObject, previous, deadline); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// validate backup schedule, and report the upcoming backups back to the user
+		nextBackups, err := amv.ValidateBackupSchedule(a.client, a.prober, obj.(*api.Elasticsearch).Spec.BackupSchedule, util.GetBackupSchedule(previous), req.Namespace, a.config.MinBackupInterval, deadline)
+		if err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		util.AppendMessage(status, amv.BackupScheduleMessage(nextBackups))
+		spec := obj.(*api.Elasticsearch).Spec
+		// check the certificates used for SSL
+		if err := checkCertificateSecret(a.client, obj.(*api.Elasticsearch), oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check the passwords of the auth secret against the policy of the namespace
+		if err := amv.CheckPasswordStrength(a.client, api.ResourceKindElasticsearch, spec.DatabaseSecret, req.Namespace, previous); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that a node can run the database pods
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckSchedulable(a.client, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the nodes have enough resources for the database pods
+		if err := util.ApplyPolicy(req, status, a.config.CapacityPolicy, amv.CheckCapacity(a.client, podCount(obj), podCount(oldObject), spec.Resources, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of the database are bound where its pods can run
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckStorageBinding(a.client, spec.Storage, spec.NodeSelector, spec.Affinity, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of a production database are kept when their claims are deleted
+		if err := util.ApplyPolicy(req, status, config.PolicyWarn, amv.CheckReclaimPolicy(a.client, spec.Storage, req.Namespace, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the certificates don't expire soon
+		if err := util.ApplyPolicy(req, status, a.config.CertificateExpiryPolicy, checkCertificateExpiry(a.client, obj.(*api.Elasticsearch), oldObject, a.config.CertificateExpiryWarning)); err != nil {
 			return hookapi.StatusForbidden(err)
 		}
 	}
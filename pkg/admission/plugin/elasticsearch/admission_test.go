@@ -1,8 +1,17 @@
 package elasticsearch
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/appscode/go/types"
 	kubeMon "github.com/appscode/kube-mon/api"
@@ -10,6 +19,8 @@ import (
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
 	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	admission "k8s.io/api/admission/v1beta1"
 	authenticationV1 "k8s.io/api/authentication/v1"
 	core "k8s.io/api/core/v1"
@@ -35,7 +46,8 @@ var requestKind = metaV1.GroupVersionKind{
 func TestElasticsearchValidator_Admit(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.testName, func(t *testing.T) {
-			validator := ElasticsearchValidator{}
+			admissionConfig := config.New()
+			validator := NewElasticsearchValidator(admissionConfig, bucket.New(admissionConfig))
 
 			validator.initialized = true
 			validator.extClient = extFake.NewSimpleClientset()
@@ -44,6 +56,9 @@ func TestElasticsearchValidator_Admit(t *testing.T) {
 					ObjectMeta: metaV1.ObjectMeta{
 						Name:      "foo-auth",
 						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindElasticsearch,
+						},
 					},
 				},
 				&storageV1beta1.StorageClass{
@@ -212,6 +227,223 @@ var cases = []struct {
 	},
 }
 
+func TestElasticsearchValidator_AdmitCapacity(t *testing.T) {
+	for _, c := range capacityCases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			admissionConfig.CapacityPolicy = c.policy
+
+			validator := NewElasticsearchValidator(admissionConfig, bucket.New(admissionConfig))
+			validator.initialized = true
+			validator.extClient = extFake.NewSimpleClientset()
+			validator.client = fake.NewSimpleClientset(
+				&storageV1beta1.StorageClass{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name: "standard",
+					},
+				},
+				&core.Node{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name: "node-1",
+					},
+					Status: core.NodeStatus{
+						Allocatable: core.ResourceList{
+							core.ResourceMemory: resource.MustParse("4Gi"),
+						},
+					},
+				},
+			)
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.Object.Raw = objJS
+
+			response := validator.Admit(req)
+			if response.Allowed != c.result {
+				t.Errorf("expected: 'Allowed=%v', but got response: %v", c.result, response)
+			}
+			if warned := response.Result != nil && strings.Contains(response.Result.Message, "warning"); warned != c.warning {
+				t.Errorf("expected warning: %v, but got response: %v", c.warning, response)
+			}
+		})
+	}
+}
+
+var capacityCases = []struct {
+	testName string
+	policy   string
+	object   api.Elasticsearch
+	result   bool
+	warning  bool
+}{
+	{"Topology fits on nodes",
+		config.PolicyDeny,
+		editSpecTopology(sampleElasticsearch(), "1Gi", 1, 2, 1),
+		true,
+		false,
+	},
+	{"Topology doesn't fit on nodes",
+		config.PolicyDeny,
+		editSpecTopology(sampleElasticsearch(), "2Gi", 1, 2, 1),
+		false,
+		false,
+	},
+	{"Topology doesn't fit on nodes with warn policy",
+		config.PolicyWarn,
+		editSpecTopology(sampleElasticsearch(), "2Gi", 1, 2, 1),
+		true,
+		true,
+	},
+}
+
+func TestElasticsearchValidator_AdmitCertificate(t *testing.T) {
+	for _, c := range certificateCases() {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			admissionConfig.CertificateExpiryPolicy = c.policy
+			validator := NewElasticsearchValidator(admissionConfig, bucket.New(admissionConfig))
+			validator.initialized = true
+			validator.extClient = extFake.NewSimpleClientset()
+			validator.client = fake.NewSimpleClientset(
+				c.secret,
+				&storageV1beta1.StorageClass{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name: "standard",
+					},
+				},
+				&core.Node{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name: "node-1",
+					},
+				},
+			)
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.Object.Raw = objJS
+			if c.oldObject != nil {
+				oldObjJS, err := meta.MarshalToJson(c.oldObject, api.SchemeGroupVersion)
+				if err != nil {
+					panic(err)
+				}
+				req.Operation = admission.Update
+				req.OldObject.Raw = oldObjJS
+			}
+
+			response := validator.Admit(req)
+			if response.Allowed != c.result {
+				t.Errorf("expected: 'Allowed=%v', but got response: %v", c.result, response)
+			}
+			if warned := response.Result != nil && strings.Contains(response.Result.Message, "warning"); warned != c.warning {
+				t.Errorf("expected warning: %v, but got response: %v", c.warning, response)
+			}
+		})
+	}
+}
+
+type certificateCase struct {
+	testName  string
+	policy    string
+	object    api.Elasticsearch
+	oldObject *api.Elasticsearch
+	secret    *core.Secret
+	result    bool
+	warning   bool
+}
+
+func certificateCases() []certificateCase {
+	return []certificateCase{
+		{"Valid certificate",
+			config.PolicyWarn,
+			editSpecEnableSSL(sampleElasticsearch()),
+			nil,
+			certificateSecret(time.Now().Add(365 * 24 * time.Hour)),
+			true,
+			false,
+		},
+		{"Certificate about to expire",
+			config.PolicyWarn,
+			editSpecEnableSSL(sampleElasticsearch()),
+			nil,
+			certificateSecret(time.Now().Add(24 * time.Hour)),
+			true,
+			true,
+		},
+		{"Certificate about to expire with deny policy",
+			config.PolicyDeny,
+			editSpecEnableSSL(sampleElasticsearch()),
+			nil,
+			certificateSecret(time.Now().Add(24 * time.Hour)),
+			false,
+			false,
+		},
+		{"Expired certificate",
+			config.PolicyWarn,
+			editSpecEnableSSL(sampleElasticsearch()),
+			nil,
+			certificateSecret(time.Now().Add(-time.Hour)),
+			false,
+			false,
+		},
+		{"Certificate secret without private key",
+			config.PolicyWarn,
+			editSpecEnableSSL(sampleElasticsearch()),
+			nil,
+			editDeleteKey(certificateSecret(time.Now().Add(365*24*time.Hour)), core.TLSPrivateKeyKey),
+			false,
+			false,
+		},
+		{"Expired certificate with SSL disabled",
+			config.PolicyWarn,
+			editSpecCertificateSecret(sampleElasticsearch()),
+			nil,
+			certificateSecret(time.Now().Add(-time.Hour)),
+			true,
+			false,
+		},
+		{"Expired certificate on unrelated update",
+			config.PolicyWarn,
+			editSpecDoNotPause(editSpecEnableSSL(sampleElasticsearch())),
+			elasticsearchPtr(editSpecEnableSSL(sampleElasticsearch())),
+			certificateSecret(time.Now().Add(-time.Hour)),
+			true,
+			false,
+		},
+		{"Certificate about to expire on unrelated update",
+			config.PolicyWarn,
+			editSpecDoNotPause(editSpecEnableSSL(sampleElasticsearch())),
+			elasticsearchPtr(editSpecEnableSSL(sampleElasticsearch())),
+			certificateSecret(time.Now().Add(24 * time.Hour)),
+			true,
+			false,
+		},
+		{"Expired certificate on enabling SSL",
+			config.PolicyWarn,
+			editSpecEnableSSL(sampleElasticsearch()),
+			elasticsearchPtr(editSpecCertificateSecret(sampleElasticsearch())),
+			certificateSecret(time.Now().Add(-time.Hour)),
+			false,
+			false,
+		},
+	}
+}
+
 func sampleElasticsearch() api.Elasticsearch {
 	return api.Elasticsearch{
 		TypeMeta: metaV1.TypeMeta{
@@ -289,3 +521,76 @@ func editSpecDoNotPause(old api.Elasticsearch) api.Elasticsearch {
 	old.Spec.DoNotPause = false
 	return old
 }
+
+func editSpecTopology(old api.Elasticsearch, memory string, master, data, client int32) api.Elasticsearch {
+	old.Spec.Topology = &api.ElasticsearchClusterTopology{
+		Master: api.ElasticsearchNode{Prefix: "master", Replicas: types.Int32P(master)},
+		Data:   api.ElasticsearchNode{Prefix: "data", Replicas: types.Int32P(data)},
+		Client: api.ElasticsearchNode{Prefix: "client", Replicas: types.Int32P(client)},
+	}
+	old.Spec.Resources = core.ResourceRequirements{
+		Requests: core.ResourceList{
+			core.ResourceMemory: resource.MustParse(memory),
+		},
+	}
+	return old
+}
+
+func editSpecCertificateSecret(old api.Elasticsearch) api.Elasticsearch {
+	old.Spec.CertificateSecret = &core.SecretVolumeSource{
+		SecretName: "foo-cert",
+	}
+	return old
+}
+
+func editSpecEnableSSL(old api.Elasticsearch) api.Elasticsearch {
+	old = editSpecCertificateSecret(old)
+	old.Spec.EnableSSL = true
+	return old
+}
+
+func elasticsearchPtr(old api.Elasticsearch) *api.Elasticsearch {
+	return &old
+}
+
+// certificateSecret returns a secret holding a self-signed certificate, which also serves as its own CA.
+func certificateSecret(notAfter time.Time) *core.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "foo"},
+		NotBefore:             notAfter.Add(-2 * 365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return &core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo-cert",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt":              cert,
+			core.TLSCertKey:       cert,
+			core.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func editDeleteKey(old *core.Secret, key string) *core.Secret {
+	delete(old.Data, key)
+	return old
+}
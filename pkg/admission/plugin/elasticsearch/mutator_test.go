@@ -0,0 +1,184 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/appscode/go/types"
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestElasticsearchMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			mutator := ElasticsearchMutator{}
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset()
+			mutator.client = fake.NewSimpleClientset()
+
+			if c.dormantDb != nil {
+				if _, err := mutator.extClient.KubedbV1alpha1().DormantDatabases(c.dormantDb.Namespace).Create(c.dormantDb); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				patch, err := jsonpatch.DecodePatch(response.Patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modJS, err := patch.Apply(objJS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.Elasticsearch).Spec, c.expected) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected, mod.(*api.Elasticsearch).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				} else if c.dormantDb != nil &&
+					c.dormantDb.Labels[api.LabelDatabaseKind] == api.ResourceKindElasticsearch &&
+					!strings.Contains(response.Result.Message, "Diff") {
+					t.Errorf("expected diff in response: %v", response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName  string
+	object    api.Elasticsearch
+	dormantDb *api.DormantDatabase
+	expected  api.ElasticsearchSpec
+	result    bool
+}{
+	{"Create Elasticsearch",
+		sampleElasticsearch(),
+		nil,
+		setReplicas(sampleElasticsearch()).Spec,
+		true,
+	},
+	{"Create Elasticsearch without Version",
+		emptyElasticsearch(),
+		nil,
+		api.ElasticsearchSpec{},
+		false,
+	},
+	{"Resume Elasticsearch with name and kind only",
+		emptyElasticsearch(),
+		sampleDormantDatabase(api.ResourceKindElasticsearch),
+		editSpecDoNotPause(api.Elasticsearch{Spec: dormantElasticsearchSpec()}).Spec,
+		true,
+	},
+	{"Resume Elasticsearch with partial Storage",
+		editSpecStorageClass(emptyElasticsearch()),
+		sampleDormantDatabase(api.ResourceKindElasticsearch),
+		editSpecDoNotPause(api.Elasticsearch{Spec: dormantElasticsearchSpec()}).Spec,
+		true,
+	},
+	{"Resume Elasticsearch with mismatched Storage",
+		editSpecStorage(emptyElasticsearch()),
+		sampleDormantDatabase(api.ResourceKindElasticsearch),
+		api.ElasticsearchSpec{},
+		false,
+	},
+	{"Resume Elasticsearch from DormantDatabase of different kind",
+		emptyElasticsearch(),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.ElasticsearchSpec{},
+		false,
+	},
+}
+
+func emptyElasticsearch() api.Elasticsearch {
+	elasticsearch := sampleElasticsearch()
+	elasticsearch.Spec = api.ElasticsearchSpec{}
+	return elasticsearch
+}
+
+func dormantElasticsearchSpec() api.ElasticsearchSpec {
+	spec := setReplicas(sampleElasticsearch()).Spec
+	spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: "foo-auth",
+	}
+	return spec
+}
+
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	spec := dormantElasticsearchSpec()
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Elasticsearch: &spec,
+				},
+			},
+		},
+	}
+}
+
+func setReplicas(old api.Elasticsearch) api.Elasticsearch {
+	old.Spec.Replicas = types.Int32P(1)
+	return old
+}
+
+func editSpecStorageClass(old api.Elasticsearch) api.Elasticsearch {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		StorageClassName: types.StringP("standard"),
+	}
+	return old
+}
+
+func editSpecStorage(old api.Elasticsearch) api.Elasticsearch {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	return old
+}
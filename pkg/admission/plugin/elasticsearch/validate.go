@@ -0,0 +1,263 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	elasticVersions    = sets.NewString("5.6", "5.6.4")
+	elasticsearchPorts = []int32{9200, 9300}
+)
+
+// ValidateElasticsearch validates elasticsearch. oldObject is the Elasticsearch before an update, and previous is oldObject, or the paused
+// database when elasticsearch resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidateElasticsearch(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, prober *bucket.Prober, elasticsearch *api.Elasticsearch, oldObject, previous runtime.Object, deadline time.Time) error {
+	if elasticsearch.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, elasticsearch.Spec)
+	}
+
+	// check Elasticsearch version validation
+	if !elasticVersions.Has(string(elasticsearch.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support Elasticsearch version: %s`, string(elasticsearch.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindElasticsearch, elasticsearch.Name, elasticsearch.Namespace, derivedNames(elasticsearch), oldObject); err != nil {
+		return err
+	}
+
+	topology := elasticsearch.Spec.Topology
+	if topology != nil {
+		if topology.Client.Prefix == topology.Master.Prefix {
+			return errors.New("client & master node should not have same prefix")
+		}
+		if topology.Client.Prefix == topology.Data.Prefix {
+			return errors.New("client & data node should not have same prefix")
+		}
+		if topology.Master.Prefix == topology.Data.Prefix {
+			return errors.New("master & data node should not have same prefix")
+		}
+
+		if topology.Client.Replicas != nil {
+			replicas := topology.Client.Replicas
+			if types.Int32(replicas) < 1 {
+				return fmt.Errorf(`topology.client.replicas "%d" invalid. Must be greater than zero`, replicas)
+			}
+		}
+
+		if topology.Master.Replicas != nil {
+			replicas := topology.Master.Replicas
+			if types.Int32(replicas) < 1 {
+				return fmt.Errorf(`topology.master.replicas "%d" invalid. Must be greater than zero`, replicas)
+			}
+		}
+
+		if topology.Data.Replicas != nil {
+			replicas := topology.Data.Replicas
+			if types.Int32(replicas) < 1 {
+				return fmt.Errorf(`topology.data.replicas "%d" invalid. Must be greater than zero`, replicas)
+			}
+		}
+	} else {
+		if elasticsearch.Spec.Replicas != nil {
+			replicas := types.Int32(elasticsearch.Spec.Replicas)
+			if replicas < 1 {
+				return fmt.Errorf(`spec.replicas "%d" invalid. Must be greater than zero`, replicas)
+			}
+		}
+	}
+
+	if err := matchWithDormantDatabase(extClient, elasticsearch); err != nil {
+		return err
+	}
+
+	if elasticsearch.Spec.Storage != nil {
+		if err := amv.ValidateStorage(client, elasticsearch.Spec.Storage, util.GetStorage(previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := amv.ValidateResources(client, elasticsearch.Spec.Resources, util.GetResources(previous), elasticsearch.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(elasticsearch.Spec.NodeSelector, elasticsearch.Spec.Affinity, elasticsearch.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, elasticsearch.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), elasticsearch.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateDatabaseSecret(client, api.ResourceKindElasticsearch, elasticsearch.Spec.DatabaseSecret, elasticsearch.Namespace, field.NewPath("spec").Child("databaseSecret")); err != nil {
+		return err
+	}
+
+	certificateSecret := elasticsearch.Spec.CertificateSecret
+	if certificateSecret != nil {
+		if _, err := client.CoreV1().Secrets(elasticsearch.Namespace).Get(certificateSecret.SecretName, metav1.GetOptions{}); err != nil {
+			return err
+		}
+	}
+
+	monitorSpec := elasticsearch.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), elasticsearchPorts...); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, elasticsearch *api.Elasticsearch) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(elasticsearch.Namespace).Get(elasticsearch.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if dormantDb.Labels[api.LabelDatabaseKind] != api.ResourceKindElasticsearch {
+		return fmt.Errorf(`invalid Elasticsearch: "%v". Exists DormantDatabase "%v" of different Kind`, elasticsearch.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.Elasticsearch
+	originalSpec := elasticsearch.Spec
+
+	if originalSpec.DatabaseSecret == nil {
+		originalSpec.DatabaseSecret = &core.SecretVolumeSource{
+			SecretName: elasticsearch.Name + "-auth",
+		}
+	}
+
+	if originalSpec.CertificateSecret == nil {
+		originalSpec.CertificateSecret = &core.SecretVolumeSource{
+			SecretName: elasticsearch.Name + "-cert",
+		}
+	}
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("object spec in Elasticsearch mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the elasticsearch in obj, the pods of all the nodes of
+// its topology if it has one. It returns 0 if obj is not an Elasticsearch.
+func podCount(obj runtime.Object) int32 {
+	elasticsearch, ok := obj.(*api.Elasticsearch)
+	if !ok {
+		return 0
+	}
+	if topology := elasticsearch.Spec.Topology; topology != nil {
+		return amv.PodCount(topology.Master.Replicas, topology.Data.Replicas, topology.Client.Replicas)
+	}
+	return amv.PodCount(elasticsearch.Spec.Replicas)
+}
+
+// certificatesUnchanged reports whether an update of elasticsearch keeps its certificate secret and SSL setting.
+// Certificates are checked only when they are set, so that expired certificates don't block unrelated updates,
+// like the removal of the finalizer by KubeDB operator.
+func certificatesUnchanged(elasticsearch *api.Elasticsearch, oldObject runtime.Object) bool {
+	old, ok := oldObject.(*api.Elasticsearch)
+	return ok &&
+		old.Spec.EnableSSL == elasticsearch.Spec.EnableSSL &&
+		meta_util.Equal(old.Spec.CertificateSecret, elasticsearch.Spec.CertificateSecret)
+}
+
+// checkCertificateSecret checks that the certificate secret of elasticsearch holds valid, unexpired certificates
+// when SSL is enabled. On update, the check is skipped when the certificate secret and SSL setting are not changed.
+func checkCertificateSecret(client kubernetes.Interface, elasticsearch *api.Elasticsearch, oldObject runtime.Object) error {
+	if !elasticsearch.Spec.EnableSSL || elasticsearch.Spec.CertificateSecret == nil || certificatesUnchanged(elasticsearch, oldObject) {
+		return nil
+	}
+	secret, err := client.CoreV1().Secrets(elasticsearch.Namespace).Get(elasticsearch.Spec.CertificateSecret.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return amv.ValidateCertificateSecret(secret, time.Now())
+}
+
+// checkCertificateExpiry returns an error when a certificate of elasticsearch expires within warning. Expired and
+// otherwise invalid certificates are rejected by checkCertificateSecret. On update, the check is skipped when the
+// certificate secret and SSL setting are not changed.
+func checkCertificateExpiry(client kubernetes.Interface, elasticsearch *api.Elasticsearch, oldObject runtime.Object, warning time.Duration) error {
+	if !elasticsearch.Spec.EnableSSL || elasticsearch.Spec.CertificateSecret == nil || certificatesUnchanged(elasticsearch, oldObject) {
+		return nil
+	}
+	secret, err := client.CoreV1().Secrets(elasticsearch.Namespace).Get(elasticsearch.Spec.CertificateSecret.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	certs, err := amv.ParseCertificateSecret(secret)
+	if err != nil {
+		return err
+	}
+	if notAfter := certs.NotAfter(); time.Until(notAfter) < warning {
+		return fmt.Errorf(`certificates in secret "%s" expire at %v`, secret.Name, notAfter.UTC())
+	}
+	return nil
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for elasticsearch.
+func derivedNames(elasticsearch *api.Elasticsearch) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindService, Name: elasticsearch.ServiceName()},
+		{Kind: amv.KindService, Name: elasticsearch.MasterServiceName()},
+	}
+	if topology := elasticsearch.Spec.Topology; topology != nil {
+		for _, node := range []api.ElasticsearchNode{topology.Master, topology.Data, topology.Client} {
+			names = append(names, amv.DerivedName{Kind: amv.KindStatefulSet, Name: nodeStatefulSetName(elasticsearch, node)})
+		}
+	} else {
+		names = append(names, amv.DerivedName{Kind: amv.KindStatefulSet, Name: elasticsearch.OffshootName()})
+	}
+	if elasticsearch.Spec.DatabaseSecret == nil {
+		names = append(names, amv.DerivedName{Kind: amv.KindSecret, Name: elasticsearch.OffshootName() + "-auth"})
+	}
+	if elasticsearch.Spec.CertificateSecret == nil {
+		names = append(names, amv.DerivedName{Kind: amv.KindSecret, Name: elasticsearch.OffshootName() + "-cert"})
+	}
+	names = append(names, amv.MonitorNames(elasticsearch.Spec.Monitor, elasticsearch.ServiceMonitorName())...)
+	if elasticsearch.Spec.BackupSchedule != nil {
+		names = append(names, amv.ScheduledSnapshotNames(elasticsearch.OffshootName())...)
+	}
+	names = append(names, amv.SnapshotLockNames(api.ResourceKindElasticsearch, elasticsearch.Name)...)
+	return names
+}
+
+// nodeStatefulSetName returns the name of the StatefulSet of the nodes of a topology, the database name
+// prefixed with the node prefix.
+func nodeStatefulSetName(elasticsearch *api.Elasticsearch, node api.ElasticsearchNode) string {
+	if node.Prefix == "" {
+		return elasticsearch.OffshootName()
+	}
+	return fmt.Sprintf("%v-%v", node.Prefix, elasticsearch.OffshootName())
+}
@@ -4,14 +4,18 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/appscode/go/types"
 	kubeMon "github.com/appscode/kube-mon/api"
 	"github.com/appscode/kutil/meta"
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
 	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	admission "k8s.io/api/admission/v1beta1"
 	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
 	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
@@ -28,14 +32,28 @@ var requestKind = metaV1.GroupVersionKind{
 	Kind:    api.ResourceKindMemcached,
 }
 
+// clusterObjects returns the objects in the cluster the Memcached validator is tested against.
+func clusterObjects() []runtime.Object {
+	return []runtime.Object{
+		&core.Namespace{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: "default",
+				Annotations: map[string]string{
+					MaxReplicasKey: "3",
+				},
+			},
+		},
+	}
+}
+
 func TestMemcachedValidator_Admit(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.testName, func(t *testing.T) {
-			validator := MemcachedValidator{}
+			validator := NewMemcachedValidator(config.New())
 
 			validator.initialized = true
 			validator.extClient = extFake.NewSimpleClientset()
-			validator.client = fake.NewSimpleClientset()
+			validator.client = fake.NewSimpleClientset(clusterObjects()...)
 
 			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
 			if err != nil {
@@ -184,6 +202,106 @@ var cases = []struct {
 		true,
 		true,
 	},
+	{"Create Memcached without memory limit",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecMemory(sampleMemcached(), ""),
+		api.Memcached{},
+		false,
+		false,
+	},
+	{"Create Memcached with too small memory limit",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecMemory(sampleMemcached(), "64Mi"),
+		api.Memcached{},
+		false,
+		false,
+	},
+	{"Create Memcached with minimum memory limit",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecMemory(sampleMemcached(), "96Mi"),
+		api.Memcached{},
+		false,
+		true,
+	},
+	{"Create Memcached with replicas at namespace cap",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecReplicas(sampleMemcached(), 3),
+		api.Memcached{},
+		false,
+		true,
+	},
+	{"Create Memcached with replicas over namespace cap",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecReplicas(sampleMemcached(), 4),
+		api.Memcached{},
+		false,
+		false,
+	},
+	{"Scale Memcached over namespace cap",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecReplicas(sampleMemcached(), 4),
+		editSpecReplicas(sampleMemcached(), 3),
+		false,
+		false,
+	},
+	{"Scale down Memcached over namespace cap",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecReplicas(sampleMemcached(), 4),
+		editSpecReplicas(sampleMemcached(), 5),
+		false,
+		true,
+	},
+	{"Edit Memcached over namespace cap without scaling",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecDoNotPause(editSpecReplicas(sampleMemcached(), 4)),
+		editSpecReplicas(sampleMemcached(), 4),
+		false,
+		true,
+	},
+	{"Edit Memcached without memory limit",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecDoNotPause(editSpecMemory(sampleMemcached(), "")),
+		editSpecMemory(sampleMemcached(), ""),
+		false,
+		true,
+	},
+	{"Edit Memcached memory limit to too small",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecMemory(sampleMemcached(), "64Mi"),
+		sampleMemcached(),
+		false,
+		false,
+	},
 	{"Delete Non Existing Memcached",
 		requestKind,
 		"foo",
@@ -196,6 +314,73 @@ var cases = []struct {
 	},
 }
 
+// TestMemcachedValidator_Resume checks that a Memcached resuming a DormantDatabase is not denied by the rules for
+// new databases, since it can't change the spec it was paused with.
+func TestMemcachedValidator_Resume(t *testing.T) {
+	for _, c := range resumeCases {
+		t.Run(c.testName, func(t *testing.T) {
+			validator := NewMemcachedValidator(config.New())
+
+			validator.initialized = true
+			validator.client = fake.NewSimpleClientset(clusterObjects()...)
+			validator.extClient = extFake.NewSimpleClientset(dormantMemcached(c.object))
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+			req := new(admission.AdmissionRequest)
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := validator.Admit(req)
+			if response.Allowed != true {
+				t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+			}
+		})
+	}
+}
+
+var resumeCases = []struct {
+	testName string
+	object   api.Memcached
+}{
+	{"Resume Memcached without memory limit",
+		editSpecMemory(sampleMemcached(), ""),
+	},
+	{"Resume Memcached with too small memory limit",
+		editSpecMemory(sampleMemcached(), "32Mi"),
+	},
+	{"Resume Memcached with replicas over namespace cap",
+		editSpecReplicas(sampleMemcached(), 5),
+	},
+}
+
+// dormantMemcached returns the DormantDatabase memcached was paused into.
+func dormantMemcached(memcached api.Memcached) *api.DormantDatabase {
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      memcached.Name,
+			Namespace: memcached.Namespace,
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindMemcached,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				ObjectMeta: memcached.ObjectMeta,
+				Spec: api.OriginSpec{
+					Memcached: &memcached.Spec,
+				},
+			},
+		},
+	}
+}
+
 func sampleMemcached() api.Memcached {
 	return api.Memcached{
 		TypeMeta: metaV1.TypeMeta{
@@ -212,6 +397,11 @@ func sampleMemcached() api.Memcached {
 		Spec: api.MemcachedSpec{
 			Version:    "1.5.4",
 			DoNotPause: true,
+			Resources: core.ResourceRequirements{
+				Limits: core.ResourceList{
+					core.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
 		},
 	}
 }
@@ -253,3 +443,17 @@ func editSpecDoNotPause(old api.Memcached) api.Memcached {
 	old.Spec.DoNotPause = false
 	return old
 }
+
+func editSpecReplicas(old api.Memcached, replicas int32) api.Memcached {
+	old.Spec.Replicas = types.Int32P(replicas)
+	return old
+}
+
+// editSpecMemory sets the memory limit of memcached, or unsets it if limit is empty
+func editSpecMemory(old api.Memcached, limit string) api.Memcached {
+	old.Spec.Resources.Limits = nil
+	if limit != "" {
+		old.Spec.Resources.Limits = core.ResourceList{core.ResourceMemory: resource.MustParse(limit)}
+	}
+	return old
+}
@@ -0,0 +1,159 @@
+package memcached
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/appscode/go/types"
+	mon_api "github.com/appscode/kube-mon/api"
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type MemcachedMutator struct {
+	config      *config.Config
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &MemcachedMutator{}
+
+// NewMemcachedMutator returns the mutator of Memcached configured by c.
+func NewMemcachedMutator(c *config.Config) *MemcachedMutator {
+	return &MemcachedMutator{config: c}
+}
+
+func (a *MemcachedMutator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "memcachedmutationreviews",
+		},
+		"memcachedmutationreview"
+}
+
+func (a *MemcachedMutator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *MemcachedMutator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	// N.B.: No Mutating for delete
+	if (req.Operation != admission.Create && req.Operation != admission.Update) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindMemcached {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+	obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+	if err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	memcachedMod, err := setDefaultValues(a.client, a.extClient, obj.(*api.Memcached).DeepCopy(), req.Operation, a.config.MemcachedDefaultMemory)
+	if err != nil {
+		return hookapi.StatusForbidden(err)
+	} else if memcachedMod != nil {
+		patch, err := meta_util.CreateJSONPatch(obj, memcachedMod)
+		if err != nil {
+			return hookapi.StatusInternalServerError(err)
+		}
+		status.Patch = patch
+		patchType := admission.PatchTypeJSONPatch
+		status.PatchType = &patchType
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// setDefaultValues provides the defaulting that is performed in mutating stage of creating/updating a Memcached database
+func setDefaultValues(client kubernetes.Interface, extClient cs.Interface, memcached *api.Memcached, operation admission.Operation, defaultMemory resource.Quantity) (runtime.Object, error) {
+	// Defaults are taken from DormantDatabase first, so that resuming a database only needs its name.
+	resumed, err := util.SetDefaultsFromDormantDB(extClient.KubedbV1alpha1(), api.ResourceKindMemcached, memcached)
+	if err != nil {
+		return nil, err
+	}
+
+	if memcached.Spec.Version == "" {
+		return nil, fmt.Errorf(`object 'Version' is missing in '%v'`, memcached.Spec)
+	}
+
+	if memcached.Spec.Replicas == nil {
+		memcached.Spec.Replicas = types.Int32P(1)
+	}
+
+	// Memory limit sets the cache size of Memcached, so set the default one if it is not given.
+	// It is set on create only, since changing it rolls the pods, and never for a resumed
+	// database, whose resources must match its DormantDatabase.
+	if operation == admission.Create && !resumed {
+		setDefaultMemory(memcached, defaultMemory)
+	}
+
+	// If monitoring spec is given without port,
+	// set default Listening port
+	setMonitoringPort(memcached)
+
+	return memcached, nil
+}
+
+// setDefaultMemory sets the memory limit of Memcached pods to defaultMemory, or the memory request if it is larger.
+func setDefaultMemory(memcached *api.Memcached, defaultMemory resource.Quantity) {
+	if _, found := memcached.Spec.Resources.Limits[core.ResourceMemory]; found {
+		return
+	}
+	limit := *defaultMemory.Copy()
+	if request, found := memcached.Spec.Resources.Requests[core.ResourceMemory]; found && request.Cmp(limit) > 0 {
+		limit = request
+	}
+	if memcached.Spec.Resources.Limits == nil {
+		memcached.Spec.Resources.Limits = core.ResourceList{}
+	}
+	memcached.Spec.Resources.Limits[core.ResourceMemory] = limit
+}
+
+// Assign Default Monitoring Port if MonitoringSpec Exists
+// and the AgentVendor is Prometheus.
+func setMonitoringPort(memcached *api.Memcached) {
+	if memcached.Spec.Monitor != nil &&
+		memcached.GetMonitoringVendor() == mon_api.VendorPrometheus {
+		if memcached.Spec.Monitor.Prometheus == nil {
+			memcached.Spec.Monitor.Prometheus = &mon_api.PrometheusSpec{}
+		}
+		if memcached.Spec.Monitor.Prometheus.Port == 0 {
+			memcached.Spec.Monitor.Prometheus.Port = api.PrometheusExporterPortNumber
+		}
+	}
+}
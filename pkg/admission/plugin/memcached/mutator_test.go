@@ -0,0 +1,215 @@
+package memcached
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/appscode/go/types"
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMemcachedMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			mutator := NewMemcachedMutator(config.New())
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset()
+			mutator.client = fake.NewSimpleClientset()
+
+			if c.dormantDb != nil {
+				if _, err := mutator.extClient.KubedbV1alpha1().DormantDatabases(c.dormantDb.Namespace).Create(c.dormantDb); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = c.operation
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				patch, err := jsonpatch.DecodePatch(response.Patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modJS, err := patch.Apply(objJS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.Memcached).Spec, c.expected) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected, mod.(*api.Memcached).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				} else if c.dormantDb != nil &&
+					c.dormantDb.Labels[api.LabelDatabaseKind] == api.ResourceKindMemcached &&
+					!strings.Contains(response.Result.Message, "Diff") {
+					t.Errorf("expected diff in response: %v", response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName  string
+	operation admission.Operation
+	object    api.Memcached
+	dormantDb *api.DormantDatabase
+	expected  api.MemcachedSpec
+	result    bool
+}{
+	{"Create Memcached",
+		admission.Create,
+		sampleMemcached(),
+		nil,
+		setReplicas(sampleMemcached()).Spec,
+		true,
+	},
+	{"Create Memcached without memory limit",
+		admission.Create,
+		editSpecMemory(sampleMemcached(), ""),
+		nil,
+		setReplicas(sampleMemcached()).Spec,
+		true,
+	},
+	{"Create Memcached with memory request larger than default limit",
+		admission.Create,
+		editSpecMemoryRequest(editSpecMemory(sampleMemcached(), ""), "256Mi"),
+		nil,
+		editSpecMemoryRequest(editSpecMemory(setReplicas(sampleMemcached()), "256Mi"), "256Mi").Spec,
+		true,
+	},
+	{"Create Memcached without Version",
+		admission.Create,
+		emptyMemcached(),
+		nil,
+		api.MemcachedSpec{},
+		false,
+	},
+	{"Resume Memcached with name and kind only",
+		admission.Create,
+		emptyMemcached(),
+		sampleDormantDatabase(api.ResourceKindMemcached),
+		editSpecDoNotPause(api.Memcached{Spec: dormantMemcachedSpec()}).Spec,
+		true,
+	},
+	{"Resume Memcached with mismatched NodeSelector",
+		admission.Create,
+		editSpecNodeSelector(emptyMemcached()),
+		sampleDormantDatabase(api.ResourceKindMemcached),
+		api.MemcachedSpec{},
+		false,
+	},
+	{"Resume Memcached from DormantDatabase of different kind",
+		admission.Create,
+		emptyMemcached(),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.MemcachedSpec{},
+		false,
+	},
+	{"Update Memcached without memory limit",
+		admission.Update,
+		editSpecMemory(sampleMemcached(), ""),
+		nil,
+		removeMemoryLimit(setReplicas(sampleMemcached())).Spec,
+		true,
+	},
+	{"Resume Memcached without memory limit",
+		admission.Create,
+		emptyMemcached(),
+		removeDormantMemoryLimit(sampleDormantDatabase(api.ResourceKindMemcached)),
+		editSpecDoNotPause(removeMemoryLimit(api.Memcached{Spec: dormantMemcachedSpec()})).Spec,
+		true,
+	},
+}
+
+func emptyMemcached() api.Memcached {
+	memcached := sampleMemcached()
+	memcached.Spec = api.MemcachedSpec{}
+	return memcached
+}
+
+func dormantMemcachedSpec() api.MemcachedSpec {
+	return setReplicas(sampleMemcached()).Spec
+}
+
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	spec := dormantMemcachedSpec()
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Memcached: &spec,
+				},
+			},
+		},
+	}
+}
+
+func removeMemoryLimit(old api.Memcached) api.Memcached {
+	old.Spec.Resources.Limits = nil
+	return old
+}
+
+func removeDormantMemoryLimit(old *api.DormantDatabase) *api.DormantDatabase {
+	old.Spec.Origin.Spec.Memcached.Resources.Limits = nil
+	return old
+}
+
+func setReplicas(old api.Memcached) api.Memcached {
+	old.Spec.Replicas = types.Int32P(1)
+	return old
+}
+
+func editSpecNodeSelector(old api.Memcached) api.Memcached {
+	old.Spec.NodeSelector = map[string]string{
+		"disktype": "ssd",
+	}
+	return old
+}
+
+func editSpecMemoryRequest(old api.Memcached, request string) api.Memcached {
+	old.Spec.Resources.Requests = core.ResourceList{
+		core.ResourceMemory: resource.MustParse(request),
+	}
+	return old
+}
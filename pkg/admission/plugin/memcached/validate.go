@@ -0,0 +1,184 @@
+package memcached
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	memcachedVersions = sets.NewString("1.5", "1.5.4")
+	memcachedPorts    = []int32{11211}
+)
+
+// MaxReplicasKey is the namespace annotation capping the number of replicas of a Memcached in the namespace
+const MaxReplicasKey = api.GenericKey + "/memcached-max-replicas"
+
+// ValidateMemcached validates memcached. oldObject is the Memcached before an update, and previous is oldObject, or the paused
+// database when memcached resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidateMemcached(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, c *config.Config, memcached *api.Memcached, oldObject, previous runtime.Object) error {
+	if memcached.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, memcached.Spec)
+	}
+
+	// check Memcached version validation
+	if !memcachedVersions.Has(string(memcached.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support Memcached version: %s`, string(memcached.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindMemcached, memcached.Name, memcached.Namespace, derivedNames(memcached), oldObject); err != nil {
+		return err
+	}
+
+	if memcached.Spec.Replicas != nil {
+		replicas := types.Int32(memcached.Spec.Replicas)
+		if replicas < 1 {
+			return fmt.Errorf(`spec.replicas "%d" invalid`, replicas)
+		}
+	}
+
+	if err := checkMaxReplicas(client, memcached, previous); err != nil {
+		return err
+	}
+
+	if err := matchWithDormantDatabase(extClient, memcached); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateResources(client, memcached.Spec.Resources, util.GetResources(previous), memcached.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := validateMemory(c, memcached, previous); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(memcached.Spec.NodeSelector, memcached.Spec.Affinity, memcached.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, memcached.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), memcached.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	monitorSpec := memcached.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), memcachedPorts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMemory checks that the memory limit of memcached leaves a cache of the minimum size of c. Memcached
+// uses all the memory up to its limit, so a pod without a limit, or with a cache too small for the
+// memory it needs outside of the cache, is killed for running out of memory. On update, the check is skipped when
+// the resources are not changed, so that Memcacheds created before the check can still be edited.
+func validateMemory(c *config.Config, memcached *api.Memcached, oldObject runtime.Object) error {
+	if old, ok := oldObject.(*api.Memcached); ok && meta_util.Equal(old.Spec.Resources, memcached.Spec.Resources) {
+		return nil
+	}
+	limit, found := memcached.Spec.Resources.Limits[core.ResourceMemory]
+	if !found {
+		return errors.New("spec.resources.limits.memory is required, as it sets the cache size of Memcached")
+	}
+	cacheSize := limit.Copy()
+	cacheSize.Sub(c.MemcachedMemoryOverhead)
+	if cacheSize.Cmp(c.MemcachedMinCacheSize) < 0 {
+		return fmt.Errorf(`spec.resources.limits.memory "%s" invalid. Memcached uses %s outside of its cache, which leaves a cache of %s, but at least %s is required`,
+			limit.String(), c.MemcachedMemoryOverhead.String(), cacheSize.String(), c.MemcachedMinCacheSize.String())
+	}
+	return nil
+}
+
+// checkMaxReplicas checks the replicas of memcached against the cap set by the annotation
+// MaxReplicasKey of its namespace. There is no cap if the annotation is not set. On update, the check is skipped
+// unless the replicas are increased, so that lowering the cap doesn't block edits or scaling down.
+func checkMaxReplicas(client kubernetes.Interface, memcached *api.Memcached, oldObject runtime.Object) error {
+	if old, ok := oldObject.(*api.Memcached); ok && amv.PodCount(memcached.Spec.Replicas) <= amv.PodCount(old.Spec.Replicas) {
+		return nil
+	}
+	ns, err := client.CoreV1().Namespaces().Get(memcached.Namespace, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	v, found := ns.Annotations[MaxReplicasKey]
+	if !found {
+		return nil
+	}
+	maxReplicas, err := strconv.Atoi(v)
+	if err != nil || maxReplicas < 1 {
+		return fmt.Errorf(`annotation %s of namespace "%s" must be a positive integer, found "%s"`, MaxReplicasKey, memcached.Namespace, v)
+	}
+	if replicas := types.Int32(memcached.Spec.Replicas); int(replicas) > maxReplicas {
+		return fmt.Errorf(`spec.replicas "%d" invalid. Namespace "%s" allows at most %d replicas`, replicas, memcached.Namespace, maxReplicas)
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, memcached *api.Memcached) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(memcached.Namespace).Get(memcached.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if dormantDb.Labels[api.LabelDatabaseKind] != api.ResourceKindMemcached {
+		return fmt.Errorf(`invalid Memcached: "%v". Exists DormantDatabase "%v" of different Kind`, memcached.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.Memcached
+	originalSpec := memcached.Spec
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("memcached spec mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the memcached in obj, or 0 if obj is not a Memcached.
+func podCount(obj runtime.Object) int32 {
+	memcached, ok := obj.(*api.Memcached)
+	if !ok {
+		return 0
+	}
+	return amv.PodCount(memcached.Spec.Replicas)
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for memcached.
+func derivedNames(memcached *api.Memcached) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindDeployment, Name: memcached.OffshootName()},
+		{Kind: amv.KindService, Name: memcached.ServiceName()},
+	}
+	names = append(names, amv.MonitorNames(memcached.Spec.Monitor, memcached.ServiceMonitorName())...)
+	return names
+}
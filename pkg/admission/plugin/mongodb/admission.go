@@ -0,0 +1,163 @@
+package mongodb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	admission "k8s.io/api/admission/v1beta1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type MongoDBValidator struct {
+	config      *config.Config
+	prober      *bucket.Prober
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &MongoDBValidator{}
+
+// NewMongoDBValidator returns the validator of MongoDB configured by c, that checks the access to backup buckets with
+// prober.
+func NewMongoDBValidator(c *config.Config, prober *bucket.Prober) *MongoDBValidator {
+	return &MongoDBValidator{config: c, prober: prober}
+}
+
+func (a *MongoDBValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "mongodbvalidationreviews",
+		},
+		"mongodbvalidationreview"
+}
+
+func (a *MongoDBValidator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *MongoDBValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	if (req.Operation != admission.Create && req.Operation != admission.Update && req.Operation != admission.Delete) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindMongoDB {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+
+	switch req.Operation {
+	case admission.Delete:
+		// req.Object.Raw = nil, so read from kubernetes
+		obj, err := a.extClient.KubedbV1alpha1().MongoDBs(req.Namespace).Get(req.Name, metav1.GetOptions{})
+		if err != nil && !kerr.IsNotFound(err) {
+			return hookapi.StatusInternalServerError(err)
+		} else if err == nil && obj.Spec.DoNotPause {
+			return hookapi.StatusBadRequest(fmt.Errorf(`mongodb "%s" can't be paused. To continue delete, unset spec.doNotPause and retry`, req.Name))
+		}
+	default:
+		obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+		if err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+		var oldObject runtime.Object
+		if req.Operation == admission.Update {
+			// validate changes made by user
+			oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
+			if err != nil {
+				return hookapi.StatusBadRequest(err)
+			}
+
+			mongodb := obj.(*api.MongoDB).DeepCopy()
+			oldMongoDB := oldObject.(*api.MongoDB).DeepCopy()
+			// Allow changing Database Secret only if there was no secret have set up yet.
+			if oldMongoDB.Spec.DatabaseSecret == nil {
+				oldMongoDB.Spec.DatabaseSecret = mongodb.Spec.DatabaseSecret
+			}
+
+			if err := util.ValidateUpdate(mongodb, oldMongoDB, req.Kind.Kind); err != nil {
+				return hookapi.StatusBadRequest(fmt.Errorf("%v", err))
+			}
+		}
+		// only KubeDB operator may set the labels and annotations reserved for it
+		if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// a database resuming a DormantDatabase is checked against the paused database, as on an update
+		previous := oldObject
+		if req.Operation == admission.Create {
+			if previous, err = util.GetPausedDatabase(a.extClient.KubedbV1alpha1(), req.Kind.Kind, obj); err != nil {
+				return hookapi.StatusInternalServerError(err)
+			}
+		}
+		deadline := time.Now().Add(a.config.BucketProbeTimeout())
+		// validate database specs
+		if err = ValidateMongoDB(a.client, a.extClient.KubedbV1alpha1(), a.prober, obj.(*api.MongoDB), oldObject, previous, deadline); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// validate backup schedule, and report the upcoming backups back to the user
+		nextBackups, err := amv.ValidateBackupSchedule(a.client, a.prober, obj.(*api.MongoDB).Spec.BackupSchedule, util.GetBackupSchedule(previous), req.Namespace, a.config.MinBackupInterval, deadline)
+		if err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		util.AppendMessage(status, amv.BackupScheduleMessage(nextBackups))
+		spec := obj.(*api.MongoDB).Spec
+		// check the passwords of the auth secret against the policy of the namespace
+		if err := amv.CheckPasswordStrength(a.client, api.ResourceKindMongoDB, spec.DatabaseSecret, req.Namespace, previous); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that a node can run the database pods
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckSchedulable(a.client, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the nodes have enough resources for the database pods
+		if err := util.ApplyPolicy(req, status, a.config.CapacityPolicy, amv.CheckCapacity(a.client, podCount(obj), podCount(oldObject), spec.Resources, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of the database are bound where its pods can run
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckStorageBinding(a.client, spec.Storage, spec.NodeSelector, spec.Affinity, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of a production database are kept when their claims are deleted
+		if err := util.ApplyPolicy(req, status, config.PolicyWarn, amv.CheckReclaimPolicy(a.client, spec.Storage, req.Namespace, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+	}
+	status.Allowed = true
+	return status
+}
@@ -0,0 +1,342 @@
+package mongodb
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/appscode/go/types"
+	kubeMon "github.com/appscode/kube-mon/api"
+	"github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	storageV1beta1 "k8s.io/api/storage/v1beta1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+}
+
+var requestKind = metaV1.GroupVersionKind{
+	Group:   api.SchemeGroupVersion.Group,
+	Version: api.SchemeGroupVersion.Version,
+	Kind:    api.ResourceKindMongoDB,
+}
+
+func TestMongoDBValidator_Admit(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			validator := NewMongoDBValidator(admissionConfig, bucket.New(admissionConfig))
+
+			validator.initialized = true
+			validator.extClient = extFake.NewSimpleClientset()
+			validator.client = fake.NewSimpleClientset(
+				&core.Secret{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "foo-auth",
+						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindMongoDB,
+						},
+					},
+					Data: map[string][]byte{
+						"user":     []byte("root"),
+						"password": []byte("mongodb-password"),
+					},
+				},
+				&storageV1beta1.StorageClass{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name: "standard",
+					},
+				},
+			)
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+			oldObjJS, err := meta.MarshalToJson(&c.oldObject, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = c.kind
+			req.Name = c.objectName
+			req.Namespace = c.namespace
+			req.Operation = c.operation
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+			req.OldObject.Raw = oldObjJS
+
+			if c.heatUp {
+				if _, err := validator.extClient.KubedbV1alpha1().MongoDBs(c.namespace).Create(&c.object); err != nil && !kerr.IsAlreadyExists(err) {
+					t.Error(err)
+				}
+			}
+			if c.operation == admission.Delete {
+				req.Object = runtime.RawExtension{}
+			}
+			if c.operation != admission.Update {
+				req.OldObject = runtime.RawExtension{}
+			}
+
+			response := validator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+
+}
+
+var cases = []struct {
+	testName   string
+	kind       metaV1.GroupVersionKind
+	objectName string
+	namespace  string
+	operation  admission.Operation
+	object     api.MongoDB
+	oldObject  api.MongoDB
+	heatUp     bool
+	result     bool
+}{
+	{"Create Valid MongoDB",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		sampleMongoDB(),
+		api.MongoDB{},
+		false,
+		true,
+	},
+	{"Create Invalid MongoDB",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		getAwkwardMongoDB(),
+		api.MongoDB{},
+		false,
+		false,
+	},
+	{"Set Spec.DatabaseSecret",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecSecret(sampleMongoDB(), "foo-auth"),
+		sampleMongoDB(),
+		false,
+		true,
+	},
+	{"Edit MongoDB Spec.DatabaseSecret",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecSecret(sampleMongoDB(), "bar-auth"),
+		editSpecSecret(sampleMongoDB(), "foo-auth"),
+		false,
+		false,
+	},
+	{"Edit Status",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editStatus(sampleMongoDB()),
+		sampleMongoDB(),
+		false,
+		true,
+	},
+	{"Edit Spec.Monitor",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecMonitor(sampleMongoDB()),
+		sampleMongoDB(),
+		false,
+		true,
+	},
+	{"Edit Invalid Spec.Monitor",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecInvalidMonitor(sampleMongoDB()),
+		sampleMongoDB(),
+		false,
+		false,
+	},
+	{"Edit Spec.DoNotPause",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editSpecDoNotPause(sampleMongoDB()),
+		sampleMongoDB(),
+		false,
+		true,
+	},
+	{"Delete MongoDB when Spec.DoNotPause=true",
+		requestKind,
+		"foo",
+		"default",
+		admission.Delete,
+		sampleMongoDB(),
+		api.MongoDB{},
+		true,
+		false,
+	},
+	{"Delete MongoDB when Spec.DoNotPause=false",
+		requestKind,
+		"foo",
+		"default",
+		admission.Delete,
+		editSpecDoNotPause(sampleMongoDB()),
+		api.MongoDB{},
+		true,
+		true,
+	},
+	{"Create standalone MongoDB with replicas",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecReplicas(sampleMongoDB(), 3),
+		api.MongoDB{},
+		false,
+		false,
+	},
+	{"Create MongoDB with read-only storage",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecAccessModes(sampleMongoDB(), core.ReadOnlyMany),
+		api.MongoDB{},
+		false,
+		false,
+	},
+	{"Delete Non Existing MongoDB",
+		requestKind,
+		"foo",
+		"default",
+		admission.Delete,
+		api.MongoDB{},
+		api.MongoDB{},
+		false,
+		true,
+	},
+}
+
+func sampleMongoDB() api.MongoDB {
+	return api.MongoDB{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindMongoDB,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindMongoDB,
+			},
+		},
+		Spec: api.MongoDBSpec{
+			Version:    "3.6",
+			Replicas:   types.Int32P(1),
+			DoNotPause: true,
+			Storage: &core.PersistentVolumeClaimSpec{
+				StorageClassName: types.StringP("standard"),
+				Resources: core.ResourceRequirements{
+					Requests: core.ResourceList{
+						core.ResourceStorage: resource.MustParse("100Mi"),
+					},
+				},
+			},
+			Init: &api.InitSpec{
+				ScriptSource: &api.ScriptSourceSpec{
+					VolumeSource: core.VolumeSource{
+						GitRepo: &core.GitRepoVolumeSource{
+							Repository: "https://github.com/kubedb/mongodb-init-scripts.git",
+							Directory:  ".",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getAwkwardMongoDB() api.MongoDB {
+	mongodb := sampleMongoDB()
+	mongodb.Spec.Version = "3.0"
+	return mongodb
+}
+
+func editSpecSecret(old api.MongoDB, name string) api.MongoDB {
+	old.Spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: name,
+	}
+	return old
+}
+
+func editStatus(old api.MongoDB) api.MongoDB {
+	old.Status = api.MongoDBStatus{
+		Phase: api.DatabasePhaseCreating,
+	}
+	return old
+}
+
+func editSpecMonitor(old api.MongoDB) api.MongoDB {
+	old.Spec.Monitor = &kubeMon.AgentSpec{
+		Agent: kubeMon.AgentPrometheusBuiltin,
+	}
+	return old
+}
+
+// should be failed because more fields required for COreOS Monitoring
+func editSpecInvalidMonitor(old api.MongoDB) api.MongoDB {
+	old.Spec.Monitor = &kubeMon.AgentSpec{
+		Agent: kubeMon.AgentCoreOSPrometheus,
+	}
+	return old
+}
+
+func editSpecDoNotPause(old api.MongoDB) api.MongoDB {
+	old.Spec.DoNotPause = false
+	return old
+}
+
+func editSpecReplicas(old api.MongoDB, replicas int32) api.MongoDB {
+	old.Spec.Replicas = types.Int32P(replicas)
+	return old
+}
+
+func editSpecAccessModes(old api.MongoDB, modes ...core.PersistentVolumeAccessMode) api.MongoDB {
+	old.Spec.Storage.AccessModes = modes
+	return old
+}
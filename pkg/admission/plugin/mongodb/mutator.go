@@ -0,0 +1,127 @@
+package mongodb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/appscode/go/types"
+	mon_api "github.com/appscode/kube-mon/api"
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type MongoDBMutator struct {
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &MongoDBMutator{}
+
+func (a *MongoDBMutator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "mongodbmutationreviews",
+		},
+		"mongodbmutationreview"
+}
+
+func (a *MongoDBMutator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *MongoDBMutator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	// N.B.: No Mutating for delete
+	if (req.Operation != admission.Create && req.Operation != admission.Update) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindMongoDB {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+	obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+	if err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	mongodbMod, err := setDefaultValues(a.client, a.extClient, obj.(*api.MongoDB).DeepCopy())
+	if err != nil {
+		return hookapi.StatusForbidden(err)
+	} else if mongodbMod != nil {
+		patch, err := meta_util.CreateJSONPatch(obj, mongodbMod)
+		if err != nil {
+			return hookapi.StatusInternalServerError(err)
+		}
+		status.Patch = patch
+		patchType := admission.PatchTypeJSONPatch
+		status.PatchType = &patchType
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// setDefaultValues provides the defaulting that is performed in mutating stage of creating/updating a MongoDB database
+func setDefaultValues(client kubernetes.Interface, extClient cs.Interface, mongodb *api.MongoDB) (runtime.Object, error) {
+	// Defaults are taken from DormantDatabase first, so that resuming a database only needs its name.
+	if _, err := util.SetDefaultsFromDormantDB(extClient.KubedbV1alpha1(), api.ResourceKindMongoDB, mongodb); err != nil {
+		return nil, err
+	}
+
+	if mongodb.Spec.Version == "" {
+		return nil, fmt.Errorf(`object 'Version' is missing in '%v'`, mongodb.Spec)
+	}
+
+	if mongodb.Spec.Replicas == nil {
+		mongodb.Spec.Replicas = types.Int32P(1)
+	}
+
+	// If monitoring spec is given without port,
+	// set default Listening port
+	setMonitoringPort(mongodb)
+
+	return mongodb, nil
+}
+
+// Assign Default Monitoring Port if MonitoringSpec Exists
+// and the AgentVendor is Prometheus.
+func setMonitoringPort(mongodb *api.MongoDB) {
+	if mongodb.Spec.Monitor != nil &&
+		mongodb.GetMonitoringVendor() == mon_api.VendorPrometheus {
+		if mongodb.Spec.Monitor.Prometheus == nil {
+			mongodb.Spec.Monitor.Prometheus = &mon_api.PrometheusSpec{}
+		}
+		if mongodb.Spec.Monitor.Prometheus.Port == 0 {
+			mongodb.Spec.Monitor.Prometheus.Port = api.PrometheusExporterPortNumber
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package mongodb
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/appscode/go/types"
+	kubeMon "github.com/appscode/kube-mon/api"
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMongoDBMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			mutator := MongoDBMutator{}
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset()
+			mutator.client = fake.NewSimpleClientset()
+
+			if c.dormantDb != nil {
+				if _, err := mutator.extClient.KubedbV1alpha1().DormantDatabases(c.dormantDb.Namespace).Create(c.dormantDb); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				patch, err := jsonpatch.DecodePatch(response.Patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modJS, err := patch.Apply(objJS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.MongoDB).Spec, c.expected) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected, mod.(*api.MongoDB).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				} else if c.dormantDb != nil &&
+					c.dormantDb.Labels[api.LabelDatabaseKind] == api.ResourceKindMongoDB &&
+					!strings.Contains(response.Result.Message, "Diff") {
+					t.Errorf("expected diff in response: %v", response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName  string
+	object    api.MongoDB
+	dormantDb *api.DormantDatabase
+	expected  api.MongoDBSpec
+	result    bool
+}{
+	{"Create MongoDB",
+		unsetReplicas(sampleMongoDB()),
+		nil,
+		sampleMongoDB().Spec,
+		true,
+	},
+	{"Create MongoDB with Spec.Monitor",
+		editSpecMonitor(sampleMongoDB()),
+		nil,
+		editSpecMonitorPort(editSpecMonitor(sampleMongoDB())).Spec,
+		true,
+	},
+	{"Create MongoDB without Version",
+		emptyMongoDB(),
+		nil,
+		api.MongoDBSpec{},
+		false,
+	},
+	{"Resume MongoDB with name and kind only",
+		emptyMongoDB(),
+		sampleDormantDatabase(api.ResourceKindMongoDB),
+		editSpecDoNotPause(api.MongoDB{Spec: dormantMongoDBSpec()}).Spec,
+		true,
+	},
+	{"Resume MongoDB with partial Storage",
+		editSpecStorageClass(emptyMongoDB()),
+		sampleDormantDatabase(api.ResourceKindMongoDB),
+		editSpecDoNotPause(api.MongoDB{Spec: dormantMongoDBSpec()}).Spec,
+		true,
+	},
+	{"Resume MongoDB with new Spec.Monitor",
+		editSpecMonitor(emptyMongoDB()),
+		sampleDormantDatabase(api.ResourceKindMongoDB),
+		editSpecMonitorPort(editSpecMonitor(editSpecDoNotPause(api.MongoDB{Spec: dormantMongoDBSpec()}))).Spec,
+		true,
+	},
+	{"Resume MongoDB with mismatched Storage",
+		editSpecStorage(emptyMongoDB()),
+		sampleDormantDatabase(api.ResourceKindMongoDB),
+		api.MongoDBSpec{},
+		false,
+	},
+	{"Resume MongoDB from DormantDatabase of different kind",
+		emptyMongoDB(),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.MongoDBSpec{},
+		false,
+	},
+}
+
+func emptyMongoDB() api.MongoDB {
+	mongodb := sampleMongoDB()
+	mongodb.Spec = api.MongoDBSpec{}
+	return mongodb
+}
+
+func dormantMongoDBSpec() api.MongoDBSpec {
+	spec := sampleMongoDB().Spec
+	spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: "foo-auth",
+	}
+	spec.Monitor = &kubeMon.AgentSpec{
+		Agent: kubeMon.AgentCoreOSPrometheus,
+		Prometheus: &kubeMon.PrometheusSpec{
+			Port: api.PrometheusExporterPortNumber,
+		},
+	}
+	return spec
+}
+
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	spec := dormantMongoDBSpec()
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					MongoDB: &spec,
+				},
+			},
+		},
+	}
+}
+
+func unsetReplicas(old api.MongoDB) api.MongoDB {
+	old.Spec.Replicas = nil
+	return old
+}
+
+func editSpecMonitorPort(old api.MongoDB) api.MongoDB {
+	old.Spec.Monitor.Prometheus = &kubeMon.PrometheusSpec{
+		Port: api.PrometheusExporterPortNumber,
+	}
+	return old
+}
+
+func editSpecStorageClass(old api.MongoDB) api.MongoDB {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		StorageClassName: types.StringP("standard"),
+	}
+	return old
+}
+
+func editSpecStorage(old api.MongoDB) api.MongoDB {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	return old
+}
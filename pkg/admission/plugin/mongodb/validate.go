@@ -0,0 +1,143 @@
+package mongodb
+
+import (
+	"fmt"
+	"time"
+
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	mongodbVersions = sets.NewString("3.4", "3.6")
+	mongodbPorts    = []int32{27017}
+)
+
+// ValidateMongoDB validates mongodb. oldObject is the MongoDB before an update, and previous is oldObject, or the paused
+// database when mongodb resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidateMongoDB(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, prober *bucket.Prober, mongodb *api.MongoDB, oldObject, previous runtime.Object, deadline time.Time) error {
+	if mongodb.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, mongodb.Spec)
+	}
+
+	// Check MongoDB version validation
+	if !mongodbVersions.Has(string(mongodb.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support MongoDB version: %s`, string(mongodb.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindMongoDB, mongodb.Name, mongodb.Namespace, derivedNames(mongodb), oldObject); err != nil {
+		return err
+	}
+
+	if mongodb.Spec.Replicas == nil || *mongodb.Spec.Replicas != 1 {
+		return fmt.Errorf(`spec.replicas "%v" invalid. Value must be one`, mongodb.Spec.Replicas)
+	}
+
+	if mongodb.Spec.Storage != nil {
+		var err error
+		if err = amv.ValidateStorage(client, mongodb.Spec.Storage, util.GetStorage(previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := amv.ValidateResources(client, mongodb.Spec.Resources, util.GetResources(previous), mongodb.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(mongodb.Spec.NodeSelector, mongodb.Spec.Affinity, mongodb.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, mongodb.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), mongodb.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateDatabaseSecret(client, api.ResourceKindMongoDB, mongodb.Spec.DatabaseSecret, mongodb.Namespace, field.NewPath("spec").Child("databaseSecret")); err != nil {
+		return err
+	}
+
+	monitorSpec := mongodb.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), mongodbPorts...); err != nil {
+			return err
+		}
+	}
+
+	if err := matchWithDormantDatabase(extClient, mongodb); err != nil {
+		return err
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, mongodb *api.MongoDB) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(mongodb.Namespace).Get(mongodb.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if value, _ := meta_util.GetStringValue(dormantDb.Labels, api.LabelDatabaseKind); value != api.ResourceKindMongoDB {
+		return fmt.Errorf(`invalid MongoDB: "%v". Exists DormantDatabase "%v" of different Kind`, mongodb.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.MongoDB
+	originalSpec := mongodb.Spec
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	// Skip checking Monitoring
+	drmnOriginSpec.Monitor = originalSpec.Monitor
+
+	// Skip Checking BackUP Scheduler
+	drmnOriginSpec.BackupSchedule = originalSpec.BackupSchedule
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("mongodb spec mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the mongodb in obj, or 0 if obj is not a MongoDB.
+func podCount(obj runtime.Object) int32 {
+	mongodb, ok := obj.(*api.MongoDB)
+	if !ok {
+		return 0
+	}
+	return amv.PodCount(mongodb.Spec.Replicas)
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for mongodb.
+func derivedNames(mongodb *api.MongoDB) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindStatefulSet, Name: mongodb.OffshootName()},
+		{Kind: amv.KindService, Name: mongodb.ServiceName()},
+	}
+	if mongodb.Spec.DatabaseSecret == nil {
+		names = append(names, amv.DerivedName{Kind: amv.KindSecret, Name: mongodb.OffshootName() + "-auth"})
+	}
+	names = append(names, amv.MonitorNames(mongodb.Spec.Monitor, mongodb.ServiceMonitorName())...)
+	if mongodb.Spec.BackupSchedule != nil {
+		names = append(names, amv.ScheduledSnapshotNames(mongodb.OffshootName())...)
+	}
+	names = append(names, amv.SnapshotLockNames(api.ResourceKindMongoDB, mongodb.Name)...)
+	return names
+}
@@ -10,6 +10,8 @@ import (
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
 	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	admission "k8s.io/api/admission/v1beta1"
 	authenticationV1 "k8s.io/api/authentication/v1"
 	core "k8s.io/api/core/v1"
@@ -35,7 +37,8 @@ var requestKind = metaV1.GroupVersionKind{
 func TestMySQLValidator_Admit(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.testName, func(t *testing.T) {
-			validator := MySQLValidator{}
+			admissionConfig := config.New()
+			validator := NewMySQLValidator(admissionConfig, bucket.New(admissionConfig))
 
 			validator.initialized = true
 			validator.extClient = extFake.NewSimpleClientset()
@@ -44,6 +47,9 @@ func TestMySQLValidator_Admit(t *testing.T) {
 					ObjectMeta: metaV1.ObjectMeta{
 						Name:      "foo-auth",
 						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindMySQL,
+						},
 					},
 				},
 				&storageV1beta1.StorageClass{
@@ -180,6 +186,26 @@ var cases = []struct {
 		false,
 		true,
 	},
+	{"Edit reserved label",
+		requestKind,
+		"foo",
+		"default",
+		admission.Update,
+		editLabel(sampleMySQL(), api.LabelDatabaseKind, api.ResourceKindPostgres),
+		sampleMySQL(),
+		false,
+		false,
+	},
+	{"Create MySQL with initialized annotation",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editAnnotation(sampleMySQL(), api.AnnotationInitialized, ""),
+		api.MySQL{},
+		false,
+		false,
+	},
 	{"Delete MySQL when Spec.DoNotPause=true",
 		requestKind,
 		"foo",
@@ -289,3 +315,13 @@ func editSpecDoNotPause(old api.MySQL) api.MySQL {
 	old.Spec.DoNotPause = false
 	return old
 }
+
+func editLabel(old api.MySQL, key, value string) api.MySQL {
+	old.Labels = map[string]string{key: value}
+	return old
+}
+
+func editAnnotation(old api.MySQL, key, value string) api.MySQL {
+	old.Annotations = map[string]string{key: value}
+	return old
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/appscode/go/types"
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMySQLMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			mutator := MySQLMutator{}
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset()
+			mutator.client = fake.NewSimpleClientset()
+
+			if c.dormantDb != nil {
+				if _, err := mutator.extClient.KubedbV1alpha1().DormantDatabases(c.dormantDb.Namespace).Create(c.dormantDb); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				patch, err := jsonpatch.DecodePatch(response.Patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modJS, err := patch.Apply(objJS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.MySQL).Spec, c.expected) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected, mod.(*api.MySQL).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				} else if c.dormantDb != nil &&
+					c.dormantDb.Labels[api.LabelDatabaseKind] == api.ResourceKindMySQL &&
+					!strings.Contains(response.Result.Message, "Diff") {
+					t.Errorf("expected diff in response: %v", response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName  string
+	object    api.MySQL
+	dormantDb *api.DormantDatabase
+	expected  api.MySQLSpec
+	result    bool
+}{
+	{"Create MySQL",
+		sampleMySQL(),
+		nil,
+		setReplicas(sampleMySQL()).Spec,
+		true,
+	},
+	{"Create MySQL without Version",
+		emptyMySQL(),
+		nil,
+		api.MySQLSpec{},
+		false,
+	},
+	{"Resume MySQL with name and kind only",
+		emptyMySQL(),
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		editSpecDoNotPause(api.MySQL{Spec: dormantMySQLSpec()}).Spec,
+		true,
+	},
+	{"Resume MySQL with partial Storage",
+		editSpecStorageClass(emptyMySQL()),
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		editSpecDoNotPause(api.MySQL{Spec: dormantMySQLSpec()}).Spec,
+		true,
+	},
+	{"Resume MySQL with mismatched Storage",
+		editSpecStorage(emptyMySQL()),
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		api.MySQLSpec{},
+		false,
+	},
+	{"Resume MySQL from DormantDatabase of different kind",
+		emptyMySQL(),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.MySQLSpec{},
+		false,
+	},
+}
+
+func emptyMySQL() api.MySQL {
+	mysql := sampleMySQL()
+	mysql.Spec = api.MySQLSpec{}
+	return mysql
+}
+
+func dormantMySQLSpec() api.MySQLSpec {
+	spec := setReplicas(sampleMySQL()).Spec
+	spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: "foo-auth",
+	}
+	return spec
+}
+
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	spec := dormantMySQLSpec()
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					MySQL: &spec,
+				},
+			},
+		},
+	}
+}
+
+func setReplicas(old api.MySQL) api.MySQL {
+	old.Spec.Replicas = types.Int32P(1)
+	return old
+}
+
+func editSpecStorageClass(old api.MySQL) api.MySQL {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		StorageClassName: types.StringP("standard"),
+	}
+	return old
+}
+
+func editSpecStorage(old api.MySQL) api.MySQL {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	return old
+}
@@ -0,0 +1,149 @@
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	mysqlVersions = sets.NewString("8.0", "8")
+	mysqlPorts    = []int32{3306}
+)
+
+// ValidateMySQL validates mysql. oldObject is the MySQL before an update, and previous is oldObject, or the paused
+// database when mysql resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidateMySQL(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, prober *bucket.Prober, mysql *api.MySQL, oldObject, previous runtime.Object, deadline time.Time) error {
+	if mysql.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, mysql.Spec)
+	}
+
+	// check MySQL version validation
+	if !mysqlVersions.Has(string(mysql.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support MySQL version: %s`, string(mysql.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindMySQL, mysql.Name, mysql.Namespace, derivedNames(mysql), oldObject); err != nil {
+		return err
+	}
+
+	if mysql.Spec.Replicas != nil {
+		replicas := types.Int32(mysql.Spec.Replicas)
+		if replicas != 1 {
+			return fmt.Errorf(`spec.replicas "%d" invalid. Value must be one`, replicas)
+		}
+	}
+
+	if err := matchWithDormantDatabase(extClient, mysql); err != nil {
+		return err
+	}
+
+	if mysql.Spec.Storage != nil {
+		var err error
+		if err = amv.ValidateStorage(client, mysql.Spec.Storage, util.GetStorage(previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := amv.ValidateResources(client, mysql.Spec.Resources, util.GetResources(previous), mysql.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(mysql.Spec.NodeSelector, mysql.Spec.Affinity, mysql.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, mysql.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), mysql.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateDatabaseSecret(client, api.ResourceKindMySQL, mysql.Spec.DatabaseSecret, mysql.Namespace, field.NewPath("spec").Child("databaseSecret")); err != nil {
+		return err
+	}
+
+	monitorSpec := mysql.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), mysqlPorts...); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, mysql *api.MySQL) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(mysql.Namespace).Get(mysql.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if dormantDb.Labels[api.LabelDatabaseKind] != api.ResourceKindMySQL {
+		return fmt.Errorf(`invalid MySQL: "%v". Exists DormantDatabase "%v" of different Kind`, mysql.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.MySQL
+	originalSpec := mysql.Spec
+
+	if originalSpec.DatabaseSecret == nil {
+		originalSpec.DatabaseSecret = &core.SecretVolumeSource{
+			SecretName: mysql.Name + "-auth",
+		}
+	}
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("mysql spec mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the mysql in obj, or 0 if obj is not a MySQL.
+func podCount(obj runtime.Object) int32 {
+	mysql, ok := obj.(*api.MySQL)
+	if !ok {
+		return 0
+	}
+	return amv.PodCount(mysql.Spec.Replicas)
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for mysql.
+func derivedNames(mysql *api.MySQL) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindStatefulSet, Name: mysql.OffshootName()},
+		{Kind: amv.KindService, Name: mysql.ServiceName()},
+	}
+	if mysql.Spec.DatabaseSecret == nil {
+		names = append(names, amv.DerivedName{Kind: amv.KindSecret, Name: mysql.OffshootName() + "-auth"})
+	}
+	names = append(names, amv.MonitorNames(mysql.Spec.Monitor, mysql.ServiceMonitorName())...)
+	if mysql.Spec.BackupSchedule != nil {
+		names = append(names, amv.ScheduledSnapshotNames(mysql.OffshootName())...)
+	}
+	names = append(names, amv.SnapshotLockNames(api.ResourceKindMySQL, mysql.Name)...)
+	return names
+}
@@ -0,0 +1,375 @@
+package offshoot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	admission "k8s.io/api/admission/v1beta1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// PersistentVolumeClaimValidator protects the PVCs of a live KubeDB database.
+type PersistentVolumeClaimValidator struct {
+	validator
+}
+
+// pvcSpecFields are the fields of a PVC users must not change. The rest of the spec is left to Kubernetes, e.g. the
+// PV controller sets spec.volumeName when it binds the claim.
+var pvcSpecFields = []string{"spec.resources", "spec.accessModes", "spec.storageClassName", "spec.selector"}
+
+var _ hookapi.AdmissionHook = &PersistentVolumeClaimValidator{}
+
+// NewPersistentVolumeClaimValidator returns the PersistentVolumeClaimValidator that lets the users privileged by c
+// through.
+func NewPersistentVolumeClaimValidator(c *config.Config) *PersistentVolumeClaimValidator {
+	return &PersistentVolumeClaimValidator{validator{config: c}}
+}
+
+func (a *PersistentVolumeClaimValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "persistentvolumeclaimreviews",
+		},
+		"persistentvolumeclaimreview"
+}
+
+func (a *PersistentVolumeClaimValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	return a.admit(req, schema.GroupKind{Kind: "PersistentVolumeClaim"}, pvcSpecFields,
+		func(namespace, name string) (metav1.Object, error) {
+			return a.client.CoreV1().PersistentVolumeClaims(namespace).Get(name, metav1.GetOptions{})
+		})
+}
+
+// SecretValidator protects the auth secret of a live KubeDB database. Its webhook sees the Secrets of the whole
+// cluster, so it is registered with failurePolicy Ignore and the secrets are unprotected while it is unavailable.
+type SecretValidator struct {
+	validator
+}
+
+var _ hookapi.AdmissionHook = &SecretValidator{}
+
+// NewSecretValidator returns the SecretValidator that lets the users privileged by c through.
+func NewSecretValidator(c *config.Config) *SecretValidator {
+	return &SecretValidator{validator{config: c}}
+}
+
+func (a *SecretValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "secretreviews",
+		},
+		"secretreview"
+}
+
+func (a *SecretValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	return a.admit(req, schema.GroupKind{Kind: "Secret"}, []string{"data", "stringData", "type"},
+		func(namespace, name string) (metav1.Object, error) {
+			return a.client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+		})
+}
+
+// StatefulSetValidator protects the StatefulSets created by KubeDB operator for a live database.
+type StatefulSetValidator struct {
+	validator
+}
+
+var _ hookapi.AdmissionHook = &StatefulSetValidator{}
+
+// NewStatefulSetValidator returns the StatefulSetValidator that lets the users privileged by c through.
+func NewStatefulSetValidator(c *config.Config) *StatefulSetValidator {
+	return &StatefulSetValidator{validator{config: c}}
+}
+
+func (a *StatefulSetValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "statefulsetreviews",
+		},
+		"statefulsetreview"
+}
+
+func (a *StatefulSetValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	return a.admit(req, schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, []string{"spec"},
+		func(namespace, name string) (metav1.Object, error) {
+			return a.client.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+		})
+}
+
+// ConfigMapValidator reserves the Snapshot lock annotation for KubeDB operator and kubedb-server, so that users
+// can't take or release the lock of a database, and protects the lock ConfigMap of a live database. Like
+// SecretValidator, its webhook sees the ConfigMaps of the whole cluster and is registered with failurePolicy Ignore.
+// ConfigMaps not named like a lock are let through by name, without reading anything from kubernetes.
+type ConfigMapValidator struct {
+	validator
+}
+
+var _ hookapi.AdmissionHook = &ConfigMapValidator{}
+
+// NewConfigMapValidator returns the ConfigMapValidator that lets kubedb-server and the users privileged by c through.
+func NewConfigMapValidator(c *config.Config) *ConfigMapValidator {
+	return &ConfigMapValidator{validator{config: c}}
+}
+
+func (a *ConfigMapValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "configmapreviews",
+		},
+		"configmapreview"
+}
+
+func (a *ConfigMapValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	if (req.Operation != admission.Create && req.Operation != admission.Update && req.Operation != admission.Delete) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != "" ||
+		req.Kind.Kind != "ConfigMap" ||
+		!amv.IsSnapshotLockName(req.Name) {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+
+	if a.config.IsPrivileged(req.UserInfo) || a.config.IsServer(req.UserInfo) {
+		status.Allowed = true
+		return status
+	}
+
+	var labels map[string]string
+	switch req.Operation {
+	case admission.Delete:
+		// req.Object.Raw = nil, so read from kubernetes
+		obj, err := a.client.CoreV1().ConfigMaps(req.Namespace).Get(req.Name, metav1.GetOptions{})
+		if err != nil && !kerr.IsNotFound(err) {
+			return hookapi.StatusInternalServerError(err)
+		} else if kerr.IsNotFound(err) {
+			status.Allowed = true
+			return status
+		}
+		labels = obj.Labels
+	case admission.Create, admission.Update:
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+		oldObj := &unstructured.Unstructured{}
+		if req.Operation == admission.Update {
+			if err := oldObj.UnmarshalJSON(req.OldObject.Raw); err != nil {
+				return hookapi.StatusBadRequest(err)
+			}
+		}
+		val, found := obj.GetAnnotations()[amv.SnapshotLockKey]
+		oldVal, oldFound := oldObj.GetAnnotations()[amv.SnapshotLockKey]
+		if val != oldVal || found != oldFound {
+			return hookapi.StatusForbidden(fmt.Errorf(`annotation "%s" of configmap "%s" is reserved for KubeDB and can't be set or removed`,
+				amv.SnapshotLockKey, req.Name))
+		}
+		if req.Operation == admission.Create || !isChanged(obj, oldObj, nil) {
+			status.Allowed = true
+			return status
+		}
+		labels = oldObj.GetLabels()
+	}
+
+	// only the lock ConfigMap is protected, the database may own other ConfigMaps labeled the same
+	if req.Name != amv.SnapshotLockName(labels[api.LabelDatabaseKind], labels[api.LabelDatabaseName]) {
+		status.Allowed = true
+		return status
+	}
+	dbKind, dbName, err := a.findOwner(schema.GroupKind{Kind: "ConfigMap"}, req.Namespace, req.Name, labels)
+	if err != nil {
+		return hookapi.StatusInternalServerError(err)
+	} else if dbKind == "" {
+		status.Allowed = true
+		return status
+	}
+
+	action := "deleted"
+	if req.Operation == admission.Update {
+		action = "modified"
+	}
+	return hookapi.StatusForbidden(fmt.Errorf(`configmap "%s" is used by %s "%s" and can't be %s. To continue, delete the %s first`,
+		req.Name, dbKind, dbName, action, strings.ToLower(dbKind)))
+}
+
+type getFunc func(namespace, name string) (metav1.Object, error)
+
+type validator struct {
+	config      *config.Config
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+func (a *validator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *validator) admit(req *admission.AdmissionRequest, kind schema.GroupKind, specFields []string, get getFunc) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	// No validation on CREATE
+	if (req.Operation != admission.Update && req.Operation != admission.Delete) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != kind.Group ||
+		req.Kind.Kind != kind.Kind {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+
+	if a.config.IsPrivileged(req.UserInfo) {
+		status.Allowed = true
+		return status
+	}
+
+	var labels map[string]string
+	switch req.Operation {
+	case admission.Delete:
+		// req.Object.Raw = nil, so read from kubernetes
+		obj, err := get(req.Namespace, req.Name)
+		if err != nil && !kerr.IsNotFound(err) {
+			return hookapi.StatusInternalServerError(err)
+		} else if kerr.IsNotFound(err) {
+			status.Allowed = true
+			return status
+		}
+		labels = obj.GetLabels()
+	case admission.Update:
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+		oldObj := &unstructured.Unstructured{}
+		if err := oldObj.UnmarshalJSON(req.OldObject.Raw); err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+		if !isChanged(obj, oldObj, specFields) {
+			status.Allowed = true
+			return status
+		}
+		labels = oldObj.GetLabels()
+	}
+
+	dbKind, dbName, err := a.findOwner(kind, req.Namespace, req.Name, labels)
+	if err != nil {
+		return hookapi.StatusInternalServerError(err)
+	} else if dbKind == "" {
+		status.Allowed = true
+		return status
+	}
+
+	action := "deleted"
+	if req.Operation == admission.Update {
+		action = "modified"
+	}
+	return hookapi.StatusForbidden(fmt.Errorf(`%s "%s" is used by %s "%s" and can't be %s. To continue, delete the %s first`,
+		strings.ToLower(kind.Kind), req.Name, dbKind, dbName, action, strings.ToLower(dbKind)))
+}
+
+// isChanged returns true if the update modifies any of the given fields or the KubeDB labels used to find the owner database.
+// Nested fields are given as dot-separated paths.
+func isChanged(obj, oldObj *unstructured.Unstructured, fields []string) bool {
+	for _, key := range []string{api.LabelDatabaseKind, api.LabelDatabaseName} {
+		if obj.GetLabels()[key] != oldObj.GetLabels()[key] {
+			return true
+		}
+	}
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		val, _ := unstructured.NestedFieldCopy(obj.Object, path...)
+		oldVal, _ := unstructured.NestedFieldCopy(oldObj.Object, path...)
+		if !reflect.DeepEqual(val, oldVal) {
+			return true
+		}
+	}
+	return false
+}
+
+// findOwner returns the kind and name of the live database that owns an object. Auth secrets created by
+// KubeDB operator are only labeled with the database kind, so they are matched against spec.databaseSecret.
+// A database that is being deleted is not live: with foreground cascading deletion, the garbage collector deletes
+// its offshoots before it, and must not be denied.
+func (a *validator) findOwner(kind schema.GroupKind, namespace, name string, labels map[string]string) (string, string, error) {
+	dbKind := labels[api.LabelDatabaseKind]
+	switch dbKind {
+	case api.ResourceKindElasticsearch, api.ResourceKindPostgres, api.ResourceKindMongoDB,
+		api.ResourceKindMySQL, api.ResourceKindRedis, api.ResourceKindMemcached:
+	default:
+		return "", "", nil
+	}
+
+	if dbName := labels[api.LabelDatabaseName]; dbName != "" {
+		db, err := util.GetDatabase(a.extClient.KubedbV1alpha1(), dbKind, namespace, dbName)
+		if err != nil {
+			if kerr.IsNotFound(err) {
+				return "", "", nil
+			}
+			return "", "", err
+		}
+		if isDeleting(db.(metav1.Object)) {
+			return "", "", nil
+		}
+		return dbKind, dbName, nil
+	}
+
+	if kind.Kind != "Secret" {
+		return "", "", nil
+	}
+	dbs, err := util.ListDatabases(a.extClient.KubedbV1alpha1(), dbKind, namespace)
+	if err != nil {
+		return "", "", err
+	}
+	for _, db := range dbs {
+		if util.GetDatabaseSecret(db) == name && !isDeleting(db.(metav1.Object)) {
+			return dbKind, db.(metav1.Object).GetName(), nil
+		}
+	}
+	return "", "", nil
+}
+
+// isDeleting returns true if the deletion of a database has started.
+func isDeleting(db metav1.Object) bool {
+	return db.GetDeletionTimestamp() != nil
+}
@@ -0,0 +1,570 @@
+package offshoot
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/appscode/go/types"
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	admission "k8s.io/api/admission/v1beta1"
+	apps "k8s.io/api/apps/v1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+	admissionConfig.BreakGlassGroups = []string{"kubedb:break-glass"}
+}
+
+var (
+	admissionConfig = config.New()
+
+	pvcKind = metaV1.GroupVersionKind{Group: "", Version: "v1", Kind: "PersistentVolumeClaim"}
+	secKind = metaV1.GroupVersionKind{Group: "", Version: "v1", Kind: "Secret"}
+	stsKind = metaV1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	cmKind  = metaV1.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+)
+
+func TestOffshootValidator_Admit(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			var hook hookapi.AdmissionHook
+			var v *validator
+			switch c.kind.Kind {
+			case pvcKind.Kind:
+				pvcValidator := NewPersistentVolumeClaimValidator(admissionConfig)
+				hook, v = pvcValidator, &pvcValidator.validator
+			case secKind.Kind:
+				secretValidator := NewSecretValidator(admissionConfig)
+				hook, v = secretValidator, &secretValidator.validator
+			case stsKind.Kind:
+				stsValidator := NewStatefulSetValidator(admissionConfig)
+				hook, v = stsValidator, &stsValidator.validator
+			case cmKind.Kind:
+				cmValidator := NewConfigMapValidator(admissionConfig)
+				hook, v = cmValidator, &cmValidator.validator
+			}
+
+			v.initialized = true
+			v.extClient = extFake.NewSimpleClientset()
+			v.client = fake.NewSimpleClientset(c.object)
+
+			if c.heatUp {
+				if _, err := v.extClient.KubedbV1alpha1().Postgreses("default").Create(samplePostgres()); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := json.Marshal(c.object)
+			if err != nil {
+				panic(err)
+			}
+			oldObjJS, err := json.Marshal(c.oldObject)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = c.kind
+			req.Name = c.object.(metaV1.Object).GetName()
+			req.Namespace = "default"
+			req.Operation = c.operation
+			req.UserInfo = c.userInfo
+			req.Object.Raw = objJS
+			req.OldObject.Raw = oldObjJS
+
+			if c.operation == admission.Delete {
+				req.Object = runtime.RawExtension{}
+			}
+			if c.operation != admission.Update {
+				req.OldObject = runtime.RawExtension{}
+			}
+
+			response := hook.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+var cases = []struct {
+	testName  string
+	kind      metaV1.GroupVersionKind
+	operation admission.Operation
+	object    runtime.Object
+	oldObject runtime.Object
+	userInfo  authenticationV1.UserInfo
+	heatUp    bool
+	result    bool
+}{
+	{"Delete PVC of live database",
+		pvcKind,
+		admission.Delete,
+		samplePVC(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Delete PVC of paused database",
+		pvcKind,
+		admission.Delete,
+		samplePVC(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		false,
+		true,
+	},
+	{"Delete PVC by operator",
+		pvcKind,
+		admission.Delete,
+		samplePVC(),
+		nil,
+		authenticationV1.UserInfo{Username: admissionConfig.OperatorServiceAccount},
+		true,
+		true,
+	},
+	{"Delete PVC by break-glass group",
+		pvcKind,
+		admission.Delete,
+		samplePVC(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice", Groups: []string{"kubedb:break-glass"}},
+		true,
+		true,
+	},
+	{"Bind PVC of live database",
+		pvcKind,
+		admission.Update,
+		samplePVC(),
+		unbindPVC(samplePVC()),
+		authenticationV1.UserInfo{Username: "system:serviceaccount:kube-system:persistent-volume-binder"},
+		true,
+		true,
+	},
+	{"Edit PVC storage request of live database",
+		pvcKind,
+		admission.Update,
+		editPVCStorage(samplePVC()),
+		samplePVC(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit PVC storage class of live database",
+		pvcKind,
+		admission.Update,
+		editPVCStorageClass(samplePVC()),
+		samplePVC(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit PVC OwnerReference of live database",
+		pvcKind,
+		admission.Update,
+		editOwnerReference(samplePVC()),
+		samplePVC(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		true,
+	},
+	{"Remove PVC label of live database",
+		pvcKind,
+		admission.Update,
+		removeLabels(samplePVC()),
+		samplePVC(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Delete auth Secret of live database",
+		secKind,
+		admission.Delete,
+		sampleSecret(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit auth Secret data of live database",
+		secKind,
+		admission.Update,
+		editSecretData(sampleSecret()),
+		sampleSecret(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit auth Secret OwnerReference of live database",
+		secKind,
+		admission.Update,
+		editOwnerReference(sampleSecret()),
+		sampleSecret(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		true,
+	},
+	{"Delete unlabeled Secret",
+		secKind,
+		admission.Delete,
+		removeLabels(sampleSecret()),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		true,
+	},
+	{"Delete StatefulSet of live database",
+		stsKind,
+		admission.Delete,
+		sampleStatefulSet(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Delete StatefulSet of paused database",
+		stsKind,
+		admission.Delete,
+		sampleStatefulSet(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		false,
+		true,
+	},
+	{"Create ConfigMap with Snapshot lock",
+		cmKind,
+		admission.Create,
+		sampleLockConfigMap(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		false,
+		false,
+	},
+	{"Create ConfigMap with Snapshot lock by kubedb-server",
+		cmKind,
+		admission.Create,
+		sampleLockConfigMap(),
+		nil,
+		authenticationV1.UserInfo{Username: admissionConfig.ServerServiceAccount},
+		true,
+		true,
+	},
+	{"Remove Snapshot lock",
+		cmKind,
+		admission.Update,
+		removeLockAnnotation(sampleLockConfigMap()),
+		sampleLockConfigMap(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit Snapshot lock by operator",
+		cmKind,
+		admission.Update,
+		removeLockAnnotation(sampleLockConfigMap()),
+		sampleLockConfigMap(),
+		authenticationV1.UserInfo{Username: admissionConfig.OperatorServiceAccount},
+		true,
+		true,
+	},
+	{"Remove lock ConfigMap labels of live database",
+		cmKind,
+		admission.Update,
+		removeLabels(sampleLockConfigMap()),
+		sampleLockConfigMap(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Edit lock ConfigMap data",
+		cmKind,
+		admission.Update,
+		editConfigMapData(sampleLockConfigMap()),
+		sampleLockConfigMap(),
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		true,
+	},
+	{"Delete lock ConfigMap of live database",
+		cmKind,
+		admission.Delete,
+		removeLockAnnotation(sampleLockConfigMap()),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		false,
+	},
+	{"Delete lock ConfigMap of paused database",
+		cmKind,
+		admission.Delete,
+		sampleLockConfigMap(),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		false,
+		true,
+	},
+	{"Create other ConfigMap with Snapshot lock annotation",
+		cmKind,
+		admission.Create,
+		renameConfigMap(sampleLockConfigMap()),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		false,
+		true,
+	},
+	{"Delete other ConfigMap of live database",
+		cmKind,
+		admission.Delete,
+		renameConfigMap(sampleLockConfigMap()),
+		nil,
+		authenticationV1.UserInfo{Username: "alice"},
+		true,
+		true,
+	},
+}
+
+// ConfigMaps not named like a lock are let through without reading them from kubernetes.
+func TestConfigMapValidator_SkipsOtherConfigMaps(t *testing.T) {
+	cmValidator := NewConfigMapValidator(admissionConfig)
+	cmValidator.initialized = true
+	cmValidator.extClient = extFake.NewSimpleClientset(samplePostgres())
+	client := fake.NewSimpleClientset(renameConfigMap(sampleLockConfigMap()))
+	cmValidator.client = client
+
+	for _, op := range []admission.Operation{admission.Create, admission.Update, admission.Delete} {
+		req := new(admission.AdmissionRequest)
+		req.Kind = cmKind
+		req.Name = "foo-config"
+		req.Namespace = "default"
+		req.Operation = op
+		req.UserInfo = authenticationV1.UserInfo{Username: "alice"}
+
+		if response := cmValidator.Admit(req); response.Allowed != true {
+			t.Errorf("%s: expected: 'Allowed=true'. but got response: %v", op, response)
+		}
+	}
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("expected no requests to kubernetes, but got: %v", actions)
+	}
+}
+
+func TestValidator_findOwner(t *testing.T) {
+	for _, c := range ownerCases {
+		t.Run(c.testName, func(t *testing.T) {
+			v := validator{config: admissionConfig}
+			v.extClient = extFake.NewSimpleClientset(c.database)
+
+			obj := c.object.(metaV1.Object)
+			gvk := c.object.GetObjectKind().GroupVersionKind()
+			dbKind, dbName, err := v.findOwner(gvk.GroupKind(), obj.GetNamespace(), obj.GetName(), c.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dbName != c.owner {
+				t.Errorf("expected owner: %q, but got: %s %q", c.owner, dbKind, dbName)
+			}
+		})
+	}
+}
+
+var ownerCases = []struct {
+	testName string
+	object   runtime.Object
+	labels   map[string]string
+	database runtime.Object
+	owner    string
+}{
+	{"PVC of live database",
+		samplePVC(),
+		offshootLabels(),
+		samplePostgres(),
+		"foo",
+	},
+	{"PVC of database being deleted",
+		samplePVC(),
+		offshootLabels(),
+		editDeletionTimestamp(samplePostgres()),
+		"",
+	},
+	{"Auth Secret of live database",
+		sampleSecret(),
+		map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres},
+		samplePostgres(),
+		"foo",
+	},
+	{"Auth Secret of database being deleted",
+		sampleSecret(),
+		map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres},
+		editDeletionTimestamp(samplePostgres()),
+		"",
+	},
+}
+
+func samplePostgres() *api.Postgres {
+	return &api.Postgres{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: api.PostgresSpec{
+			Version: "9.6",
+			DatabaseSecret: &core.SecretVolumeSource{
+				SecretName: "foo-auth",
+			},
+		},
+	}
+}
+
+func offshootLabels() map[string]string {
+	return map[string]string{
+		api.LabelDatabaseKind: api.ResourceKindPostgres,
+		api.LabelDatabaseName: "foo",
+	}
+}
+
+func samplePVC() runtime.Object {
+	return &core.PersistentVolumeClaim{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "data-foo-0",
+			Namespace: "default",
+			Labels:    offshootLabels(),
+		},
+		Spec: core.PersistentVolumeClaimSpec{
+			VolumeName: "pv-foo",
+		},
+	}
+}
+
+func sampleSecret() runtime.Object {
+	return &core.Secret{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo-auth",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Data: map[string][]byte{
+			"POSTGRES_PASSWORD": []byte("secret"),
+		},
+	}
+}
+
+func sampleStatefulSet() runtime.Object {
+	return &apps.StatefulSet{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels:    offshootLabels(),
+		},
+	}
+}
+
+func sampleLockConfigMap() runtime.Object {
+	return &core.ConfigMap{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo-postgres-snapshot-lock",
+			Namespace: "default",
+			Labels:    offshootLabels(),
+			Annotations: map[string]string{
+				amv.SnapshotLockKey: `{"holderIdentity":"snapshot-foo","acquireTime":"2018-06-01T00:00:00Z"}`,
+			},
+		},
+	}
+}
+
+func unbindPVC(obj runtime.Object) runtime.Object {
+	obj.(*core.PersistentVolumeClaim).Spec.VolumeName = ""
+	return obj
+}
+
+func editPVCStorage(obj runtime.Object) runtime.Object {
+	obj.(*core.PersistentVolumeClaim).Spec.Resources.Requests = core.ResourceList{
+		core.ResourceStorage: resource.MustParse("2Gi"),
+	}
+	return obj
+}
+
+func editPVCStorageClass(obj runtime.Object) runtime.Object {
+	obj.(*core.PersistentVolumeClaim).Spec.StorageClassName = types.StringP("fast")
+	return obj
+}
+
+func editSecretData(obj runtime.Object) runtime.Object {
+	obj.(*core.Secret).Data["POSTGRES_PASSWORD"] = []byte("changed")
+	return obj
+}
+
+func editOwnerReference(obj runtime.Object) runtime.Object {
+	obj.(metaV1.Object).SetOwnerReferences([]metaV1.OwnerReference{
+		{
+			APIVersion: api.SchemeGroupVersion.String(),
+			Kind:       api.ResourceKindDormantDatabase,
+			Name:       "foo",
+		},
+	})
+	return obj
+}
+
+func editConfigMapData(obj runtime.Object) runtime.Object {
+	obj.(*core.ConfigMap).Data = map[string]string{"foo": "bar"}
+	return obj
+}
+
+func removeLockAnnotation(obj runtime.Object) runtime.Object {
+	obj.(metaV1.Object).SetAnnotations(nil)
+	return obj
+}
+
+func renameConfigMap(obj runtime.Object) runtime.Object {
+	obj.(metaV1.Object).SetName("foo-config")
+	return obj
+}
+
+func removeLabels(obj runtime.Object) runtime.Object {
+	obj.(metaV1.Object).SetLabels(nil)
+	return obj
+}
+
+func editDeletionTimestamp(old *api.Postgres) *api.Postgres {
+	now := metaV1.Now()
+	old.DeletionTimestamp = &now
+	return old
+}
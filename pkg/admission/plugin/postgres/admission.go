@@ -3,22 +3,28 @@ package postgres
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
 	meta_util "github.com/appscode/kutil/meta"
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	"github.com/kubedb/kubedb-server/pkg/admission/util"
-	pgv "github.com/kubedb/postgres/pkg/validator"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
 	admission "k8s.io/api/admission/v1beta1"
 	kerr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type PostgresValidator struct {
+	config      *config.Config
+	prober      *bucket.Prober
 	client      kubernetes.Interface
 	extClient   cs.Interface
 	lock        sync.RWMutex
@@ -27,6 +33,12 @@ type PostgresValidator struct {
 
 var _ hookapi.AdmissionHook = &PostgresValidator{}
 
+// NewPostgresValidator returns the validator of Postgres configured by c, that checks the access to backup buckets with
+// prober.
+func NewPostgresValidator(c *config.Config, prober *bucket.Prober) *PostgresValidator {
+	return &PostgresValidator{config: c, prober: prober}
+}
+
 func (a *PostgresValidator) Resource() (plural schema.GroupVersionResource, singular string) {
 	return schema.GroupVersionResource{
 			Group:    "admission.kubedb.com",
@@ -83,9 +95,10 @@ func (a *PostgresValidator) Admit(req *admission.AdmissionRequest) *admission.Ad
 		if err != nil {
 			return hookapi.StatusBadRequest(err)
 		}
+		var oldObject runtime.Object
 		if req.Operation == admission.Update {
 			// validate changes made by user
-			oldObject, err := meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
+			oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
 			if err != nil {
 				return hookapi.StatusBadRequest(err)
 			}
@@ -93,8 +106,47 @@ func (a *PostgresValidator) Admit(req *admission.AdmissionRequest) *admission.Ad
 				return hookapi.StatusBadRequest(fmt.Errorf("%v", err))
 			}
 		}
+		// only KubeDB operator may set the labels and annotations reserved for it
+		if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// a database resuming a DormantDatabase is checked against the paused database, as on an update
+		previous := oldObject
+		if req.Operation == admission.Create {
+			if previous, err = util.GetPausedDatabase(a.extClient.KubedbV1alpha1(), req.Kind.Kind, obj); err != nil {
+				return hookapi.StatusInternalServerError(err)
+			}
+		}
+		deadline := time.Now().Add(a.config.BucketProbeTimeout())
 		// validate database specs
-		if err = pgv.ValidatePostgres(a.client, a.extClient.KubedbV1alpha1(), obj.(*api.Postgres)); err != nil {
+		if err = ValidatePostgres(a.client, a.extClient.KubedbV1alpha1(), a.prober, obj.(*api.Postgres), oldObject, previous, deadline); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// validate backup schedule, and report the upcoming backups back to the user
+		nextBackups, err := amv.ValidateBackupSchedule(a.client, a.prober, obj.(*api.Postgres).Spec.BackupSchedule, util.GetBackupSchedule(previous), req.Namespace, a.config.MinBackupInterval, deadline)
+		if err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		util.AppendMessage(status, amv.BackupScheduleMessage(nextBackups))
+		spec := obj.(*api.Postgres).Spec
+		// check the passwords of the auth secret against the policy of the namespace
+		if err := amv.CheckPasswordStrength(a.client, api.ResourceKindPostgres, spec.DatabaseSecret, req.Namespace, previous); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that a node can run the database pods
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckSchedulable(a.client, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the nodes have enough resources for the database pods
+		if err := util.ApplyPolicy(req, status, a.config.CapacityPolicy, amv.CheckCapacity(a.client, podCount(obj), podCount(oldObject), spec.Resources, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of the database are bound where its pods can run
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckStorageBinding(a.client, spec.Storage, spec.NodeSelector, spec.Affinity, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of a production database are kept when their claims are deleted
+		if err := util.ApplyPolicy(req, status, config.PolicyWarn, amv.CheckReclaimPolicy(a.client, spec.Storage, req.Namespace, oldObject)); err != nil {
 			return hookapi.StatusForbidden(err)
 		}
 	}
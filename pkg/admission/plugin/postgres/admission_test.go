@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/appscode/go/types"
@@ -10,6 +11,9 @@ import (
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
 	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
 	admission "k8s.io/api/admission/v1beta1"
 	authenticationV1 "k8s.io/api/authentication/v1"
 	core "k8s.io/api/core/v1"
@@ -32,26 +36,87 @@ var requestKind = metaV1.GroupVersionKind{
 	Kind:    api.ResourceKindPostgres,
 }
 
+// clusterObjects returns the objects in the cluster the Postgres validator is tested against.
+func clusterObjects() []runtime.Object {
+	return []runtime.Object{
+		&core.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "foo-auth",
+				Namespace: "default",
+				Labels: map[string]string{
+					api.LabelDatabaseKind: api.ResourceKindPostgres,
+				},
+			},
+			Data: map[string][]byte{
+				"POSTGRES_PASSWORD": []byte("s3cret-password"),
+			},
+		},
+		&core.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "short-auth",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"POSTGRES_PASSWORD": []byte("secret"),
+			},
+		},
+		&core.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "mysql-auth",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"user":     []byte("root"),
+				"password": []byte("s3cret-password"),
+			},
+		},
+		&core.Service{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "bar-replicas",
+				Namespace: "default",
+			},
+		},
+		&core.Namespace{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: "default",
+				Annotations: map[string]string{
+					amv.PasswordMinLengthKey: "8",
+				},
+			},
+		},
+		&storageV1beta1.StorageClass{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: "standard",
+			},
+		},
+		&core.LimitRange{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "limits",
+				Namespace: "default",
+			},
+			Spec: core.LimitRangeSpec{
+				Limits: []core.LimitRangeItem{
+					{
+						Type:           core.LimitTypeContainer,
+						Max:            core.ResourceList{core.ResourceCPU: resource.MustParse("2")},
+						Default:        core.ResourceList{core.ResourceCPU: resource.MustParse("500m")},
+						DefaultRequest: core.ResourceList{core.ResourceCPU: resource.MustParse("250m")},
+					},
+				},
+			},
+		},
+	}
+}
+
 func TestPostgresValidator_Admit(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.testName, func(t *testing.T) {
-			validator := PostgresValidator{}
+			admissionConfig := config.New()
+			validator := NewPostgresValidator(admissionConfig, bucket.New(admissionConfig))
 
 			validator.initialized = true
 			validator.extClient = extFake.NewSimpleClientset()
-			validator.client = fake.NewSimpleClientset(
-				&core.Secret{
-					ObjectMeta: metaV1.ObjectMeta{
-						Name:      "foo-auth",
-						Namespace: "default",
-					},
-				},
-				&storageV1beta1.StorageClass{
-					ObjectMeta: metaV1.ObjectMeta{
-						Name: "standard",
-					},
-				},
-			)
+			validator.client = fake.NewSimpleClientset(clusterObjects()...)
 
 			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
 			if err != nil {
@@ -200,6 +265,166 @@ var cases = []struct {
 		true,
 		true,
 	},
+	{"Create Postgres with BackupSchedule descriptor",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecBackupSchedule(samplePostgres(), "@daily"),
+		api.Postgres{},
+		false,
+		true,
+	},
+	{"Create Postgres with invalid BackupSchedule",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecBackupSchedule(samplePostgres(), "every 5 minuts"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with too frequent BackupSchedule",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecBackupSchedule(samplePostgres(), "*/1 * * * *"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with archiver storage secret missing credentials",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecArchiver(samplePostgres(), "foo-auth"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with WAL storage secret missing credentials",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecInitWAL(samplePostgres(), "foo-auth"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with Spec.Resources",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecResources(samplePostgres(), "1", "2"),
+		api.Postgres{},
+		false,
+		true,
+	},
+	{"Create Postgres with Spec.Resources limit below request",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecResources(samplePostgres(), "1", "500m"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with Spec.Resources above LimitRange maximum",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecResources(samplePostgres(), "1", "4"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with Spec.Resources request above LimitRange default limit",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecResources(samplePostgres(), "1", ""),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with invalid Spec.Tolerations",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecTolerations(samplePostgres(), core.Toleration{Operator: core.TolerationOpEqual, Value: "db"}),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with missing Spec.ImagePullSecrets",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecImagePullSecrets(samplePostgres(), "regsitry"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with Spec.DatabaseSecret",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecSecret(samplePostgres()),
+		api.Postgres{},
+		false,
+		true,
+	},
+	{"Create Postgres with Spec.DatabaseSecret missing keys",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecSecretName(samplePostgres(), "mysql-auth"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with Spec.DatabaseSecret holding short password",
+		requestKind,
+		"foo",
+		"default",
+		admission.Create,
+		editSpecSecretName(samplePostgres(), "short-auth"),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with too long name",
+		requestKind,
+		longName,
+		"default",
+		admission.Create,
+		editName(samplePostgres(), longName),
+		api.Postgres{},
+		false,
+		false,
+	},
+	{"Create Postgres with name of existing Service",
+		requestKind,
+		"bar",
+		"default",
+		admission.Create,
+		editName(samplePostgres(), "bar"),
+		api.Postgres{},
+		false,
+		false,
+	},
 	{"Delete Non Existing Postgres",
 		requestKind,
 		"foo",
@@ -212,6 +437,107 @@ var cases = []struct {
 	},
 }
 
+// TestPostgresValidator_Resume checks that a Postgres resuming a DormantDatabase is not denied by the rules for new
+// databases, since it can't change the spec it was paused with.
+func TestPostgresValidator_Resume(t *testing.T) {
+	for _, c := range resumeCases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			validator := NewPostgresValidator(admissionConfig, bucket.New(admissionConfig))
+
+			validator.initialized = true
+			validator.client = fake.NewSimpleClientset(clusterObjects()...)
+			validator.extClient = extFake.NewSimpleClientset(dormantPostgres(c.object))
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+			req := new(admission.AdmissionRequest)
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := validator.Admit(req)
+			if response.Allowed != true {
+				t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+			}
+		})
+	}
+}
+
+var resumeCases = []struct {
+	testName string
+	object   api.Postgres
+}{
+	{"Resume Postgres with too frequent BackupSchedule",
+		editSpecBackupSchedule(editSpecSecret(samplePostgres()), "*/1 * * * *"),
+	},
+	{"Resume Postgres with weak password",
+		editSpecSecretName(samplePostgres(), "short-auth"),
+	},
+	{"Resume Postgres with resources over LimitRange",
+		editSpecResources(editSpecSecret(samplePostgres()), "1", "4"),
+	},
+	{"Resume Postgres with read-only storage",
+		editSpecAccessModes(editSpecSecret(samplePostgres()), core.ReadOnlyMany),
+	},
+}
+
+// dormantPostgres returns the DormantDatabase postgres was paused into.
+func dormantPostgres(postgres api.Postgres) *api.DormantDatabase {
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      postgres.Name,
+			Namespace: postgres.Namespace,
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				ObjectMeta: postgres.ObjectMeta,
+				Spec: api.OriginSpec{
+					Postgres: &postgres.Spec,
+				},
+			},
+		},
+	}
+}
+
+func TestMatchWithDormantDatabase(t *testing.T) {
+	dormant := samplePostgres()
+	resumed := editSpecSecret(getAwkwardPostgres())
+	dormant.Spec.DatabaseSecret = resumed.Spec.DatabaseSecret
+	extClient := extFake.NewSimpleClientset(&api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Postgres: &dormant.Spec,
+				},
+			},
+		},
+	})
+
+	err := matchWithDormantDatabase(extClient.KubedbV1alpha1(), &resumed)
+	if err == nil {
+		t.Fatal("expected spec mismatch to be denied")
+	}
+	if !strings.Contains(err.Error(), "Diff:") || !strings.Contains(err.Error(), "3.0") {
+		t.Errorf("expected denial to show the changed field, but got: %v", err)
+	}
+}
+
 func samplePostgres() api.Postgres {
 	return api.Postgres{
 		TypeMeta: metaV1.TypeMeta{
@@ -250,6 +576,17 @@ func samplePostgres() api.Postgres {
 	}
 }
 
+// longName is a valid object name, that is too long for the name of the replicas Service
+var longName = strings.Repeat("a", 60)
+
+func editName(old api.Postgres, name string) api.Postgres {
+	old.Name = name
+	old.Spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: "foo-auth",
+	}
+	return old
+}
+
 func getAwkwardPostgres() api.Postgres {
 	postgres := samplePostgres()
 	postgres.Spec.Version = "3.0"
@@ -257,8 +594,12 @@ func getAwkwardPostgres() api.Postgres {
 }
 
 func editSpecSecret(old api.Postgres) api.Postgres {
+	return editSpecSecretName(old, "foo-auth")
+}
+
+func editSpecSecretName(old api.Postgres, name string) api.Postgres {
 	old.Spec.DatabaseSecret = &core.SecretVolumeSource{
-		SecretName: "foo-auth",
+		SecretName: name,
 	}
 	return old
 }
@@ -289,3 +630,73 @@ func editSpecDoNotPause(old api.Postgres) api.Postgres {
 	old.Spec.DoNotPause = false
 	return old
 }
+
+func editSpecBackupSchedule(old api.Postgres, cronExpression string) api.Postgres {
+	old.Spec.BackupSchedule = &api.BackupScheduleSpec{
+		CronExpression: cronExpression,
+		SnapshotStorageSpec: api.SnapshotStorageSpec{
+			Local: &api.LocalSpec{
+				VolumeSource: core.VolumeSource{
+					EmptyDir: &core.EmptyDirVolumeSource{},
+				},
+				MountPath: "/repo",
+			},
+		},
+	}
+	return old
+}
+
+func editSpecArchiver(old api.Postgres, storageSecretName string) api.Postgres {
+	old.Spec.Archiver = &api.PostgresArchiverSpec{
+		Storage: &api.SnapshotStorageSpec{
+			StorageSecretName: storageSecretName,
+			S3: &api.S3Spec{
+				Endpoint: "minio.example.com",
+				Bucket:   "wal",
+			},
+		},
+	}
+	return old
+}
+
+func editSpecInitWAL(old api.Postgres, storageSecretName string) api.Postgres {
+	old.Spec.Init = &api.InitSpec{
+		PostgresWAL: &api.PostgresWALSourceSpec{
+			SnapshotStorageSpec: api.SnapshotStorageSpec{
+				StorageSecretName: storageSecretName,
+				S3: &api.S3Spec{
+					Endpoint: "minio.example.com",
+					Bucket:   "wal",
+				},
+			},
+		},
+	}
+	return old
+}
+
+func editSpecResources(old api.Postgres, request, limit string) api.Postgres {
+	old.Spec.Resources = core.ResourceRequirements{
+		Requests: core.ResourceList{core.ResourceCPU: resource.MustParse(request)},
+	}
+	if limit != "" {
+		old.Spec.Resources.Limits = core.ResourceList{core.ResourceCPU: resource.MustParse(limit)}
+	}
+	return old
+}
+
+func editSpecTolerations(old api.Postgres, tolerations ...core.Toleration) api.Postgres {
+	old.Spec.Tolerations = tolerations
+	return old
+}
+
+func editSpecImagePullSecrets(old api.Postgres, names ...string) api.Postgres {
+	for _, name := range names {
+		old.Spec.ImagePullSecrets = append(old.Spec.ImagePullSecrets, core.LocalObjectReference{Name: name})
+	}
+	return old
+}
+
+func editSpecAccessModes(old api.Postgres, modes ...core.PersistentVolumeAccessMode) api.Postgres {
+	old.Spec.Storage.AccessModes = modes
+	return old
+}
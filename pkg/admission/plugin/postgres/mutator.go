@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/appscode/go/types"
+	mon_api "github.com/appscode/kube-mon/api"
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type PostgresMutator struct {
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &PostgresMutator{}
+
+func (a *PostgresMutator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "postgresmutationreviews",
+		},
+		"postgresmutationreview"
+}
+
+func (a *PostgresMutator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *PostgresMutator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	// N.B.: No Mutating for delete
+	if (req.Operation != admission.Create && req.Operation != admission.Update) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindPostgres {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+	obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+	if err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	postgresMod, err := setDefaultValues(a.client, a.extClient, obj.(*api.Postgres).DeepCopy())
+	if err != nil {
+		return hookapi.StatusForbidden(err)
+	} else if postgresMod != nil {
+		patch, err := meta_util.CreateJSONPatch(obj, postgresMod)
+		if err != nil {
+			return hookapi.StatusInternalServerError(err)
+		}
+		status.Patch = patch
+		patchType := admission.PatchTypeJSONPatch
+		status.PatchType = &patchType
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// setDefaultValues provides the defaulting that is performed in mutating stage of creating/updating a Postgres database
+func setDefaultValues(client kubernetes.Interface, extClient cs.Interface, postgres *api.Postgres) (runtime.Object, error) {
+	// Defaults are taken from DormantDatabase first, so that resuming a database only needs its name.
+	if _, err := util.SetDefaultsFromDormantDB(extClient.KubedbV1alpha1(), api.ResourceKindPostgres, postgres); err != nil {
+		return nil, err
+	}
+
+	if postgres.Spec.Version == "" {
+		return nil, fmt.Errorf(`object 'Version' is missing in '%v'`, postgres.Spec)
+	}
+
+	if postgres.Spec.Replicas == nil {
+		postgres.Spec.Replicas = types.Int32P(1)
+	}
+
+	// If monitoring spec is given without port,
+	// set default Listening port
+	setMonitoringPort(postgres)
+
+	return postgres, nil
+}
+
+// Assign Default Monitoring Port if MonitoringSpec Exists
+// and the AgentVendor is Prometheus.
+func setMonitoringPort(postgres *api.Postgres) {
+	if postgres.Spec.Monitor != nil &&
+		postgres.GetMonitoringVendor() == mon_api.VendorPrometheus {
+		if postgres.Spec.Monitor.Prometheus == nil {
+			postgres.Spec.Monitor.Prometheus = &mon_api.PrometheusSpec{}
+		}
+		if postgres.Spec.Monitor.Prometheus.Port == 0 {
+			postgres.Spec.Monitor.Prometheus.Port = api.PrometheusExporterPortNumber
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package postgres
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/appscode/go/types"
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPostgresMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			mutator := PostgresMutator{}
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset()
+			mutator.client = fake.NewSimpleClientset()
+
+			if c.dormantDb != nil {
+				if _, err := mutator.extClient.KubedbV1alpha1().DormantDatabases(c.dormantDb.Namespace).Create(c.dormantDb); err != nil {
+					t.Error(err)
+				}
+			}
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				patch, err := jsonpatch.DecodePatch(response.Patch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				modJS, err := patch.Apply(objJS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.Postgres).Spec, c.expected) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected, mod.(*api.Postgres).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				} else if c.dormantDb != nil &&
+					c.dormantDb.Labels[api.LabelDatabaseKind] == api.ResourceKindPostgres &&
+					!strings.Contains(response.Result.Message, "Diff") {
+					t.Errorf("expected diff in response: %v", response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName  string
+	object    api.Postgres
+	dormantDb *api.DormantDatabase
+	expected  api.PostgresSpec
+	result    bool
+}{
+	{"Create Postgres",
+		samplePostgres(),
+		nil,
+		setReplicas(samplePostgres()).Spec,
+		true,
+	},
+	{"Create Postgres without Version",
+		emptyPostgres(),
+		nil,
+		api.PostgresSpec{},
+		false,
+	},
+	{"Resume Postgres with name and kind only",
+		emptyPostgres(),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		editSpecDoNotPause(api.Postgres{Spec: dormantPostgresSpec()}).Spec,
+		true,
+	},
+	{"Resume Postgres with partial Storage",
+		editSpecStorageClass(emptyPostgres()),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		editSpecDoNotPause(api.Postgres{Spec: dormantPostgresSpec()}).Spec,
+		true,
+	},
+	{"Resume Postgres with mismatched Storage",
+		editSpecStorage(emptyPostgres()),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		api.PostgresSpec{},
+		false,
+	},
+	{"Resume Postgres from DormantDatabase of different kind",
+		emptyPostgres(),
+		sampleDormantDatabase(api.ResourceKindMySQL),
+		api.PostgresSpec{},
+		false,
+	},
+}
+
+// A Postgres resumed from a DormantDatabase initialized from WAL archive is marked as initialized by the mutator,
+// and the validator must let the annotation through.
+func TestPostgresMutator_ResumeFromWAL(t *testing.T) {
+	dormantDb := sampleDormantDatabase(api.ResourceKindPostgres)
+	dormantDb.Spec.Origin.Spec.Postgres.Init = &api.InitSpec{
+		PostgresWAL: &api.PostgresWALSourceSpec{
+			BackupName: "foo-backup",
+		},
+	}
+
+	mutator := PostgresMutator{}
+	mutator.initialized = true
+	mutator.extClient = extFake.NewSimpleClientset(dormantDb)
+	mutator.client = fake.NewSimpleClientset()
+
+	object := emptyPostgres()
+	objJS, err := meta.MarshalToJson(&object, api.SchemeGroupVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := new(admission.AdmissionRequest)
+	req.Kind = requestKind
+	req.Name = object.Name
+	req.Namespace = object.Namespace
+	req.Operation = admission.Create
+	req.UserInfo = authenticationV1.UserInfo{Username: "alice"}
+	req.Object.Raw = objJS
+
+	response := mutator.Admit(req)
+	if response.Allowed != true {
+		t.Fatalf("expected: 'Allowed=true'. but got response: %v", response)
+	}
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modJS, err := patch.Apply(objJS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := mod.(*api.Postgres).Annotations[api.AnnotationInitialized]; !found {
+		t.Fatalf("expected annotation %s on resumed Postgres", api.AnnotationInitialized)
+	}
+
+	if err := util.ValidateReservedKeys(config.New(), mutator.extClient.KubedbV1alpha1(), req.UserInfo, api.ResourceKindPostgres, mod, nil); err != nil {
+		t.Errorf("expected initialized annotation of resumed Postgres to be allowed, but got error: %v", err)
+	}
+}
+
+func emptyPostgres() api.Postgres {
+	postgres := samplePostgres()
+	postgres.Spec = api.PostgresSpec{}
+	return postgres
+}
+
+func dormantPostgresSpec() api.PostgresSpec {
+	spec := setReplicas(samplePostgres()).Spec
+	spec.DatabaseSecret = &core.SecretVolumeSource{
+		SecretName: "foo-auth",
+	}
+	return spec
+}
+
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	spec := dormantPostgresSpec()
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Postgres: &spec,
+				},
+			},
+		},
+	}
+}
+
+func setReplicas(old api.Postgres) api.Postgres {
+	old.Spec.Replicas = types.Int32P(1)
+	return old
+}
+
+func editSpecStorageClass(old api.Postgres) api.Postgres {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		StorageClassName: types.StringP("standard"),
+	}
+	return old
+}
+
+func editSpecStorage(old api.Postgres) api.Postgres {
+	old.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	return old
+}
@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	postgresVersions = sets.NewString("9.6", "9.6.7", "10.2")
+	postgresPorts    = []int32{5432}
+)
+
+// ValidatePostgres validates postgres. oldObject is the Postgres before an update, and previous is oldObject, or the paused
+// database when postgres resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidatePostgres(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, prober *bucket.Prober, postgres *api.Postgres, oldObject, previous runtime.Object, deadline time.Time) error {
+
+	if postgres.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, postgres.Spec)
+	}
+
+	// check Postgres version validation
+	if !postgresVersions.Has(string(postgres.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support Postgres version: %s`, string(postgres.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindPostgres, postgres.Name, postgres.Namespace, derivedNames(postgres), oldObject); err != nil {
+		return err
+	}
+
+	if postgres.Spec.Replicas != nil {
+		replicas := types.Int32(postgres.Spec.Replicas)
+		if replicas < 1 {
+			return fmt.Errorf(`spec.replicas "%d" invalid`, replicas)
+		}
+	}
+
+	if err := matchWithDormantDatabase(extClient, postgres); err != nil {
+		return err
+	}
+
+	if postgres.Spec.Storage != nil {
+		var err error
+		if err = amv.ValidateStorage(client, postgres.Spec.Storage, util.GetStorage(previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := amv.ValidateResources(client, postgres.Spec.Resources, util.GetResources(previous), postgres.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(postgres.Spec.NodeSelector, postgres.Spec.Affinity, postgres.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, postgres.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), postgres.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	if postgres.Spec.StandbyMode != nil {
+		standByMode := *postgres.Spec.StandbyMode
+		if standByMode != api.HotStandby && standByMode != api.WarmStandby {
+			return fmt.Errorf(`spec.standbyMode "%s" invalid`, standByMode)
+		}
+	}
+
+	if postgres.Spec.StreamingMode != nil {
+		streamingMode := *postgres.Spec.StreamingMode
+		// TODO: synchronous Streaming is unavailable due to lack of support
+		if streamingMode != api.AsynchronousStreaming {
+			return fmt.Errorf(`spec.streamingMode "%s" invalid`, streamingMode)
+		}
+	}
+
+	if postgres.Spec.Archiver != nil {
+		archiverStorage := postgres.Spec.Archiver.Storage
+		if archiverStorage != nil {
+			if archiverStorage.StorageSecretName == "" {
+				return fmt.Errorf(`object 'StorageSecretName' is missing in '%v'`, archiverStorage)
+			}
+			if archiverStorage.S3 == nil {
+				return errors.New("no storage provider is configured")
+			}
+			if !(archiverStorage.GCS == nil && archiverStorage.Azure == nil && archiverStorage.Swift == nil && archiverStorage.Local == nil) {
+				return errors.New("invalid storage provider is configured")
+			}
+
+			if err := amv.ValidateStorageSecret(client, *archiverStorage, postgres.Namespace); err != nil {
+				return err
+			}
+
+			if err := prober.CheckBucketAccess(client, *archiverStorage, postgres.Namespace, deadline); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := amv.ValidateDatabaseSecret(client, api.ResourceKindPostgres, postgres.Spec.DatabaseSecret, postgres.Namespace, field.NewPath("spec").Child("databaseSecret")); err != nil {
+		return err
+	}
+
+	if postgres.Spec.Init != nil && postgres.Spec.Init.PostgresWAL != nil {
+		wal := postgres.Spec.Init.PostgresWAL
+		if wal.StorageSecretName == "" {
+			return fmt.Errorf(`object 'StorageSecretName' is missing in '%v'`, wal)
+		}
+		if wal.S3 == nil {
+			return errors.New("no storage provider is configured")
+		}
+		if !(wal.GCS == nil && wal.Azure == nil && wal.Swift == nil && wal.Local == nil) {
+			return errors.New("invalid storage provider is configured")
+		}
+
+		if err := amv.ValidateStorageSecret(client, wal.SnapshotStorageSpec, postgres.Namespace); err != nil {
+			return err
+		}
+
+		if err := prober.CheckBucketAccess(client, wal.SnapshotStorageSpec, postgres.Namespace, deadline); err != nil {
+			return err
+		}
+	}
+
+	monitorSpec := postgres.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), postgresPorts...); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, postgres *api.Postgres) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(postgres.Namespace).Get(postgres.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if dormantDb.Labels[api.LabelDatabaseKind] != api.ResourceKindPostgres {
+		return fmt.Errorf(`invalid Postgres: "%v". Exists DormantDatabase "%v" of different Kind`, postgres.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.Postgres
+	originalSpec := postgres.Spec
+
+	if originalSpec.DatabaseSecret == nil {
+		originalSpec.DatabaseSecret = &core.SecretVolumeSource{
+			SecretName: postgres.OffshootName() + "-auth",
+		}
+	}
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("object spec in Postgres mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the postgres in obj, or 0 if obj is not a Postgres.
+func podCount(obj runtime.Object) int32 {
+	postgres, ok := obj.(*api.Postgres)
+	if !ok {
+		return 0
+	}
+	return amv.PodCount(postgres.Spec.Replicas)
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for postgres.
+func derivedNames(postgres *api.Postgres) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindStatefulSet, Name: postgres.OffshootName()},
+		{Kind: amv.KindService, Name: postgres.ServiceName()},
+		{Kind: amv.KindService, Name: postgres.ReplicasServiceName()},
+	}
+	if postgres.Spec.DatabaseSecret == nil {
+		names = append(names, amv.DerivedName{Kind: amv.KindSecret, Name: postgres.OffshootName() + "-auth"})
+	}
+	names = append(names, amv.MonitorNames(postgres.Spec.Monitor, postgres.ServiceMonitorName())...)
+	if postgres.Spec.BackupSchedule != nil {
+		names = append(names, amv.ScheduledSnapshotNames(postgres.OffshootName())...)
+	}
+	names = append(names, amv.SnapshotLockNames(api.ResourceKindPostgres, postgres.Name)...)
+	return names
+}
@@ -8,17 +8,20 @@ import (
 	meta_util "github.com/appscode/kutil/meta"
 	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
 	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	"github.com/kubedb/kubedb-server/pkg/admission/util"
-	rdv "github.com/kubedb/redis/pkg/validator"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
 	admission "k8s.io/api/admission/v1beta1"
 	kerr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type RedisValidator struct {
+	config      *config.Config
 	client      kubernetes.Interface
 	extClient   cs.Interface
 	lock        sync.RWMutex
@@ -27,6 +30,11 @@ type RedisValidator struct {
 
 var _ hookapi.AdmissionHook = &RedisValidator{}
 
+// NewRedisValidator returns the validator of Redis configured by c.
+func NewRedisValidator(c *config.Config) *RedisValidator {
+	return &RedisValidator{config: c}
+}
+
 func (a *RedisValidator) Resource() (plural schema.GroupVersionResource, singular string) {
 	return schema.GroupVersionResource{
 			Group:    "admission.kubedb.com",
@@ -83,9 +91,10 @@ func (a *RedisValidator) Admit(req *admission.AdmissionRequest) *admission.Admis
 		if err != nil {
 			return hookapi.StatusBadRequest(err)
 		}
+		var oldObject runtime.Object
 		if req.Operation == admission.Update {
 			// validate changes made by user
-			oldObject, err := meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
+			oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
 			if err != nil {
 				return hookapi.StatusBadRequest(err)
 			}
@@ -93,8 +102,36 @@ func (a *RedisValidator) Admit(req *admission.AdmissionRequest) *admission.Admis
 				return hookapi.StatusBadRequest(fmt.Errorf("%v", err))
 			}
 		}
+		// only KubeDB operator may set the labels and annotations reserved for it
+		if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// a database resuming a DormantDatabase is checked against the paused database, as on an update
+		previous := oldObject
+		if req.Operation == admission.Create {
+			if previous, err = util.GetPausedDatabase(a.extClient.KubedbV1alpha1(), req.Kind.Kind, obj); err != nil {
+				return hookapi.StatusInternalServerError(err)
+			}
+		}
 		// validate database specs
-		if err = rdv.ValidateRedis(a.client, a.extClient.KubedbV1alpha1(), obj.(*api.Redis)); err != nil {
+		if err = ValidateRedis(a.client, a.extClient.KubedbV1alpha1(), obj.(*api.Redis), oldObject, previous); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		spec := obj.(*api.Redis).Spec
+		// check that a node can run the database pods
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckSchedulable(a.client, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the nodes have enough resources for the database pods
+		if err := util.ApplyPolicy(req, status, a.config.CapacityPolicy, amv.CheckCapacity(a.client, podCount(obj), podCount(oldObject), spec.Resources, spec.NodeSelector, spec.Affinity, spec.Tolerations, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of the database are bound where its pods can run
+		if err := util.ApplyPolicy(req, status, a.config.SchedulingPolicy, amv.CheckStorageBinding(a.client, spec.Storage, spec.NodeSelector, spec.Affinity, oldObject)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// check that the volumes of a production database are kept when their claims are deleted
+		if err := util.ApplyPolicy(req, status, config.PolicyWarn, amv.CheckReclaimPolicy(a.client, spec.Storage, req.Namespace, oldObject)); err != nil {
 			return hookapi.StatusForbidden(err)
 		}
 	}
@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	redisVersions = sets.NewString("4", "4.0", "4.0.6")
+	redisPorts    = []int32{6379}
+)
+
+// ValidateRedis validates redis. oldObject is the Redis before an update, and previous is oldObject, or the paused
+// database when redis resumes a DormantDatabase. The rules for new databases are checked only for fields changed
+// from previous.
+func ValidateRedis(client kubernetes.Interface, extClient cs.KubedbV1alpha1Interface, redis *api.Redis, oldObject, previous runtime.Object) error {
+	if redis.Spec.Version == "" {
+		return fmt.Errorf(`object 'Version' is missing in '%v'`, redis.Spec)
+	}
+
+	// check Redis version validation
+	if !redisVersions.Has(string(redis.Spec.Version)) {
+		return fmt.Errorf(`KubeDB doesn't support Redis version: %s`, string(redis.Spec.Version))
+	}
+
+	if err := amv.ValidateNames(client, api.ResourceKindRedis, redis.Name, redis.Namespace, derivedNames(redis), oldObject); err != nil {
+		return err
+	}
+
+	if redis.Spec.Replicas != nil {
+		replicas := types.Int32(redis.Spec.Replicas)
+		if replicas != 1 {
+			return fmt.Errorf(`spec.replicas "%d" invalid. Value must be one`, replicas)
+		}
+	}
+
+	if err := matchWithDormantDatabase(extClient, redis); err != nil {
+		return err
+	}
+
+	if redis.Spec.Storage != nil {
+		var err error
+		if err = amv.ValidateStorage(client, redis.Spec.Storage, util.GetStorage(previous)); err != nil {
+			return err
+		}
+	}
+
+	if err := amv.ValidateResources(client, redis.Spec.Resources, util.GetResources(previous), redis.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateScheduling(redis.Spec.NodeSelector, redis.Spec.Affinity, redis.Spec.Tolerations, field.NewPath("spec")); err != nil {
+		return err
+	}
+
+	if err := amv.ValidateImagePullSecrets(client, redis.Spec.ImagePullSecrets, util.GetImagePullSecrets(oldObject), redis.Namespace, field.NewPath("spec").Child("imagePullSecrets")); err != nil {
+		return err
+	}
+
+	monitorSpec := redis.Spec.Monitor
+	if monitorSpec != nil {
+		if err := amv.ValidateMonitorSpec(client, monitorSpec, field.NewPath("spec").Child("monitor"), redisPorts...); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func matchWithDormantDatabase(extClient cs.KubedbV1alpha1Interface, redis *api.Redis) error {
+	// Check if DormantDatabase exists or not
+	dormantDb, err := extClient.DormantDatabases(redis.Namespace).Get(redis.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Check DatabaseKind
+	if dormantDb.Labels[api.LabelDatabaseKind] != api.ResourceKindRedis {
+		return fmt.Errorf(`invalid Redis: "%v". Exists DormantDatabase "%v" of different Kind`, redis.Name, dormantDb.Name)
+	}
+
+	// Check Origin Spec
+	drmnOriginSpec := dormantDb.Spec.Origin.Spec.Redis
+	originalSpec := redis.Spec
+
+	// Skip checking doNotPause
+	drmnOriginSpec.DoNotPause = originalSpec.DoNotPause
+
+	if !meta_util.Equal(drmnOriginSpec, &originalSpec) {
+		diff := meta_util.Diff(drmnOriginSpec, &originalSpec)
+		return fmt.Errorf("redis spec mismatches with OriginSpec in DormantDatabases. Diff: %v", diff)
+	}
+
+	return nil
+}
+
+// podCount returns the number of pods KubeDB operator runs for the redis in obj, or 0 if obj is not a Redis.
+func podCount(obj runtime.Object) int32 {
+	redis, ok := obj.(*api.Redis)
+	if !ok {
+		return 0
+	}
+	return amv.PodCount(redis.Spec.Replicas)
+}
+
+// derivedNames returns the names of the objects KubeDB operator creates for redis.
+func derivedNames(redis *api.Redis) []amv.DerivedName {
+	names := []amv.DerivedName{
+		{Kind: amv.KindStatefulSet, Name: redis.OffshootName()},
+		{Kind: amv.KindService, Name: redis.ServiceName()},
+	}
+	names = append(names, amv.MonitorNames(redis.Spec.Monitor, redis.ServiceMonitorName())...)
+	return names
+}
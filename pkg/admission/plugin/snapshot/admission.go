@@ -0,0 +1,387 @@
+package snapshot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appscode/go/log"
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type SnapshotValidator struct {
+	config      *config.Config
+	prober      *bucket.Prober
+	client      kubernetes.Interface
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &SnapshotValidator{}
+
+// NewSnapshotValidator returns the validator of Snapshots configured by c, that checks the access to their buckets
+// with prober.
+func NewSnapshotValidator(c *config.Config, prober *bucket.Prober) *SnapshotValidator {
+	return &SnapshotValidator{config: c, prober: prober}
+}
+
+func (a *SnapshotValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "snapshotreviews",
+		},
+		"snapshotreview"
+}
+
+func (a *SnapshotValidator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.client, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *SnapshotValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	if (req.Operation != admission.Create && req.Operation != admission.Update && req.Operation != admission.Delete) ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindSnapshot {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+
+	if req.Operation == admission.Delete {
+		// req.Object.Raw = nil, so read from kubernetes
+		snapshot, err := a.extClient.KubedbV1alpha1().Snapshots(req.Namespace).Get(req.Name, metav1.GetOptions{})
+		if err != nil && !kerr.IsNotFound(err) {
+			return hookapi.StatusInternalServerError(err)
+		} else if err == nil && !a.config.IsPrivileged(req.UserInfo) {
+			if err := a.checkDeletion(snapshot); err != nil {
+				return hookapi.StatusForbidden(err)
+			}
+		}
+		status.Allowed = true
+		return status
+	}
+
+	obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+	if err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	var oldObject runtime.Object
+	if req.Operation == admission.Update {
+		oldObject, err = meta_util.UnmarshalFromJSON(req.OldObject.Raw, api.SchemeGroupVersion)
+		if err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+	}
+	// only KubeDB operator may set the labels reserved for it, eg: the status label of a Snapshot
+	if err := util.ValidateReservedKeys(a.config, a.extClient.KubedbV1alpha1(), req.UserInfo, req.Kind.Kind, obj, oldObject); err != nil {
+		return hookapi.StatusForbidden(err)
+	}
+	if req.Operation == admission.Update {
+		if err := util.ValidateUpdate(obj, oldObject, req.Kind.Kind); err != nil {
+			return hookapi.StatusBadRequest(fmt.Errorf("%v", err))
+		}
+		// a completed Snapshot can't be changed, except for the status written by KubeDB operator
+		if err := validateCompleted(a.config, obj.(*api.Snapshot), oldObject.(*api.Snapshot), req.UserInfo); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// release the lock of the database when KubeDB operator completes the Snapshot. A lock left behind is free
+		// anyway, since its holder has completed.
+		if a.config.IsOperator(req.UserInfo) &&
+			!isCompleted(oldObject.(*api.Snapshot).Status.Phase) && isCompleted(obj.(*api.Snapshot).Status.Phase) {
+			if err := releaseLock(a.client, obj.(*api.Snapshot)); err != nil {
+				log.Errorf("failed to release the lock of snapshot %s/%s: %v", req.Namespace, req.Name, err)
+			}
+		}
+		// Skip checking validation if Spec is not changed
+		if meta_util.Equal(obj.(*api.Snapshot).Spec, oldObject.(*api.Snapshot).Spec) {
+			status.Allowed = true
+			return status
+		}
+	}
+	// validates if database of particular kind exists
+	if err := a.validateSnapshot(obj.(*api.Snapshot)); err != nil {
+		return hookapi.StatusForbidden(err)
+	}
+	// validates compute resources of the backup job
+	var oldResources *core.ResourceRequirements
+	if oldObject != nil {
+		oldResources = &oldObject.(*api.Snapshot).Spec.Resources
+	}
+	if err := amv.ValidateResources(a.client, obj.(*api.Snapshot).Spec.Resources, oldResources, req.Namespace, field.NewPath("spec").Child("resources")); err != nil {
+		return hookapi.StatusForbidden(err)
+	}
+	// validates Snapshot Spec
+	if err := amv.ValidateSnapshotSpec(a.client, a.prober, obj.(*api.Snapshot).Spec.SnapshotStorageSpec, req.Namespace, time.Now().Add(a.config.BucketProbeTimeout())); err != nil {
+		return hookapi.StatusForbidden(err)
+	}
+	if req.Operation == admission.Create {
+		// validates that the database is running, or has failed and the Snapshot is forced
+		if err := a.validateReadiness(obj.(*api.Snapshot)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// isSnapshotRunning checks if a snapshot is already running. Check this only when creating snapshot,
+		// because Snapshot.Status will be needed to edit later and this method will give error for that update.
+		if err := a.isSnapshotRunning(obj.(*api.Snapshot)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// validates the names of the backup job and the storage secret, which are derived from the Snapshot name
+		if err := a.validateNames(obj.(*api.Snapshot)); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+		// acquireLock records the Snapshot in the lock of its database, so that a concurrent Snapshot, or one created before
+		// KubeDB operator labels this one as running, is denied. It must be the last check, so that a denied
+		// Snapshot doesn't hold the lock.
+		if err := acquireLock(a.client, a.extClient, obj.(*api.Snapshot), time.Now(), a.config.WebhookTimeout); err != nil {
+			return hookapi.StatusForbidden(err)
+		}
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// ForceKey is the annotation of a Snapshot that forces it to be taken of a database in Failed phase, eg: to
+// salvage its data.
+const ForceKey = api.SnapshotKey + "/force"
+
+// backupSupported records which kinds of databases KubeDB operator can take Snapshots of
+var backupSupported = map[string]bool{
+	api.ResourceKindElasticsearch: true,
+	api.ResourceKindPostgres:      true,
+	api.ResourceKindMongoDB:       true,
+	api.ResourceKindMySQL:         true,
+	api.ResourceKindRedis:         false,
+	api.ResourceKindMemcached:     false,
+}
+
+// validateSnapshot checks if the database of the particular kind actually exists, and supports Snapshots.
+func (a *SnapshotValidator) validateSnapshot(snapshot *api.Snapshot) error {
+	// Database name can't empty
+	databaseName := snapshot.Spec.DatabaseName
+	if databaseName == "" {
+		return fmt.Errorf(`object 'DatabaseName' is missing in '%v'`, snapshot.Spec)
+	}
+
+	kind, err := meta_util.GetStringValue(snapshot.Labels, api.LabelDatabaseKind)
+	if err != nil {
+		return fmt.Errorf("'%v:XDB' label is missing", api.LabelDatabaseKind)
+	}
+	if supported, found := backupSupported[kind]; !found {
+		return fmt.Errorf(`unknown database kind "%v"`, kind)
+	} else if !supported {
+		return fmt.Errorf(`%s "%s" can't be snapshotted, %s doesn't support backup`, strings.ToLower(kind), databaseName, kind)
+	}
+
+	// Check if DB exists
+	_, err = util.GetDatabase(a.extClient.KubedbV1alpha1(), kind, snapshot.Namespace, databaseName)
+	return err
+}
+
+// validateReadiness checks that the database of a new Snapshot is running. A database that is still being created
+// or initialized has nothing consistent to back up. A failed database may only be snapshotted if the Snapshot has
+// the ForceKey annotation.
+func (a *SnapshotValidator) validateReadiness(snapshot *api.Snapshot) error {
+	kind := snapshot.Labels[api.LabelDatabaseKind]
+	db, err := util.GetDatabase(a.extClient.KubedbV1alpha1(), kind, snapshot.Namespace, snapshot.Spec.DatabaseName)
+	if err != nil {
+		return err
+	}
+
+	switch phase := util.GetDatabasePhase(db); phase {
+	case api.DatabasePhaseRunning:
+		return nil
+	case api.DatabasePhaseFailed:
+		if snapshot.Annotations[ForceKey] == "true" {
+			return nil
+		}
+		return fmt.Errorf(`%s "%s" has failed. To snapshot it anyway, set annotation %s=true on the Snapshot`,
+			strings.ToLower(kind), snapshot.Spec.DatabaseName, ForceKey)
+	default:
+		return fmt.Errorf(`%s "%s" is not running yet, its phase is "%s"`, strings.ToLower(kind), snapshot.Spec.DatabaseName, phase)
+	}
+}
+
+func (a *SnapshotValidator) isSnapshotRunning(snapshot *api.Snapshot) error {
+	labelMap := map[string]string{
+		api.LabelDatabaseKind:   snapshot.Labels[api.LabelDatabaseKind],
+		api.LabelDatabaseName:   snapshot.Spec.DatabaseName,
+		api.LabelSnapshotStatus: string(api.SnapshotPhaseRunning),
+	}
+
+	snapshotList, err := a.extClient.KubedbV1alpha1().Snapshots(snapshot.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labelMap).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(snapshotList.Items) > 0 {
+		return fmt.Errorf("one Snapshot is already running")
+	}
+
+	return nil
+}
+
+// validateNames checks that the names of the objects KubeDB operator creates for snapshot are valid, and not
+// taken by objects KubeDB operator didn't create for the database of snapshot.
+func (a *SnapshotValidator) validateNames(snapshot *api.Snapshot) error {
+	return amv.ValidateNames(a.client, snapshot.Labels[api.LabelDatabaseKind], snapshot.Spec.DatabaseName, snapshot.Namespace, amv.SnapshotNames(snapshot), nil)
+}
+
+// validateCompleted checks that the spec of a Snapshot that has succeeded is unchanged. Its labels and
+// annotations may only be changed by KubeDB operator, which records the status of the Snapshot in a label.
+func validateCompleted(c *config.Config, snapshot, oldSnapshot *api.Snapshot, user authenticationv1.UserInfo) error {
+	if oldSnapshot.Status.Phase != api.SnapshotPhaseSucceeded {
+		return nil
+	}
+	if !meta_util.Equal(snapshot.Spec, oldSnapshot.Spec) {
+		return fmt.Errorf(`snapshot "%s" has succeeded and its spec can't be changed`, snapshot.Name)
+	}
+	if c.IsOperator(user) {
+		return nil
+	}
+	if !meta_util.Equal(snapshot.Labels, oldSnapshot.Labels) || !meta_util.Equal(snapshot.Annotations, oldSnapshot.Annotations) {
+		return fmt.Errorf(`snapshot "%s" has succeeded and its labels and annotations can't be changed`, snapshot.Name)
+	}
+	return nil
+}
+
+// checkDeletion checks that snapshot is not needed to initialize a live or dormant database, and is not the newest
+// successful Snapshot of a database that can't be paused.
+func (a *SnapshotValidator) checkDeletion(snapshot *api.Snapshot) error {
+	if err := a.checkInitReferences(snapshot); err != nil {
+		return err
+	}
+	return a.checkNewestSnapshot(snapshot)
+}
+
+// checkInitReferences checks that no database and no DormantDatabase in the namespace of snapshot is initialized
+// from snapshot. Only the namespace of snapshot is listed, so that deleting a Snapshot doesn't read the databases of
+// every namespace. References from other namespaces are not checked.
+func (a *SnapshotValidator) checkInitReferences(snapshot *api.Snapshot) error {
+	for _, kind := range databaseKinds {
+		dbs, err := util.ListDatabases(a.extClient.KubedbV1alpha1(), kind, snapshot.Namespace)
+		if err != nil {
+			return err
+		}
+		for _, db := range dbs {
+			o := db.(metav1.Object)
+			if isInitializedFrom(util.GetInitSpec(db), o.GetNamespace(), snapshot) {
+				return fmt.Errorf(`snapshot "%s" is used to initialize %s "%s/%s" and can't be deleted`,
+					snapshot.Name, strings.ToLower(kind), o.GetNamespace(), o.GetName())
+			}
+		}
+	}
+
+	dormantDbs, err := a.extClient.KubedbV1alpha1().DormantDatabases(snapshot.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, ddb := range dormantDbs.Items {
+		if isInitializedFrom(util.GetOriginInitSpec(ddb.Spec.Origin.Spec), ddb.Namespace, snapshot) {
+			return fmt.Errorf(`snapshot "%s" is used to initialize dormantdatabase "%s/%s" and can't be deleted. To continue, delete the dormantdatabase first`,
+				snapshot.Name, ddb.Namespace, ddb.Name)
+		}
+	}
+	return nil
+}
+
+// isInitializedFrom returns true if a database in namespace with init spec is initialized from snapshot. A snapshot
+// source without namespace refers to the namespace of the database.
+func isInitializedFrom(init *api.InitSpec, namespace string, snapshot *api.Snapshot) bool {
+	if init == nil || init.SnapshotSource == nil || init.SnapshotSource.Name != snapshot.Name {
+		return false
+	}
+	if init.SnapshotSource.Namespace != "" {
+		namespace = init.SnapshotSource.Namespace
+	}
+	return namespace == snapshot.Namespace
+}
+
+// checkNewestSnapshot checks that snapshot is not the newest successful Snapshot of a database with
+// spec.doNotPause, which would be left without a backup to restore from.
+func (a *SnapshotValidator) checkNewestSnapshot(snapshot *api.Snapshot) error {
+	kind := snapshot.Labels[api.LabelDatabaseKind]
+	if kind == "" || snapshot.Status.Phase != api.SnapshotPhaseSucceeded {
+		return nil
+	}
+	db, err := util.GetDatabase(a.extClient.KubedbV1alpha1(), kind, snapshot.Namespace, snapshot.Spec.DatabaseName)
+	if kerr.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !util.IsDoNotPause(db) {
+		return nil
+	}
+
+	snapshotList, err := a.extClient.KubedbV1alpha1().Snapshots(snapshot.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			api.LabelDatabaseKind: kind,
+			api.LabelDatabaseName: snapshot.Spec.DatabaseName,
+		}).String(),
+	})
+	if err != nil {
+		return err
+	}
+	var newest *api.Snapshot
+	for i, s := range snapshotList.Items {
+		if s.Status.Phase == api.SnapshotPhaseSucceeded && (newest == nil || completionTime(newest).Before(completionTime(&s))) {
+			newest = &snapshotList.Items[i]
+		}
+	}
+	if newest != nil && newest.Name == snapshot.Name {
+		return fmt.Errorf(`snapshot "%s" is the newest successful Snapshot of %s "%s", which has spec.doNotPause set, and can't be deleted`,
+			snapshot.Name, strings.ToLower(kind), snapshot.Spec.DatabaseName)
+	}
+	return nil
+}
+
+// completionTime returns the time a Snapshot completed, or the time it was created if the completion time is not
+// recorded.
+func completionTime(snapshot *api.Snapshot) *metav1.Time {
+	if snapshot.Status.CompletionTime != nil {
+		return snapshot.Status.CompletionTime
+	}
+	return &snapshot.CreationTimestamp
+}
@@ -0,0 +1,359 @@
+package snapshot
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	operator = authenticationV1.UserInfo{Username: config.New().OperatorServiceAccount}
+	user     = authenticationV1.UserInfo{Username: "alice"}
+)
+
+func TestSnapshotValidator_Admit(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			validator := NewSnapshotValidator(admissionConfig, bucket.New(admissionConfig))
+
+			validator.initialized = true
+			validator.client = fake.NewSimpleClientset()
+			validator.extClient = extFake.NewSimpleClientset(
+				editSpecInit(samplePostgres(), "", "init-snapshot"),
+				sampleDormantDatabase("default", "", "dormant-init-snapshot"),
+				sampleDormantDatabase("other", "default", "foreign-init-snapshot"),
+				editNamespace(editSpecInit(samplePostgres(), "default", "foreign-init-snapshot"), "other"),
+				completedSnapshot("foo-old", api.SnapshotPhaseSucceeded, 3*time.Hour),
+				completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, 2*time.Hour),
+				completedSnapshot("foo-failed", api.SnapshotPhaseFailed, time.Hour),
+				editSnapshotDatabase(completedSnapshot("init-snapshot", api.SnapshotPhaseSucceeded, time.Hour), "baz"),
+				editSnapshotDatabase(completedSnapshot("dormant-init-snapshot", api.SnapshotPhaseSucceeded, time.Hour), "baz"),
+				editSnapshotDatabase(completedSnapshot("foreign-init-snapshot", api.SnapshotPhaseSucceeded, time.Hour), "baz"),
+			)
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+			oldObjJS, err := meta.MarshalToJson(&c.oldObject, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.objectName
+			req.Namespace = "default"
+			req.Operation = c.operation
+			req.UserInfo = c.user
+			req.Object.Raw = objJS
+			req.OldObject.Raw = oldObjJS
+
+			if c.operation == admission.Delete {
+				req.Object = runtime.RawExtension{}
+			}
+			if c.operation != admission.Update {
+				req.OldObject = runtime.RawExtension{}
+			}
+
+			response := validator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+var cases = []struct {
+	testName   string
+	objectName string
+	operation  admission.Operation
+	user       authenticationV1.UserInfo
+	object     api.Snapshot
+	oldObject  api.Snapshot
+	result     bool
+}{
+	{"Edit Spec of succeeded Snapshot",
+		"foo-new",
+		admission.Update,
+		user,
+		editSpecDatabaseName(*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour), "bar"),
+		*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour),
+		false,
+	},
+	{"Edit labels of succeeded Snapshot",
+		"foo-new",
+		admission.Update,
+		user,
+		editLabel(*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour), "app", "web"),
+		*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour),
+		false,
+	},
+	{"Edit labels of running Snapshot",
+		"foo-new",
+		admission.Update,
+		user,
+		editLabel(*completedSnapshot("foo-new", api.SnapshotPhaseRunning, time.Hour), "app", "web"),
+		*completedSnapshot("foo-new", api.SnapshotPhaseRunning, time.Hour),
+		true,
+	},
+	{"Operator edits status label of succeeded Snapshot",
+		"foo-new",
+		admission.Update,
+		operator,
+		editLabel(*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour), api.LabelSnapshotStatus, string(api.SnapshotPhaseSucceeded)),
+		*completedSnapshot("foo-new", api.SnapshotPhaseSucceeded, time.Hour),
+		true,
+	},
+	{"Delete Snapshot initializing database",
+		"init-snapshot",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		false,
+	},
+	{"Delete Snapshot initializing DormantDatabase",
+		"dormant-init-snapshot",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		false,
+	},
+	{"Delete Snapshot initializing DormantDatabase in other namespace",
+		"foreign-init-snapshot",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+	{"Delete Snapshot initializing database in other namespace",
+		"foreign-init-snapshot",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+	{"Delete newest Snapshot of database with Spec.DoNotPause=true",
+		"foo-new",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		false,
+	},
+	{"Operator deletes newest Snapshot of database with Spec.DoNotPause=true",
+		"foo-new",
+		admission.Delete,
+		operator,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+	{"Delete older Snapshot of database with Spec.DoNotPause=true",
+		"foo-old",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+	{"Delete failed Snapshot of database with Spec.DoNotPause=true",
+		"foo-failed",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+	{"Delete Non Existing Snapshot",
+		"foo",
+		admission.Delete,
+		user,
+		api.Snapshot{},
+		api.Snapshot{},
+		true,
+	},
+}
+
+func TestSnapshotValidator_validateReadiness(t *testing.T) {
+	for _, c := range readinessCases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			validator := NewSnapshotValidator(admissionConfig, bucket.New(admissionConfig))
+			validator.extClient = extFake.NewSimpleClientset(c.database)
+
+			snapshot := completedSnapshot("new", "", 0)
+			snapshot.Labels[api.LabelDatabaseKind] = c.kind
+			snapshot.Annotations = c.annotations
+
+			err := validator.validateSnapshot(snapshot)
+			if err == nil {
+				err = validator.validateReadiness(snapshot)
+			}
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var readinessCases = []struct {
+	testName    string
+	kind        string
+	database    runtime.Object
+	annotations map[string]string
+	result      bool
+}{
+	{"Snapshot of running database",
+		api.ResourceKindPostgres,
+		editPhase(samplePostgres(), api.DatabasePhaseRunning),
+		nil,
+		true,
+	},
+	{"Snapshot of creating database",
+		api.ResourceKindPostgres,
+		editPhase(samplePostgres(), api.DatabasePhaseCreating),
+		nil,
+		false,
+	},
+	{"Snapshot of initializing database",
+		api.ResourceKindPostgres,
+		editPhase(samplePostgres(), api.DatabasePhaseInitializing),
+		map[string]string{ForceKey: "true"},
+		false,
+	},
+	{"Snapshot of database without phase",
+		api.ResourceKindPostgres,
+		samplePostgres(),
+		nil,
+		false,
+	},
+	{"Snapshot of failed database",
+		api.ResourceKindPostgres,
+		editPhase(samplePostgres(), api.DatabasePhaseFailed),
+		nil,
+		false,
+	},
+	{"Forced Snapshot of failed database",
+		api.ResourceKindPostgres,
+		editPhase(samplePostgres(), api.DatabasePhaseFailed),
+		map[string]string{ForceKey: "true"},
+		true,
+	},
+	{"Snapshot of running Redis",
+		api.ResourceKindRedis,
+		&api.Redis{
+			ObjectMeta: metaV1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status:     api.RedisStatus{Phase: api.DatabasePhaseRunning},
+		},
+		nil,
+		false,
+	},
+	{"Snapshot of running Memcached",
+		api.ResourceKindMemcached,
+		&api.Memcached{
+			ObjectMeta: metaV1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status:     api.MemcachedStatus{Phase: api.DatabasePhaseRunning},
+		},
+		nil,
+		false,
+	},
+	{"Snapshot of missing database",
+		api.ResourceKindMySQL,
+		editPhase(samplePostgres(), api.DatabasePhaseRunning),
+		nil,
+		false,
+	},
+}
+
+// completedSnapshot returns a Snapshot of samplePostgres that completed age ago
+func completedSnapshot(name string, phase api.SnapshotPhase, age time.Duration) *api.Snapshot {
+	snapshot := sampleSnapshot()
+	snapshot.Name = name
+	snapshot.Labels = map[string]string{
+		api.LabelDatabaseKind: api.ResourceKindPostgres,
+		api.LabelDatabaseName: snapshot.Spec.DatabaseName,
+	}
+	snapshot.Spec.SnapshotStorageSpec = samplePostgres().Spec.BackupSchedule.SnapshotStorageSpec
+	completionTime := metaV1.NewTime(time.Now().Add(-age).Truncate(time.Second))
+	snapshot.Status = api.SnapshotStatus{
+		Phase:          phase,
+		CompletionTime: &completionTime,
+	}
+	return &snapshot
+}
+
+func sampleDormantDatabase(namespace, snapshotNamespace, snapshotName string) *api.DormantDatabase {
+	spec := editSpecInit(samplePostgres(), snapshotNamespace, snapshotName).Spec
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "bar",
+			Namespace: namespace,
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Postgres: &spec,
+				},
+			},
+		},
+	}
+}
+
+func editNamespace(old *api.Postgres, namespace string) *api.Postgres {
+	old.Namespace = namespace
+	return old
+}
+
+func editSpecInit(old *api.Postgres, snapshotNamespace, snapshotName string) *api.Postgres {
+	old.Spec.DoNotPause = true
+	old.Spec.Init = &api.InitSpec{
+		SnapshotSource: &api.SnapshotSourceSpec{
+			Namespace: snapshotNamespace,
+			Name:      snapshotName,
+		},
+	}
+	return old
+}
+
+func editSnapshotDatabase(old *api.Snapshot, name string) *api.Snapshot {
+	old.Spec.DatabaseName = name
+	old.Labels[api.LabelDatabaseName] = name
+	return old
+}
+
+func editLabel(old api.Snapshot, key, value string) api.Snapshot {
+	old.Labels[key] = value
+	return old
+}
+
+func editPhase(old *api.Postgres, phase api.DatabasePhase) *api.Postgres {
+	old.Status.Phase = phase
+	return old
+}
@@ -0,0 +1,167 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	core_util "github.com/appscode/kutil/core/v1"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LockKey is the annotation of the lock ConfigMap of a database that records the Snapshot being taken of it. It is
+// set when a Snapshot is admitted, and removed when KubeDB operator moves the Snapshot to a terminal phase.
+// Kubernetes 1.9 has no coordination API, so a ConfigMap next to the database is the lock: the annotation is
+// written against the resourceVersion the lock was checked at, and concurrent writers fail with a conflict and
+// check the lock again. The database itself is not written, so that it needs no update permission and its
+// webhooks are not called again.
+const LockKey = amv.SnapshotLockKey
+
+// maxLockAttempts is the number of times the lock of a database is checked and written before giving up
+const maxLockAttempts = 5
+
+// lockRecord is the value of the LockKey annotation
+type lockRecord struct {
+	HolderIdentity string      `json:"holderIdentity"`
+	AcquireTime    metav1.Time `json:"acquireTime"`
+}
+
+// acquireLock records snapshot as the Snapshot being taken of its database. It fails if another Snapshot holds
+// the lock. The lock ConfigMap is created on first use and owned by the database, so that it is garbage collected
+// with the database.
+func acquireLock(client kubernetes.Interface, extClient cs.Interface, snapshot *api.Snapshot, now time.Time, timeout time.Duration) error {
+	kind := snapshot.Labels[api.LabelDatabaseKind]
+	name := amv.SnapshotLockName(kind, snapshot.Spec.DatabaseName)
+	record, err := json.Marshal(lockRecord{HolderIdentity: snapshot.Name, AcquireTime: metav1.NewTime(now)})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < maxLockAttempts; i++ {
+		lock, err := client.CoreV1().ConfigMaps(snapshot.Namespace).Get(name, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			lock, err = newLock(extClient, snapshot, name)
+			if err != nil {
+				return err
+			}
+			lock.Annotations = map[string]string{LockKey: string(record)}
+			if _, err := client.CoreV1().ConfigMaps(snapshot.Namespace).Create(lock); !kerr.IsAlreadyExists(err) {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if holder, err := lockHolder(extClient, lock, snapshot, now, timeout); err != nil {
+			return err
+		} else if holder != "" {
+			return fmt.Errorf(`snapshot "%s" of %s "%s" is already running`, holder, strings.ToLower(kind), snapshot.Spec.DatabaseName)
+		}
+		lock.Annotations = core_util.UpsertMap(lock.Annotations, map[string]string{LockKey: string(record)})
+		if _, err := client.CoreV1().ConfigMaps(snapshot.Namespace).Update(lock); !kerr.IsConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf(`failed to lock %s "%s" for snapshot "%s", it is changed concurrently. Retry later`,
+		strings.ToLower(kind), snapshot.Spec.DatabaseName, snapshot.Name)
+}
+
+// newLock returns the lock ConfigMap with name for the database of snapshot, owned by the database.
+func newLock(extClient cs.Interface, snapshot *api.Snapshot, name string) (*core.ConfigMap, error) {
+	kind := snapshot.Labels[api.LabelDatabaseKind]
+	db, err := util.GetDatabase(extClient.KubedbV1alpha1(), kind, snapshot.Namespace, snapshot.Spec.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+	o := db.(metav1.Object)
+	return &core.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: snapshot.Namespace,
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+				api.LabelDatabaseName: o.GetName(),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: api.SchemeGroupVersion.String(),
+					Kind:       kind,
+					Name:       o.GetName(),
+					UID:        o.GetUID(),
+				},
+			},
+		},
+	}, nil
+}
+
+// lockHolder returns the name of the Snapshot that holds a lock, or an empty string if the lock is free for
+// snapshot. A lock is free if snapshot holds it, or its holder has completed. A holder that doesn't exist may still
+// be in the admission chain, so its lock is only free once the webhook timeout has passed.
+func lockHolder(extClient cs.Interface, lock metav1.Object, snapshot *api.Snapshot, now time.Time, timeout time.Duration) (string, error) {
+	record, found := getLockRecord(lock)
+	if !found || record.HolderIdentity == snapshot.Name {
+		return "", nil
+	}
+
+	holder, err := extClient.KubedbV1alpha1().Snapshots(lock.GetNamespace()).Get(record.HolderIdentity, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		if now.Sub(record.AcquireTime.Time) < timeout {
+			return record.HolderIdentity, nil
+		}
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if isCompleted(holder.Status.Phase) {
+		return "", nil
+	}
+	return record.HolderIdentity, nil
+}
+
+// releaseLock removes the lock of the database of snapshot, if snapshot holds it. The lock ConfigMap is kept, as
+// deleting it could remove a lock acquired after it was read.
+func releaseLock(client kubernetes.Interface, snapshot *api.Snapshot) error {
+	kind := snapshot.Labels[api.LabelDatabaseKind]
+	name := amv.SnapshotLockName(kind, snapshot.Spec.DatabaseName)
+	for i := 0; i < maxLockAttempts; i++ {
+		lock, err := client.CoreV1().ConfigMaps(snapshot.Namespace).Get(name, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if record, found := getLockRecord(lock); !found || record.HolderIdentity != snapshot.Name {
+			return nil
+		}
+
+		delete(lock.Annotations, LockKey)
+		if _, err := client.CoreV1().ConfigMaps(snapshot.Namespace).Update(lock); !kerr.IsConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf(`failed to unlock %s "%s" for snapshot "%s", it is changed concurrently`,
+		strings.ToLower(kind), snapshot.Spec.DatabaseName, snapshot.Name)
+}
+
+// getLockRecord returns the lock record of a lock ConfigMap. A malformed record is not honored.
+func getLockRecord(lock metav1.Object) (lockRecord, bool) {
+	var record lockRecord
+	val, found := lock.GetAnnotations()[LockKey]
+	if !found || json.Unmarshal([]byte(val), &record) != nil || record.HolderIdentity == "" {
+		return lockRecord{}, false
+	}
+	return record, true
+}
+
+// isCompleted returns true if a Snapshot in phase is not running and won't run anymore.
+func isCompleted(phase api.SnapshotPhase) bool {
+	return phase == api.SnapshotPhaseSucceeded || phase == api.SnapshotPhaseFailed
+}
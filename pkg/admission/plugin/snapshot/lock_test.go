@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
+)
+
+func TestAcquireLock(t *testing.T) {
+	now := time.Now()
+	for _, c := range lockCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if c.lock != "" {
+				client = fake.NewSimpleClientset(sampleLock(c.lock))
+			}
+			extClient := extFake.NewSimpleClientset(
+				samplePostgres(),
+				completedSnapshot("running", api.SnapshotPhaseRunning, time.Hour),
+				completedSnapshot("pending", "", time.Hour),
+				completedSnapshot("succeeded", api.SnapshotPhaseSucceeded, time.Hour),
+			)
+
+			snapshot := completedSnapshot("new", "", 0)
+			err := acquireLock(client, extClient, snapshot, now, config.New().WebhookTimeout)
+			if c.result != (err == nil) {
+				t.Fatalf("expected success: %v, but got error: %v", c.result, err)
+			}
+
+			lock, err := client.CoreV1().ConfigMaps("default").Get(amv.SnapshotLockName(api.ResourceKindPostgres, "foo"), metaV1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			record, _ := getLockRecord(lock)
+			if record.HolderIdentity != c.holder {
+				t.Errorf("expected lock holder: %q, but got: %q", c.holder, record.HolderIdentity)
+			}
+			if owners := lock.OwnerReferences; len(owners) != 1 || owners[0].Kind != api.ResourceKindPostgres || owners[0].Name != "foo" {
+				t.Errorf("expected lock owned by the database, but got owners: %v", owners)
+			}
+		})
+	}
+}
+
+var lockCases = []struct {
+	testName string
+	lock     string
+	holder   string
+	result   bool
+}{
+	{"Unlocked database",
+		"",
+		"new",
+		true,
+	},
+	{"Database locked by running Snapshot",
+		lockValue("running", time.Hour),
+		"running",
+		false,
+	},
+	{"Database locked by Snapshot not labeled as running yet",
+		lockValue("pending", time.Hour),
+		"pending",
+		false,
+	},
+	{"Database locked by succeeded Snapshot",
+		lockValue("succeeded", time.Hour),
+		"new",
+		true,
+	},
+	{"Database locked by Snapshot in admission",
+		lockValue("admitted", time.Second),
+		"admitted",
+		false,
+	},
+	{"Database locked by denied Snapshot",
+		lockValue("denied", time.Hour),
+		"new",
+		true,
+	},
+	{"Database locked by the same Snapshot",
+		lockValue("new", time.Second),
+		"new",
+		true,
+	},
+	{"Database with malformed lock",
+		"new",
+		"new",
+		true,
+	},
+}
+
+func TestReleaseLock(t *testing.T) {
+	for _, c := range releaseCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(sampleLock(lockValue("running", time.Hour)))
+
+			if err := releaseLock(client, completedSnapshot(c.snapshot, api.SnapshotPhaseSucceeded, 0)); err != nil {
+				t.Fatal(err)
+			}
+
+			lock, err := client.CoreV1().ConfigMaps("default").Get(amv.SnapshotLockName(api.ResourceKindPostgres, "foo"), metaV1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			record, _ := getLockRecord(lock)
+			if record.HolderIdentity != c.holder {
+				t.Errorf("expected lock holder: %q, but got: %q", c.holder, record.HolderIdentity)
+			}
+		})
+	}
+}
+
+var releaseCases = []struct {
+	testName string
+	snapshot string
+	holder   string
+}{
+	{"Release lock held by Snapshot",
+		"running",
+		"",
+	},
+	{"Release lock held by other Snapshot",
+		"other",
+		"running",
+	},
+}
+
+func TestLockConflict(t *testing.T) {
+	client := fake.NewSimpleClientset(sampleLock(lockValue("succeeded", time.Hour)))
+	extClient := extFake.NewSimpleClientset(samplePostgres(), completedSnapshot("succeeded", api.SnapshotPhaseSucceeded, time.Hour))
+
+	// the first update of each call conflicts, as if the lock was written after it was read
+	updates := 0
+	client.PrependReactor("update", "configmaps", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		updates++
+		if updates%2 == 1 {
+			return true, nil, kerr.NewConflict(core.Resource("configmaps"), amv.SnapshotLockName(api.ResourceKindPostgres, "foo"),
+				errors.New("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	snapshot := completedSnapshot("new", "", 0)
+	if err := acquireLock(client, extClient, snapshot, time.Now(), config.New().WebhookTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if updates != 2 {
+		t.Errorf("expected 2 updates to acquire the lock, but got: %d", updates)
+	}
+	if holder := lockHolderIdentity(t, client); holder != "new" {
+		t.Errorf("expected lock holder: %q, but got: %q", "new", holder)
+	}
+
+	if err := releaseLock(client, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if updates != 4 {
+		t.Errorf("expected 2 updates to release the lock, but got: %d", updates-2)
+	}
+	if holder := lockHolderIdentity(t, client); holder != "" {
+		t.Errorf("expected lock holder: %q, but got: %q", "", holder)
+	}
+}
+
+// lockHolderIdentity returns the holder recorded in the lock ConfigMap of the Postgres "foo"
+func lockHolderIdentity(t *testing.T, client *fake.Clientset) string {
+	lock, err := client.CoreV1().ConfigMaps("default").Get(amv.SnapshotLockName(api.ResourceKindPostgres, "foo"), metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, _ := getLockRecord(lock)
+	return record.HolderIdentity
+}
+
+// lockValue returns the lock record of a lock acquired by holder age ago
+func lockValue(holder string, age time.Duration) string {
+	record, err := json.Marshal(lockRecord{
+		HolderIdentity: holder,
+		AcquireTime:    metaV1.NewTime(time.Now().Add(-age)),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(record)
+}
+
+// sampleLock returns the lock ConfigMap of the Postgres "foo" with the lock record lock
+func sampleLock(lock string) *core.ConfigMap {
+	return &core.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      amv.SnapshotLockName(api.ResourceKindPostgres, "foo"),
+			Namespace: "default",
+			Annotations: map[string]string{
+				LockKey: lock,
+			},
+			OwnerReferences: []metaV1.OwnerReference{
+				{
+					APIVersion: api.SchemeGroupVersion.String(),
+					Kind:       api.ResourceKindPostgres,
+					Name:       "foo",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	core_util "github.com/appscode/kutil/core/v1"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	admission "k8s.io/api/admission/v1beta1"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// databaseKinds are the kinds of the databases a Snapshot can be taken of
+var databaseKinds = []string{
+	api.ResourceKindElasticsearch,
+	api.ResourceKindPostgres,
+	api.ResourceKindMongoDB,
+	api.ResourceKindMySQL,
+	api.ResourceKindRedis,
+	api.ResourceKindMemcached,
+}
+
+// SnapshotMutator labels a new Snapshot with its database, and defaults its storage and resources from the backup
+// schedule of the database. With --snapshot-owner-reference, the Snapshot is also owned by its database.
+type SnapshotMutator struct {
+	config      *config.Config
+	extClient   cs.Interface
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &SnapshotMutator{}
+
+// NewSnapshotMutator returns the mutator of Snapshots configured by c.
+func NewSnapshotMutator(c *config.Config) *SnapshotMutator {
+	return &SnapshotMutator{config: c}
+}
+
+func (a *SnapshotMutator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "snapshotmutationreviews",
+		},
+		"snapshotmutationreview"
+}
+
+func (a *SnapshotMutator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+
+	var err error
+	if a.extClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+	return err
+}
+
+func (a *SnapshotMutator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	// N.B.: Snapshots are only mutated on create, later changes of the labels are denied by SnapshotValidator
+	if req.Operation != admission.Create ||
+		len(req.SubResource) != 0 ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		req.Kind.Kind != api.ResourceKindSnapshot {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+	obj, err := meta_util.UnmarshalFromJSON(req.Object.Raw, api.SchemeGroupVersion)
+	if err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	snapshotMod, err := setDefaultValues(a.config, a.extClient, obj.(*api.Snapshot).DeepCopy())
+	if err != nil {
+		return hookapi.StatusForbidden(err)
+	} else if snapshotMod != nil {
+		patch, err := meta_util.CreateJSONPatch(obj, snapshotMod)
+		if err != nil {
+			return hookapi.StatusInternalServerError(err)
+		}
+		status.Patch = patch
+		patchType := admission.PatchTypeJSONPatch
+		status.PatchType = &patchType
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// setDefaultValues provides the defaulting that is performed in mutating stage of creating a Snapshot. A Snapshot
+// whose database doesn't exist is left for SnapshotValidator to deny.
+func setDefaultValues(c *config.Config, extClient cs.Interface, snapshot *api.Snapshot) (runtime.Object, error) {
+	if snapshot.Spec.DatabaseName == "" {
+		return nil, fmt.Errorf(`object 'DatabaseName' is missing in '%v'`, snapshot.Spec)
+	}
+
+	kind, db, err := findDatabase(extClient, snapshot)
+	if err != nil {
+		return nil, err
+	} else if db == nil {
+		return snapshot, nil
+	}
+
+	snapshot.Labels = core_util.UpsertMap(snapshot.Labels, map[string]string{
+		api.LabelDatabaseKind: kind,
+		api.LabelDatabaseName: snapshot.Spec.DatabaseName,
+	})
+
+	// Take the storage and the resources not given in the Snapshot from the backup schedule of the database
+	if schedule := util.GetBackupSchedule(db); schedule != nil {
+		if reflect.DeepEqual(snapshot.Spec.SnapshotStorageSpec, api.SnapshotStorageSpec{}) {
+			snapshot.Spec.SnapshotStorageSpec = *schedule.SnapshotStorageSpec.DeepCopy()
+		}
+		if len(snapshot.Spec.Resources.Limits) == 0 && len(snapshot.Spec.Resources.Requests) == 0 {
+			snapshot.Spec.Resources = *schedule.Resources.DeepCopy()
+		}
+	}
+
+	if c.SnapshotOwnerReference {
+		o, err := meta.Accessor(db)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.ObjectMeta = core_util.EnsureOwnerReference(snapshot.ObjectMeta, &core.ObjectReference{
+			APIVersion: api.SchemeGroupVersion.String(),
+			Kind:       kind,
+			Name:       o.GetName(),
+			UID:        o.GetUID(),
+		})
+	}
+
+	return snapshot, nil
+}
+
+// findDatabase returns the kind and the database of snapshot. If the Snapshot is not labeled with the database
+// kind, the kind is looked up by spec.databaseName, and must be unambiguous. It returns a nil database if none
+// is found.
+func findDatabase(extClient cs.Interface, snapshot *api.Snapshot) (string, runtime.Object, error) {
+	kinds := databaseKinds
+	if kind := snapshot.Labels[api.LabelDatabaseKind]; kind != "" {
+		kinds = []string{kind}
+	}
+
+	var foundKinds []string
+	var found runtime.Object
+	for _, kind := range kinds {
+		db, err := util.GetDatabase(extClient.KubedbV1alpha1(), kind, snapshot.Namespace, snapshot.Spec.DatabaseName)
+		if kerr.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", nil, err
+		}
+		foundKinds = append(foundKinds, kind)
+		found = db
+	}
+
+	switch len(foundKinds) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return foundKinds[0], found, nil
+	}
+	return "", nil, fmt.Errorf(`databases of kinds %s are named "%s". Label the Snapshot with %s to choose one`,
+		strings.Join(foundKinds, ", "), snapshot.Spec.DatabaseName, api.LabelDatabaseKind)
+}
@@ -0,0 +1,265 @@
+package snapshot
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+}
+
+var requestKind = metaV1.GroupVersionKind{
+	Group:   api.SchemeGroupVersion.Group,
+	Version: api.SchemeGroupVersion.Version,
+	Kind:    api.ResourceKindSnapshot,
+}
+
+func TestSnapshotMutator_Admit(t *testing.T) {
+	for _, c := range mutatorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			admissionConfig := config.New()
+			admissionConfig.SnapshotOwnerReference = c.ownerReference
+			mutator := NewSnapshotMutator(admissionConfig)
+
+			mutator.initialized = true
+			mutator.extClient = extFake.NewSimpleClientset(c.databases...)
+
+			objJS, err := meta.MarshalToJson(&c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind
+			req.Name = c.object.Name
+			req.Namespace = c.object.Namespace
+			req.Operation = admission.Create
+			req.UserInfo = authenticationV1.UserInfo{}
+			req.Object.Raw = objJS
+
+			response := mutator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+					return
+				}
+				modJS := objJS
+				if response.Patch != nil {
+					patch, err := jsonpatch.DecodePatch(response.Patch)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if modJS, err = patch.Apply(objJS); err != nil {
+						t.Fatal(err)
+					}
+				}
+				mod, err := meta.UnmarshalFromJSON(modJS, api.SchemeGroupVersion)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.Equal(mod.(*api.Snapshot).ObjectMeta, c.expected.ObjectMeta) {
+					t.Errorf("expected metadata mismatches. Diff: %v", meta.Diff(c.expected.ObjectMeta, mod.(*api.Snapshot).ObjectMeta))
+				}
+				if !meta.Equal(mod.(*api.Snapshot).Spec, c.expected.Spec) {
+					t.Errorf("expected spec mismatches. Diff: %v", meta.Diff(c.expected.Spec, mod.(*api.Snapshot).Spec))
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+var mutatorCases = []struct {
+	testName       string
+	object         api.Snapshot
+	databases      []runtime.Object
+	ownerReference bool
+	expected       api.Snapshot
+	result         bool
+}{
+	{"Create Snapshot",
+		sampleSnapshot(),
+		[]runtime.Object{samplePostgres()},
+		false,
+		editLabels(editSpecDefaults(sampleSnapshot()), api.ResourceKindPostgres),
+		true,
+	},
+	{"Create Snapshot with storage and resources",
+		editSpecResources(editSpecStorage(sampleSnapshot())),
+		[]runtime.Object{samplePostgres()},
+		false,
+		editLabels(editSpecResources(editSpecStorage(sampleSnapshot())), api.ResourceKindPostgres),
+		true,
+	},
+	{"Create Snapshot of database without backup schedule",
+		sampleSnapshot(),
+		[]runtime.Object{sampleMySQL()},
+		false,
+		editLabels(sampleSnapshot(), api.ResourceKindMySQL),
+		true,
+	},
+	{"Create Snapshot of ambiguous database",
+		sampleSnapshot(),
+		[]runtime.Object{samplePostgres(), sampleMySQL()},
+		false,
+		api.Snapshot{},
+		false,
+	},
+	{"Create Snapshot labeled with kind of ambiguous database",
+		editLabels(sampleSnapshot(), api.ResourceKindMySQL),
+		[]runtime.Object{samplePostgres(), sampleMySQL()},
+		false,
+		editLabels(sampleSnapshot(), api.ResourceKindMySQL),
+		true,
+	},
+	{"Create Snapshot of missing database",
+		sampleSnapshot(),
+		nil,
+		false,
+		sampleSnapshot(),
+		true,
+	},
+	{"Create Snapshot without DatabaseName",
+		editSpecDatabaseName(sampleSnapshot(), ""),
+		[]runtime.Object{samplePostgres()},
+		false,
+		api.Snapshot{},
+		false,
+	},
+	{"Create Snapshot owned by its database",
+		sampleSnapshot(),
+		[]runtime.Object{samplePostgres()},
+		true,
+		editOwnerReference(editLabels(editSpecDefaults(sampleSnapshot()), api.ResourceKindPostgres), api.ResourceKindPostgres),
+		true,
+	},
+}
+
+func sampleSnapshot() api.Snapshot {
+	return api.Snapshot{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindSnapshot,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo-snapshot",
+			Namespace: "default",
+		},
+		Spec: api.SnapshotSpec{
+			DatabaseName: "foo",
+		},
+	}
+}
+
+func samplePostgres() *api.Postgres {
+	return &api.Postgres{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "postgres-uid",
+		},
+		Spec: api.PostgresSpec{
+			Version: "9.6",
+			BackupSchedule: &api.BackupScheduleSpec{
+				CronExpression: "@every 6h",
+				SnapshotStorageSpec: api.SnapshotStorageSpec{
+					StorageSecretName: "gcs-secret",
+					GCS: &api.GCSSpec{
+						Bucket: "kubedb",
+					},
+				},
+				Resources: core.ResourceRequirements{
+					Requests: core.ResourceList{
+						core.ResourceMemory: resource.MustParse("256Mi"),
+					},
+				},
+			},
+		},
+	}
+}
+
+func sampleMySQL() *api.MySQL {
+	return &api.MySQL{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "mysql-uid",
+		},
+		Spec: api.MySQLSpec{
+			Version: "8.0",
+		},
+	}
+}
+
+func editLabels(old api.Snapshot, kind string) api.Snapshot {
+	old.Labels = map[string]string{
+		api.LabelDatabaseKind: kind,
+		api.LabelDatabaseName: old.Spec.DatabaseName,
+	}
+	return old
+}
+
+// editSpecDefaults sets the storage and the resources of the backup schedule of samplePostgres
+func editSpecDefaults(old api.Snapshot) api.Snapshot {
+	schedule := samplePostgres().Spec.BackupSchedule
+	old.Spec.SnapshotStorageSpec = schedule.SnapshotStorageSpec
+	old.Spec.Resources = schedule.Resources
+	return old
+}
+
+func editSpecStorage(old api.Snapshot) api.Snapshot {
+	old.Spec.SnapshotStorageSpec = api.SnapshotStorageSpec{
+		StorageSecretName: "s3-secret",
+		S3: &api.S3Spec{
+			Bucket: "kubedb-qa",
+		},
+	}
+	return old
+}
+
+func editSpecResources(old api.Snapshot) api.Snapshot {
+	old.Spec.Resources = core.ResourceRequirements{
+		Limits: core.ResourceList{
+			core.ResourceCPU: resource.MustParse("500m"),
+		},
+	}
+	return old
+}
+
+func editSpecDatabaseName(old api.Snapshot, name string) api.Snapshot {
+	old.Spec.DatabaseName = name
+	return old
+}
+
+func editOwnerReference(old api.Snapshot, kind string) api.Snapshot {
+	blockOwnerDeletion := false
+	old.OwnerReferences = []metaV1.OwnerReference{
+		{
+			APIVersion:         api.SchemeGroupVersion.String(),
+			Kind:               kind,
+			Name:               old.Spec.DatabaseName,
+			UID:                "postgres-uid",
+			BlockOwnerDeletion: &blockOwnerDeletion,
+		},
+	}
+	return old
+}
@@ -0,0 +1,124 @@
+package status
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+)
+
+// kinds are the KubeDB kinds whose status is written by KubeDB operator
+var kinds = sets.NewString(
+	api.ResourceKindElasticsearch,
+	api.ResourceKindPostgres,
+	api.ResourceKindMySQL,
+	api.ResourceKindMongoDB,
+	api.ResourceKindRedis,
+	api.ResourceKindMemcached,
+	api.ResourceKindSnapshot,
+	api.ResourceKindDormantDatabase,
+)
+
+// StatusValidator allows only KubeDB operator to write the status of KubeDB objects, either through the main
+// resource or the status subresource.
+type StatusValidator struct {
+	config      *config.Config
+	lock        sync.RWMutex
+	initialized bool
+}
+
+var _ hookapi.AdmissionHook = &StatusValidator{}
+
+// NewStatusValidator returns the StatusValidator that allows the operator named by c.
+func NewStatusValidator(c *config.Config) *StatusValidator {
+	return &StatusValidator{config: c}
+}
+
+func (a *StatusValidator) Resource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.kubedb.com",
+			Version:  "v1alpha1",
+			Resource: "statusreviews",
+		},
+		"statusreview"
+}
+
+func (a *StatusValidator) Initialize(config *rest.Config, stopCh <-chan struct{}) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.initialized = true
+	return nil
+}
+
+func (a *StatusValidator) Admit(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	status := &admission.AdmissionResponse{}
+
+	if (req.Operation != admission.Create && req.Operation != admission.Update) ||
+		(len(req.SubResource) != 0 && req.SubResource != "status") ||
+		req.Kind.Group != api.SchemeGroupVersion.Group ||
+		!kinds.Has(req.Kind.Kind) {
+		status.Allowed = true
+		return status
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if !a.initialized {
+		return hookapi.StatusUninitialized()
+	}
+
+	if a.config.IsOperator(req.UserInfo) {
+		status.Allowed = true
+		return status
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+		return hookapi.StatusBadRequest(err)
+	}
+	// An update of the main resource that doesn't set a status, eg: kubectl replace or apply of a manifest without
+	// status, doesn't write the status, and KubeDB operator sets it again.
+	if val, _ := unstructured.NestedMap(obj.Object, "status"); len(val) == 0 && req.SubResource == "" {
+		status.Allowed = true
+		return status
+	}
+	// a new object has no status
+	oldObj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if req.Operation == admission.Update {
+		if err := oldObj.UnmarshalJSON(req.OldObject.Raw); err != nil {
+			return hookapi.StatusBadRequest(err)
+		}
+	}
+
+	if field := changedStatusField(obj, oldObj); field != "" {
+		return hookapi.StatusForbidden(fmt.Errorf(`%s of %s "%s" is written by KubeDB operator and can't be changed`,
+			field, strings.ToLower(req.Kind.Kind), req.Name))
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// changedStatusField returns the path of the first status field that differs between obj and oldObj, or an empty
+// string if the status is unchanged.
+func changedStatusField(obj, oldObj *unstructured.Unstructured) string {
+	val, _ := unstructured.NestedMap(obj.Object, "status")
+	oldVal, _ := unstructured.NestedMap(oldObj.Object, "status")
+
+	for _, key := range sets.StringKeySet(val).Union(sets.StringKeySet(oldVal)).List() {
+		if !reflect.DeepEqual(val[key], oldVal[key]) {
+			return "status." + key
+		}
+	}
+	return ""
+}
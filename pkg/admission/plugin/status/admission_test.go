@@ -0,0 +1,283 @@
+package status
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+}
+
+var (
+	operator = authenticationV1.UserInfo{Username: config.New().OperatorServiceAccount}
+	user     = authenticationV1.UserInfo{Username: "alice"}
+)
+
+func requestKind(kind string) metaV1.GroupVersionKind {
+	return metaV1.GroupVersionKind{
+		Group:   api.SchemeGroupVersion.Group,
+		Version: api.SchemeGroupVersion.Version,
+		Kind:    kind,
+	}
+}
+
+func TestStatusValidator_Admit(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			validator := NewStatusValidator(config.New())
+			validator.initialized = true
+
+			objJS, err := meta.MarshalToJson(c.object, api.SchemeGroupVersion)
+			if err != nil {
+				panic(err)
+			}
+
+			req := new(admission.AdmissionRequest)
+
+			req.Kind = requestKind(c.kind)
+			req.Name = "foo"
+			req.Namespace = "default"
+			req.Operation = c.operation
+			req.SubResource = c.subResource
+			req.UserInfo = c.user
+			req.Object.Raw = objJS
+			if c.oldObject != nil {
+				oldObjJS, err := meta.MarshalToJson(c.oldObject, api.SchemeGroupVersion)
+				if err != nil {
+					panic(err)
+				}
+				req.OldObject.Raw = oldObjJS
+			}
+
+			response := validator.Admit(req)
+			if c.result == true {
+				if response.Allowed != true {
+					t.Errorf("expected: 'Allowed=true'. but got response: %v", response)
+				}
+			} else if c.result == false {
+				if response.Allowed == true || response.Result.Code == http.StatusInternalServerError {
+					t.Errorf("expected: 'Allowed=false', but got response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+var cases = []struct {
+	testName    string
+	kind        string
+	operation   admission.Operation
+	subResource string
+	user        authenticationV1.UserInfo
+	object      runtime.Object
+	oldObject   runtime.Object
+	result      bool
+}{
+	{"Operator edits Postgres status",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"",
+		operator,
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseCreating),
+		true,
+	},
+	{"Edit Postgres status",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"",
+		user,
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseCreating),
+		false,
+	},
+	{"Edit Postgres status subresource",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"status",
+		user,
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseCreating),
+		false,
+	},
+	{"Edit Postgres spec",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"",
+		user,
+		editPostgresDoNotPause(editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning)),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		true,
+	},
+	{"Replace Postgres without status",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"",
+		user,
+		editPostgresDoNotPause(samplePostgres()),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		true,
+	},
+	{"Edit Postgres status subresource without status",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"status",
+		user,
+		samplePostgres(),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		false,
+	},
+	{"Scale Postgres",
+		api.ResourceKindPostgres,
+		admission.Update,
+		"scale",
+		user,
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseCreating),
+		true,
+	},
+	{"Create Postgres with status",
+		api.ResourceKindPostgres,
+		admission.Create,
+		"",
+		user,
+		editPostgresPhase(samplePostgres(), api.DatabasePhaseRunning),
+		nil,
+		false,
+	},
+	{"Create Postgres",
+		api.ResourceKindPostgres,
+		admission.Create,
+		"",
+		user,
+		samplePostgres(),
+		nil,
+		true,
+	},
+	{"Edit Snapshot completion time",
+		api.ResourceKindSnapshot,
+		admission.Update,
+		"",
+		user,
+		editSnapshotCompletionTime(sampleSnapshot()),
+		sampleSnapshot(),
+		false,
+	},
+	{"Apply Snapshot without status",
+		api.ResourceKindSnapshot,
+		admission.Update,
+		"",
+		user,
+		removeSnapshotStatus(sampleSnapshot()),
+		editSnapshotCompletionTime(sampleSnapshot()),
+		true,
+	},
+	{"Edit DormantDatabase wipe out time",
+		api.ResourceKindDormantDatabase,
+		admission.Update,
+		"status",
+		user,
+		editDormantDatabaseWipeOutTime(sampleDormantDatabase()),
+		sampleDormantDatabase(),
+		false,
+	},
+	{"Operator edits DormantDatabase wipe out time",
+		api.ResourceKindDormantDatabase,
+		admission.Update,
+		"status",
+		operator,
+		editDormantDatabaseWipeOutTime(sampleDormantDatabase()),
+		sampleDormantDatabase(),
+		true,
+	},
+}
+
+func samplePostgres() *api.Postgres {
+	return &api.Postgres{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindPostgres,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: api.PostgresSpec{
+			Version:    "9.6",
+			DoNotPause: true,
+		},
+	}
+}
+
+func editPostgresPhase(old *api.Postgres, phase api.DatabasePhase) *api.Postgres {
+	old.Status.Phase = phase
+	return old
+}
+
+func editPostgresDoNotPause(old *api.Postgres) *api.Postgres {
+	old.Spec.DoNotPause = false
+	return old
+}
+
+func sampleSnapshot() *api.Snapshot {
+	return &api.Snapshot{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindSnapshot,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: api.SnapshotSpec{
+			DatabaseName: "foo",
+		},
+		Status: api.SnapshotStatus{
+			Phase: api.SnapshotPhaseSucceeded,
+		},
+	}
+}
+
+func editSnapshotCompletionTime(old *api.Snapshot) *api.Snapshot {
+	now := metaV1.Now()
+	old.Status.CompletionTime = &now
+	return old
+}
+
+func removeSnapshotStatus(old *api.Snapshot) *api.Snapshot {
+	old.Status = api.SnapshotStatus{}
+	return old
+}
+
+func sampleDormantDatabase() *api.DormantDatabase {
+	return &api.DormantDatabase{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindDormantDatabase,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Status: api.DormantDatabaseStatus{
+			Phase: api.DormantDatabasePhasePaused,
+		},
+	}
+}
+
+func editDormantDatabaseWipeOutTime(old *api.DormantDatabase) *api.DormantDatabase {
+	now := metaV1.Now()
+	old.Status.WipeOutTime = &now
+	return old
+}
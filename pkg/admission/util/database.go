@@ -0,0 +1,325 @@
+package util
+
+import (
+	"fmt"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GetDatabase reads the KubeDB database of the given kind from kubernetes.
+func GetDatabase(extClient cs.KubedbV1alpha1Interface, kind, namespace, name string) (runtime.Object, error) {
+	switch kind {
+	case api.ResourceKindElasticsearch:
+		return extClient.Elasticsearches(namespace).Get(name, metav1.GetOptions{})
+	case api.ResourceKindPostgres:
+		return extClient.Postgreses(namespace).Get(name, metav1.GetOptions{})
+	case api.ResourceKindMongoDB:
+		return extClient.MongoDBs(namespace).Get(name, metav1.GetOptions{})
+	case api.ResourceKindMySQL:
+		return extClient.MySQLs(namespace).Get(name, metav1.GetOptions{})
+	case api.ResourceKindRedis:
+		return extClient.Redises(namespace).Get(name, metav1.GetOptions{})
+	case api.ResourceKindMemcached:
+		return extClient.Memcacheds(namespace).Get(name, metav1.GetOptions{})
+	}
+	return nil, fmt.Errorf(`unknown database kind "%v"`, kind)
+}
+
+// GetDatabaseSecret returns the auth secret used by a KubeDB database. Redis and Memcached have none.
+func GetDatabaseSecret(db runtime.Object) string {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		if obj.Spec.DatabaseSecret != nil {
+			return obj.Spec.DatabaseSecret.SecretName
+		}
+	case *api.Postgres:
+		if obj.Spec.DatabaseSecret != nil {
+			return obj.Spec.DatabaseSecret.SecretName
+		}
+	case *api.MongoDB:
+		if obj.Spec.DatabaseSecret != nil {
+			return obj.Spec.DatabaseSecret.SecretName
+		}
+	case *api.MySQL:
+		if obj.Spec.DatabaseSecret != nil {
+			return obj.Spec.DatabaseSecret.SecretName
+		}
+	}
+	return ""
+}
+
+// GetBackupSchedule returns the backup schedule of a KubeDB database. Redis and Memcached have none.
+func GetBackupSchedule(db runtime.Object) *api.BackupScheduleSpec {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.BackupSchedule
+	case *api.Postgres:
+		return obj.Spec.BackupSchedule
+	case *api.MongoDB:
+		return obj.Spec.BackupSchedule
+	case *api.MySQL:
+		return obj.Spec.BackupSchedule
+	}
+	return nil
+}
+
+// GetResources returns the compute resources of the database container of a KubeDB database, or nil if db is nil.
+func GetResources(db runtime.Object) *core.ResourceRequirements {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return &obj.Spec.Resources
+	case *api.Postgres:
+		return &obj.Spec.Resources
+	case *api.MongoDB:
+		return &obj.Spec.Resources
+	case *api.MySQL:
+		return &obj.Spec.Resources
+	case *api.Redis:
+		return &obj.Spec.Resources
+	case *api.Memcached:
+		return &obj.Spec.Resources
+	}
+	return nil
+}
+
+// GetStorage returns the claim spec of the volumes of a KubeDB database. Memcached has none.
+func GetStorage(db runtime.Object) *core.PersistentVolumeClaimSpec {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.Storage
+	case *api.Postgres:
+		return obj.Spec.Storage
+	case *api.MongoDB:
+		return obj.Spec.Storage
+	case *api.MySQL:
+		return obj.Spec.Storage
+	case *api.Redis:
+		return obj.Spec.Storage
+	}
+	return nil
+}
+
+// GetImagePullSecrets returns the image pull secrets of the pods of a KubeDB database.
+func GetImagePullSecrets(db runtime.Object) []core.LocalObjectReference {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.ImagePullSecrets
+	case *api.Postgres:
+		return obj.Spec.ImagePullSecrets
+	case *api.MongoDB:
+		return obj.Spec.ImagePullSecrets
+	case *api.MySQL:
+		return obj.Spec.ImagePullSecrets
+	case *api.Redis:
+		return obj.Spec.ImagePullSecrets
+	case *api.Memcached:
+		return obj.Spec.ImagePullSecrets
+	}
+	return nil
+}
+
+// GetNodeSelector returns the node selector of the pods of a KubeDB database.
+func GetNodeSelector(db runtime.Object) map[string]string {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.NodeSelector
+	case *api.Postgres:
+		return obj.Spec.NodeSelector
+	case *api.MongoDB:
+		return obj.Spec.NodeSelector
+	case *api.MySQL:
+		return obj.Spec.NodeSelector
+	case *api.Redis:
+		return obj.Spec.NodeSelector
+	case *api.Memcached:
+		return obj.Spec.NodeSelector
+	}
+	return nil
+}
+
+// GetAffinity returns the affinity of the pods of a KubeDB database.
+func GetAffinity(db runtime.Object) *core.Affinity {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.Affinity
+	case *api.Postgres:
+		return obj.Spec.Affinity
+	case *api.MongoDB:
+		return obj.Spec.Affinity
+	case *api.MySQL:
+		return obj.Spec.Affinity
+	case *api.Redis:
+		return obj.Spec.Affinity
+	case *api.Memcached:
+		return obj.Spec.Affinity
+	}
+	return nil
+}
+
+// GetTolerations returns the tolerations of the pods of a KubeDB database.
+func GetTolerations(db runtime.Object) []core.Toleration {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.Tolerations
+	case *api.Postgres:
+		return obj.Spec.Tolerations
+	case *api.MongoDB:
+		return obj.Spec.Tolerations
+	case *api.MySQL:
+		return obj.Spec.Tolerations
+	case *api.Redis:
+		return obj.Spec.Tolerations
+	case *api.Memcached:
+		return obj.Spec.Tolerations
+	}
+	return nil
+}
+
+// GetDatabasePhase returns the phase of a KubeDB database recorded by KubeDB operator.
+func GetDatabasePhase(db runtime.Object) api.DatabasePhase {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Status.Phase
+	case *api.Postgres:
+		return obj.Status.Phase
+	case *api.MongoDB:
+		return obj.Status.Phase
+	case *api.MySQL:
+		return obj.Status.Phase
+	case *api.Redis:
+		return obj.Status.Phase
+	case *api.Memcached:
+		return obj.Status.Phase
+	}
+	return ""
+}
+
+// GetInitSpec returns the init spec of a KubeDB database. Redis and Memcached have none.
+func GetInitSpec(db runtime.Object) *api.InitSpec {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.Init
+	case *api.Postgres:
+		return obj.Spec.Init
+	case *api.MongoDB:
+		return obj.Spec.Init
+	case *api.MySQL:
+		return obj.Spec.Init
+	}
+	return nil
+}
+
+// GetOriginInitSpec returns the init spec of the database a DormantDatabase was paused from.
+func GetOriginInitSpec(origin api.OriginSpec) *api.InitSpec {
+	switch {
+	case origin.Elasticsearch != nil:
+		return origin.Elasticsearch.Init
+	case origin.Postgres != nil:
+		return origin.Postgres.Init
+	case origin.MongoDB != nil:
+		return origin.MongoDB.Init
+	case origin.MySQL != nil:
+		return origin.MySQL.Init
+	}
+	return nil
+}
+
+// GetOriginKind returns the kind of the database a DormantDatabase was paused from.
+func GetOriginKind(origin api.OriginSpec) string {
+	switch {
+	case origin.Elasticsearch != nil:
+		return api.ResourceKindElasticsearch
+	case origin.Postgres != nil:
+		return api.ResourceKindPostgres
+	case origin.MongoDB != nil:
+		return api.ResourceKindMongoDB
+	case origin.MySQL != nil:
+		return api.ResourceKindMySQL
+	case origin.Redis != nil:
+		return api.ResourceKindRedis
+	case origin.Memcached != nil:
+		return api.ResourceKindMemcached
+	}
+	return ""
+}
+
+// IsDoNotPause returns true if a KubeDB database has spec.doNotPause set.
+func IsDoNotPause(db runtime.Object) bool {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return obj.Spec.DoNotPause
+	case *api.Postgres:
+		return obj.Spec.DoNotPause
+	case *api.MongoDB:
+		return obj.Spec.DoNotPause
+	case *api.MySQL:
+		return obj.Spec.DoNotPause
+	case *api.Redis:
+		return obj.Spec.DoNotPause
+	case *api.Memcached:
+		return obj.Spec.DoNotPause
+	}
+	return false
+}
+
+// ListDatabases lists the KubeDB databases of the given kind in a namespace.
+func ListDatabases(extClient cs.KubedbV1alpha1Interface, kind, namespace string) ([]runtime.Object, error) {
+	var out []runtime.Object
+	switch kind {
+	case api.ResourceKindElasticsearch:
+		list, err := extClient.Elasticsearches(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	case api.ResourceKindPostgres:
+		list, err := extClient.Postgreses(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	case api.ResourceKindMongoDB:
+		list, err := extClient.MongoDBs(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	case api.ResourceKindMySQL:
+		list, err := extClient.MySQLs(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	case api.ResourceKindRedis:
+		list, err := extClient.Redises(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	case api.ResourceKindMemcached:
+		list, err := extClient.Memcacheds(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf(`unknown database kind "%v"`, kind)
+	}
+	return out, nil
+}
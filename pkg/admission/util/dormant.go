@@ -0,0 +1,187 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	core_util "github.com/appscode/kutil/core/v1"
+	meta_util "github.com/appscode/kutil/meta"
+	jsonpatch "github.com/evanphx/json-patch"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SetDefaultsFromDormantDB takes the fields not given in a new database of kind from the DormantDatabase of the same
+// name, so that resuming a database only needs its name. DoNotPause is kept as given, and so are the monitoring and
+// backup schedule given to a MongoDB. A database initialized from a snapshot, or a Postgres initialized from WAL
+// archive, is marked as initialized. It reports whether db resumes a DormantDatabase, and returns an error if the
+// DormantDatabase is of a different kind or the spec of db mismatches its origin spec.
+func SetDefaultsFromDormantDB(extClient cs.KubedbV1alpha1Interface, kind string, db runtime.Object) (bool, error) {
+	o, err := meta.Accessor(db)
+	if err != nil {
+		return false, err
+	}
+	dormantDb, err := extClient.DormantDatabases(o.GetNamespace()).Get(o.GetName(), metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if dormantDb.Labels[api.LabelDatabaseKind] != kind {
+		return false, fmt.Errorf(`invalid %v: "%v". Exists DormantDatabase "%v" of different Kind`, kind, o.GetName(), dormantDb.Name)
+	}
+	spec, originSpec := getSpecs(db, dormantDb.Spec.Origin.Spec)
+	if spec == nil || reflect.ValueOf(originSpec).IsNil() {
+		return false, fmt.Errorf(`DormantDatabase "%v" has no %v spec in origin`, dormantDb.Name, kind)
+	}
+
+	// Take the fields not given in db from the origin spec, then put back the skipped fields, which are not checked.
+	given := reflect.ValueOf(spec).Elem().Interface()
+	if err := SetDefaultsFromOriginSpec(spec, originSpec); err != nil {
+		return false, err
+	}
+	skipped := []string{"DoNotPause"}
+	if kind == api.ResourceKindMongoDB {
+		skipped = append(skipped, "Monitor", "BackupSchedule")
+	}
+	specVal, givenVal, originVal := reflect.ValueOf(spec).Elem(), reflect.ValueOf(given), reflect.ValueOf(originSpec).Elem()
+	for _, field := range skipped {
+		if val := givenVal.FieldByName(field); val.Kind() != reflect.Ptr || !val.IsNil() {
+			specVal.FieldByName(field).Set(val)
+		}
+		originVal.FieldByName(field).Set(specVal.FieldByName(field))
+	}
+
+	if !meta_util.Equal(originSpec, spec) {
+		return false, fmt.Errorf("%v spec mismatches with OriginSpec in DormantDatabases. Diff: %v",
+			strings.ToLower(kind), meta_util.Diff(originSpec, spec))
+	}
+
+	if _, found := o.GetAnnotations()[api.AnnotationInitialized]; !found && initializesData(kind, GetInitSpec(db)) {
+		o.SetAnnotations(core_util.UpsertMap(o.GetAnnotations(), map[string]string{
+			api.AnnotationInitialized: "",
+		}))
+	}
+	return true, nil
+}
+
+// GetPausedDatabase returns the database of kind that db resumes, rebuilt from the origin of the DormantDatabase of
+// the same name, or nil if db doesn't resume one. A resumed database keeps the spec it was paused with, so the rules
+// for new databases are checked against the paused database as on an update, and don't block resuming a database
+// created before those rules.
+func GetPausedDatabase(extClient cs.KubedbV1alpha1Interface, kind string, db runtime.Object) (runtime.Object, error) {
+	o, err := meta.Accessor(db)
+	if err != nil {
+		return nil, err
+	}
+	dormantDb, err := extClient.DormantDatabases(o.GetNamespace()).Get(o.GetName(), metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if dormantDb.Labels[api.LabelDatabaseKind] != kind {
+		return nil, nil
+	}
+
+	origin := dormantDb.Spec.Origin
+	switch kind {
+	case api.ResourceKindElasticsearch:
+		if origin.Spec.Elasticsearch != nil {
+			return &api.Elasticsearch{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.Elasticsearch}, nil
+		}
+	case api.ResourceKindPostgres:
+		if origin.Spec.Postgres != nil {
+			return &api.Postgres{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.Postgres}, nil
+		}
+	case api.ResourceKindMongoDB:
+		if origin.Spec.MongoDB != nil {
+			return &api.MongoDB{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.MongoDB}, nil
+		}
+	case api.ResourceKindMySQL:
+		if origin.Spec.MySQL != nil {
+			return &api.MySQL{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.MySQL}, nil
+		}
+	case api.ResourceKindRedis:
+		if origin.Spec.Redis != nil {
+			return &api.Redis{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.Redis}, nil
+		}
+	case api.ResourceKindMemcached:
+		if origin.Spec.Memcached != nil {
+			return &api.Memcached{ObjectMeta: origin.ObjectMeta, Spec: *origin.Spec.Memcached}, nil
+		}
+	}
+	return nil, nil
+}
+
+// getSpecs returns pointers to the spec of a KubeDB database and to the spec of the same kind in origin.
+func getSpecs(db runtime.Object, origin api.OriginSpec) (interface{}, interface{}) {
+	switch obj := db.(type) {
+	case *api.Elasticsearch:
+		return &obj.Spec, origin.Elasticsearch
+	case *api.Postgres:
+		return &obj.Spec, origin.Postgres
+	case *api.MongoDB:
+		return &obj.Spec, origin.MongoDB
+	case *api.MySQL:
+		return &obj.Spec, origin.MySQL
+	case *api.Redis:
+		return &obj.Spec, origin.Redis
+	case *api.Memcached:
+		return &obj.Spec, origin.Memcached
+	}
+	return nil, nil
+}
+
+// initializesData returns true if init restores the data of a database of kind, from a snapshot or, for Postgres,
+// from WAL archive.
+func initializesData(kind string, init *api.InitSpec) bool {
+	return init != nil && (init.SnapshotSource != nil || (kind == api.ResourceKindPostgres && init.PostgresWAL != nil))
+}
+
+// SetDefaultsFromOriginSpec fills the fields left empty in spec with the values stored in the origin spec of a
+// DormantDatabase. Fields set by the user are kept as is, so that a mismatch can still be reported.
+// spec and origin must be pointers to the same type.
+func SetDefaultsFromOriginSpec(spec, origin interface{}) error {
+	originJson, err := json.Marshal(origin)
+	if err != nil {
+		return err
+	}
+	specJson, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	// Drop empty values, otherwise merge patch will override the values of origin spec with them.
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(specJson, &fields); err != nil {
+		return err
+	}
+	for key, val := range fields {
+		if val == nil || val == "" {
+			delete(fields, key)
+		}
+	}
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	merged, err := jsonpatch.MergePatch(originJson, patch)
+	if err != nil {
+		return err
+	}
+	out := reflect.New(reflect.TypeOf(spec).Elem())
+	if err := json.Unmarshal(merged, out.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(spec).Elem().Set(out.Elem())
+	return nil
+}
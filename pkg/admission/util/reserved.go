@@ -0,0 +1,138 @@
+package util
+
+import (
+	"fmt"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned/typed/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// reservedLabels and reservedAnnotations are the keys KubeDB operator and KubeDB admission hooks rely on to find
+// the database of an object, the running Snapshots of a database and the databases that are initialized.
+var (
+	reservedLabels = []string{
+		api.LabelDatabaseKind,
+		api.LabelDatabaseName,
+		api.LabelSnapshotStatus,
+	}
+	reservedAnnotations = []string{
+		api.AnnotationInitialized,
+	}
+)
+
+// ValidateReservedKeys checks that only KubeDB operator adds, changes or removes the reserved labels and
+// annotations of a KubeDB object of kind. oldObj is nil on create. The labels that identify the database of a new
+// object are allowed on create, as long as they match the object: the database itself, the database of a
+// Snapshot, or the database a DormantDatabase was paused from. So are the annotations the mutating hooks add to a
+// database that resumes a DormantDatabase.
+func ValidateReservedKeys(c *config.Config, extClient cs.KubedbV1alpha1Interface, user authenticationv1.UserInfo, kind string, obj, oldObj runtime.Object) error {
+	if c.IsOperator(user) {
+		return nil
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	var oldLabels, oldAnnotations map[string]string
+	if oldObj != nil {
+		old, err := meta.Accessor(oldObj)
+		if err != nil {
+			return err
+		}
+		oldLabels, oldAnnotations = old.GetLabels(), old.GetAnnotations()
+	} else {
+		identity := identityLabels(kind, obj)
+		oldLabels = map[string]string{}
+		for key, val := range identity {
+			if _, found := o.GetLabels()[key]; found {
+				oldLabels[key] = val
+			}
+		}
+		if _, found := o.GetAnnotations()[api.AnnotationInitialized]; found {
+			if oldAnnotations, err = resumeAnnotations(extClient, kind, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := checkReservedKeys("label", reservedLabels, o.GetLabels(), oldLabels); err != nil {
+		return err
+	}
+	return checkReservedKeys("annotation", reservedAnnotations, o.GetAnnotations(), oldAnnotations)
+}
+
+// identityLabels returns the values KubeDB labels a new object of kind with to identify its database.
+func identityLabels(kind string, obj runtime.Object) map[string]string {
+	switch obj := obj.(type) {
+	case *api.Snapshot:
+		// the database kind of a Snapshot is checked against the database it names
+		return map[string]string{
+			api.LabelDatabaseKind: obj.Labels[api.LabelDatabaseKind],
+			api.LabelDatabaseName: obj.Spec.DatabaseName,
+		}
+	case *api.DormantDatabase:
+		return map[string]string{
+			api.LabelDatabaseKind: GetOriginKind(obj.Spec.Origin.Spec),
+			api.LabelDatabaseName: obj.Name,
+		}
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	return map[string]string{
+		api.LabelDatabaseKind: kind,
+		api.LabelDatabaseName: o.GetName(),
+	}
+}
+
+// resumeAnnotations returns the reserved annotations the mutating hook of kind adds to a new database that resumes
+// a DormantDatabase. A database initialized from a snapshot, or a Postgres initialized from WAL archive, is marked
+// as initialized, so that it is not initialized again.
+func resumeAnnotations(extClient cs.KubedbV1alpha1Interface, kind string, obj runtime.Object) (map[string]string, error) {
+	if !initializesData(kind, GetInitSpec(obj)) {
+		return nil, nil
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	dormantDb, err := extClient.DormantDatabases(o.GetNamespace()).Get(o.GetName(), metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if dormantDb.Labels[api.LabelDatabaseKind] != kind {
+		return nil, nil
+	}
+	return map[string]string{api.AnnotationInitialized: ""}, nil
+}
+
+// checkReservedKeys returns an error naming the first reserved key that differs between values and oldValues.
+func checkReservedKeys(what string, keys []string, values, oldValues map[string]string) error {
+	for _, key := range keys {
+		val, found := values[key]
+		oldVal, oldFound := oldValues[key]
+		action := ""
+		switch {
+		case found && !oldFound:
+			action = "added"
+		case !found && oldFound:
+			action = "removed"
+		case val != oldVal:
+			action = "changed"
+		default:
+			continue
+		}
+		return fmt.Errorf(`%s "%s" is reserved for KubeDB operator and can't be %s`, what, key, action)
+	}
+	return nil
+}
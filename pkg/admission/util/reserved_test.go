@@ -0,0 +1,260 @@
+package util
+
+import (
+	"testing"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	extFake "github.com/kubedb/apimachinery/client/clientset/versioned/fake"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	operator = authenticationV1.UserInfo{Username: config.New().OperatorServiceAccount}
+	user     = authenticationV1.UserInfo{Username: "alice"}
+)
+
+func TestValidateReservedKeys(t *testing.T) {
+	for _, c := range reservedCases {
+		t.Run(c.testName, func(t *testing.T) {
+			// a Postgres "foo" was paused
+			extClient := extFake.NewSimpleClientset(sampleDormantDatabase(api.ResourceKindPostgres))
+
+			err := ValidateReservedKeys(config.New(), extClient.KubedbV1alpha1(), c.user, c.kind, c.object, c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var reservedCases = []struct {
+	testName  string
+	user      authenticationV1.UserInfo
+	kind      string
+	object    runtime.Object
+	oldObject runtime.Object
+	result    bool
+}{
+	{"Create database with its kind label",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres}, nil),
+		nil,
+		true,
+	},
+	{"Create database with kind label of other kind",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(map[string]string{api.LabelDatabaseKind: api.ResourceKindMySQL}, nil),
+		nil,
+		false,
+	},
+	{"Create database with name label of other database",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(map[string]string{api.LabelDatabaseName: "bar"}, nil),
+		nil,
+		false,
+	},
+	{"Create database with initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""}),
+		nil,
+		false,
+	},
+	{"Operator creates database with initialized annotation",
+		operator,
+		api.ResourceKindPostgres,
+		samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""}),
+		nil,
+		true,
+	},
+	{"Resume database initialized from snapshot with initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		editInitSnapshot(samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""})),
+		nil,
+		true,
+	},
+	{"Resume database initialized from snapshot with other initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		editInitSnapshot(samplePostgres(nil, map[string]string{api.AnnotationInitialized: "false"})),
+		nil,
+		false,
+	},
+	{"Resume Postgres initialized from WAL archive with initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		editInitWAL(samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""})),
+		nil,
+		true,
+	},
+	{"Create database initialized from snapshot with initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		editName(editInitSnapshot(samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""})), "bar"),
+		nil,
+		false,
+	},
+	{"Resume database of other kind with initialized annotation",
+		user,
+		api.ResourceKindMySQL,
+		editInitSnapshot(samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""})),
+		nil,
+		false,
+	},
+	{"Edit other labels",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, "app": "web"}, nil),
+		samplePostgres(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres}, nil),
+		true,
+	},
+	{"Remove kind label",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(nil, nil),
+		samplePostgres(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres}, nil),
+		false,
+	},
+	{"Remove initialized annotation",
+		user,
+		api.ResourceKindPostgres,
+		samplePostgres(nil, nil),
+		samplePostgres(nil, map[string]string{api.AnnotationInitialized: ""}),
+		false,
+	},
+	{"Create Snapshot of its database",
+		user,
+		api.ResourceKindSnapshot,
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelDatabaseName: "foo"}),
+		nil,
+		true,
+	},
+	{"Create Snapshot with status label",
+		user,
+		api.ResourceKindSnapshot,
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelSnapshotStatus: string(api.SnapshotPhaseSucceeded)}),
+		nil,
+		false,
+	},
+	{"Change Snapshot status label",
+		user,
+		api.ResourceKindSnapshot,
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelSnapshotStatus: string(api.SnapshotPhaseFailed)}),
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelSnapshotStatus: string(api.SnapshotPhaseRunning)}),
+		false,
+	},
+	{"Operator changes Snapshot status label",
+		operator,
+		api.ResourceKindSnapshot,
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelSnapshotStatus: string(api.SnapshotPhaseSucceeded)}),
+		sampleSnapshot(map[string]string{api.LabelDatabaseKind: api.ResourceKindPostgres, api.LabelSnapshotStatus: string(api.SnapshotPhaseRunning)}),
+		true,
+	},
+	{"Create DormantDatabase with kind label of its origin",
+		user,
+		api.ResourceKindDormantDatabase,
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		nil,
+		true,
+	},
+	{"Create DormantDatabase with kind label of other kind",
+		user,
+		api.ResourceKindDormantDatabase,
+		editLabels(sampleDormantDatabase(api.ResourceKindPostgres), map[string]string{api.LabelDatabaseKind: api.ResourceKindMySQL}),
+		nil,
+		false,
+	},
+	{"Change DormantDatabase kind label",
+		user,
+		api.ResourceKindDormantDatabase,
+		editLabels(sampleDormantDatabase(api.ResourceKindPostgres), map[string]string{api.LabelDatabaseKind: api.ResourceKindMySQL}),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		false,
+	},
+	{"Operator changes DormantDatabase kind label",
+		operator,
+		api.ResourceKindDormantDatabase,
+		editLabels(sampleDormantDatabase(api.ResourceKindPostgres), map[string]string{api.LabelDatabaseKind: api.ResourceKindMySQL}),
+		sampleDormantDatabase(api.ResourceKindPostgres),
+		true,
+	},
+}
+
+func samplePostgres(labels, annotations map[string]string) *api.Postgres {
+	return &api.Postgres{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+}
+
+func sampleSnapshot(labels map[string]string) *api.Snapshot {
+	return &api.Snapshot{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo-snapshot",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: api.SnapshotSpec{
+			DatabaseName: "foo",
+		},
+	}
+}
+
+func editName(old *api.Postgres, name string) *api.Postgres {
+	old.Name = name
+	return old
+}
+
+func editInitSnapshot(old *api.Postgres) *api.Postgres {
+	old.Spec.Init = &api.InitSpec{
+		SnapshotSource: &api.SnapshotSourceSpec{
+			Name: "foo-snapshot",
+		},
+	}
+	return old
+}
+
+func editInitWAL(old *api.Postgres) *api.Postgres {
+	old.Spec.Init = &api.InitSpec{
+		PostgresWAL: &api.PostgresWALSourceSpec{
+			BackupName: "foo-backup",
+		},
+	}
+	return old
+}
+
+// sampleDormantDatabase returns the DormantDatabase of a paused database "foo" of kind Postgres, labeled with kind.
+func sampleDormantDatabase(kind string) *api.DormantDatabase {
+	return &api.DormantDatabase{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: kind,
+			},
+		},
+		Spec: api.DormantDatabaseSpec{
+			Origin: api.Origin{
+				Spec: api.OriginSpec{
+					Postgres: &api.PostgresSpec{},
+				},
+			},
+		},
+	}
+}
+
+func editLabels(old *api.DormantDatabase, labels map[string]string) *api.DormantDatabase {
+	old.Labels = labels
+	return old
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/appscode/go/log"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	admission "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppendMessage adds an informational message to the result of an admission response.
+// AdmissionResponse has no field for warnings, so the messages are reported back through Result.
+func AppendMessage(status *admission.AdmissionResponse, msg string) {
+	if msg == "" {
+		return
+	}
+	if status.Result == nil {
+		status.Result = &metav1.Status{
+			Status: metav1.StatusSuccess,
+		}
+	}
+	if status.Result.Message == "" {
+		status.Result.Message = msg
+	} else {
+		status.Result.Message = strings.Join([]string{status.Result.Message, msg}, "; ")
+	}
+}
+
+// ApplyPolicy returns err when policy is "deny". Otherwise the request is allowed and err is logged as a
+// warning. kube-apiserver 1.9 drops the result of an allowed admission response, so the warning is also added to
+// it only for clients of later versions.
+func ApplyPolicy(req *admission.AdmissionRequest, status *admission.AdmissionResponse, policy string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if policy == config.PolicyDeny {
+		return err
+	}
+	log.Warningf(`%s of %s "%s/%s" by %s is allowed with warning: %v`, strings.ToLower(string(req.Operation)),
+		strings.ToLower(req.Kind.Kind), req.Namespace, req.Name, req.UserInfo.Username, err)
+	AppendMessage(status, "warning: "+err.Error())
+	return nil
+}
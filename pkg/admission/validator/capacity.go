@@ -0,0 +1,140 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	meta_util "github.com/appscode/kutil/meta"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CheckCapacity checks that the Nodes the database pods can be scheduled on have enough allocatable resources
+// to run all of them at once, ignoring the pods that are already running there. Pods of a database share a node
+// unless a required pod anti-affinity spreads them by hostname. On update, ie: when the database before the update
+// is given as oldObject with oldReplicas pods, the check is skipped when neither the number of pods nor their
+// resources and scheduling constraints are changed.
+func CheckCapacity(client kubernetes.Interface, replicas, oldReplicas int32, resources core.ResourceRequirements, nodeSelector map[string]string, affinity *core.Affinity, tolerations []core.Toleration, oldObject runtime.Object) error {
+	if replicas == oldReplicas &&
+		schedulingUnchanged(nodeSelector, affinity, tolerations, oldObject) &&
+		meta_util.Equal(*util.GetResources(oldObject), resources) {
+		return nil
+	}
+	requests := core.ResourceList{}
+	for name, quantity := range resources.Limits {
+		requests[name] = quantity
+	}
+	for name, quantity := range resources.Requests {
+		requests[name] = quantity
+	}
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			delete(requests, name)
+		}
+	}
+	if replicas < 1 || len(requests) == 0 {
+		return nil
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	spread := hasHostnameAntiAffinity(affinity)
+	eligible, capacity := 0, int64(0)
+	var missing []string
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		// nodes that can't run the pods at all are reported by CheckSchedulable
+		if nodeFitsScheduling(node, nodeSelector, affinity, tolerations) != "" {
+			continue
+		}
+		eligible++
+
+		fits := int64(-1)
+		for _, name := range resourceNames(requests) {
+			request := requests[name]
+			allocatable, found := node.Status.Allocatable[name]
+			if !found {
+				allocatable = node.Status.Capacity[name]
+			}
+			n := allocatable.MilliValue() / request.MilliValue()
+			if fits < 0 || n < fits {
+				fits = n
+			}
+		}
+		if spread && fits > 1 {
+			fits = 1
+		}
+		capacity += fits
+		if capacity >= int64(replicas) {
+			return nil
+		}
+		if fits == 0 && len(missing) == 0 {
+			missing = insufficientResources(node, requests)
+		}
+	}
+	if eligible == 0 {
+		return nil
+	}
+
+	if capacity == 0 {
+		return fmt.Errorf("none of the %d eligible nodes has enough allocatable %s to run a database pod requesting %s",
+			eligible, strings.Join(missing, ", "), formatResourceList(requests))
+	}
+	return fmt.Errorf("%d eligible nodes can run at most %d database pods requesting %s, but %d replicas are requested",
+		eligible, capacity, formatResourceList(requests), replicas)
+}
+
+// PodCount returns the total number of pods for the given replicas fields. Replicas that are not set count as 1.
+func PodCount(replicas ...*int32) int32 {
+	var count int32
+	for _, r := range replicas {
+		if r == nil {
+			count++
+		} else {
+			count += *r
+		}
+	}
+	return count
+}
+
+func hasHostnameAntiAffinity(affinity *core.Affinity) bool {
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey == "kubernetes.io/hostname" {
+			return true
+		}
+	}
+	return false
+}
+
+func insufficientResources(node *core.Node, requests core.ResourceList) []string {
+	var names []string
+	for _, name := range resourceNames(requests) {
+		request := requests[name]
+		allocatable, found := node.Status.Allocatable[name]
+		if !found {
+			allocatable = node.Status.Capacity[name]
+		}
+		if allocatable.Cmp(request) < 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names
+}
+
+func formatResourceList(list core.ResourceList) string {
+	var items []string
+	for _, name := range resourceNames(list) {
+		quantity := list[name]
+		items = append(items, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,166 @@
+package validator
+
+import (
+	"testing"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckCapacity(t *testing.T) {
+	for _, c := range capacityCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(c.nodes...)
+			err := CheckCapacity(client, c.replicas, c.oldReplicas, c.resources, c.nodeSelector, c.affinity, nil, c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var capacityCases = []struct {
+	testName     string
+	nodes        []runtime.Object
+	replicas     int32
+	resources    core.ResourceRequirements
+	nodeSelector map[string]string
+	affinity     *core.Affinity
+	oldReplicas  int32
+	oldObject    runtime.Object
+	result       bool
+}{
+	{"No requests",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "cpu=1,memory=1Gi")},
+		3,
+		core.ResourceRequirements{},
+		nil,
+		nil,
+		0,
+		nil,
+		true,
+	},
+	{"Pods share a node",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "cpu=4,memory=16Gi")},
+		3,
+		resources("cpu=1,memory=4Gi", ""),
+		nil,
+		nil,
+		0,
+		nil,
+		true,
+	},
+	{"Pods spread over nodes",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=20Gi"), nodeWithAllocatable("b", nil, "memory=20Gi")},
+		3,
+		resources("memory=8Gi", ""),
+		nil,
+		nil,
+		0,
+		nil,
+		true,
+	},
+	{"Pod larger than any node",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=8Gi"), nodeWithAllocatable("b", nil, "memory=12Gi")},
+		1,
+		resources("memory=16Gi", ""),
+		nil,
+		nil,
+		0,
+		nil,
+		false,
+	},
+	{"Requests defaulted from limits",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=8Gi")},
+		1,
+		resources("", "memory=16Gi"),
+		nil,
+		nil,
+		0,
+		nil,
+		false,
+	},
+	{"Not enough nodes",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=20Gi")},
+		3,
+		resources("memory=8Gi", ""),
+		nil,
+		nil,
+		0,
+		nil,
+		false,
+	},
+	{"Large node excluded by NodeSelector",
+		[]runtime.Object{nodeWithAllocatable("a", map[string]string{"pool": "db"}, "memory=8Gi"), nodeWithAllocatable("b", nil, "memory=64Gi")},
+		1,
+		resources("memory=16Gi", ""),
+		map[string]string{"pool": "db"},
+		nil,
+		0,
+		nil,
+		false,
+	},
+	{"Pods spread by hostname anti-affinity",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=64Gi"), nodeWithAllocatable("b", nil, "memory=64Gi")},
+		3,
+		resources("memory=1Gi", ""),
+		nil,
+		&core.Affinity{
+			PodAntiAffinity: &core.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []core.PodAffinityTerm{
+					{
+						LabelSelector: &metaV1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+		0,
+		nil,
+		false,
+	},
+	{"No eligible nodes",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=64Gi")},
+		1,
+		resources("memory=1Gi", ""),
+		map[string]string{"pool": "db"},
+		nil,
+		0,
+		nil,
+		true,
+	},
+	{"Unchanged pods on update",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=20Gi")},
+		3,
+		resources("memory=8Gi", ""),
+		nil,
+		nil,
+		3,
+		editResources(samplePostgres(), resources("memory=8Gi", "")),
+		true,
+	},
+	{"Scaled up on update",
+		[]runtime.Object{nodeWithAllocatable("a", nil, "memory=20Gi")},
+		3,
+		resources("memory=8Gi", ""),
+		nil,
+		nil,
+		2,
+		editResources(samplePostgres(), resources("memory=8Gi", "")),
+		false,
+	},
+}
+
+func nodeWithAllocatable(name string, labels map[string]string, allocatable string) *core.Node {
+	node := sampleNode(name, labels)
+	node.Status.Allocatable = resourceList(allocatable)
+	return node
+}
+
+func editResources(old *api.Postgres, resources core.ResourceRequirements) *api.Postgres {
+	old.Spec.Resources = resources
+	return old
+}
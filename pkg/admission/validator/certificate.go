@@ -0,0 +1,365 @@
+package validator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// keys of certificate secrets in Java KeyStore format, as created by KubeDB operator for Search Guard
+	CertificateKeyRootJKS    = "root.jks"
+	CertificateKeyNodeJKS    = "node.jks"
+	CertificateKeySgAdminJKS = "sgadmin.jks"
+	CertificateKeyPassword   = "key_pass"
+
+	// key of the CA certificate of certificate secrets in PEM format. The certificate and the
+	// private key are stored in tls.crt and tls.key, like in kubernetes.io/tls secrets.
+	CertificateKeyCA = "ca.crt"
+)
+
+// Certificates are the certificates found in a certificate secret.
+type Certificates struct {
+	// Roots are the trusted CA certificates.
+	Roots []*x509.Certificate
+	// Chains are the certificate chains of the keys in the secret, leaf certificate first.
+	Chains map[string][]*x509.Certificate
+}
+
+// NotAfter returns the time the first certificate expires at.
+func (c *Certificates) NotAfter() time.Time {
+	var notAfter time.Time
+	visit := func(cert *x509.Certificate) {
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	for _, cert := range c.Roots {
+		visit(cert)
+	}
+	for _, chain := range c.Chains {
+		for _, cert := range chain {
+			visit(cert)
+		}
+	}
+	return notAfter
+}
+
+// ParseCertificateSecret reads the certificates of an Elasticsearch certificate secret. The secret either holds
+// Java KeyStores (root.jks, node.jks, key_pass and optionally sgadmin.jks), or PEM encoded ca.crt, tls.crt and tls.key.
+func ParseCertificateSecret(secret *core.Secret) (*Certificates, error) {
+	if _, found := secret.Data[CertificateKeyRootJKS]; found {
+		return parseKeyStoreSecret(secret)
+	}
+	if _, found := secret.Data[CertificateKeyCA]; found {
+		return parsePEMSecret(secret)
+	}
+	return nil, fmt.Errorf(`secret "%s" must have either keys %s, %s, %s or keys %s, %s, %s`, secret.Name,
+		CertificateKeyRootJKS, CertificateKeyNodeJKS, CertificateKeyPassword, CertificateKeyCA, core.TLSCertKey, core.TLSPrivateKeyKey)
+}
+
+// ValidateCertificateSecret checks that the certificates of an Elasticsearch certificate secret are valid at now,
+// and that every certificate chain leads to one of the trusted CA certificates.
+func ValidateCertificateSecret(secret *core.Secret, now time.Time) error {
+	certs, err := ParseCertificateSecret(secret)
+	if err != nil {
+		return err
+	}
+	if len(certs.Roots) == 0 {
+		return fmt.Errorf(`secret "%s" has no CA certificate`, secret.Name)
+	}
+
+	roots := x509.NewCertPool()
+	for _, cert := range certs.Roots {
+		if err := checkValidity(cert, now); err != nil {
+			return fmt.Errorf(`CA certificate in secret "%s" %v`, secret.Name, err)
+		}
+		roots.AddCert(cert)
+	}
+	for key, chain := range certs.Chains {
+		if len(chain) == 0 {
+			return fmt.Errorf(`key "%s" of secret "%s" has no certificate`, key, secret.Name)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range chain {
+			if err := checkValidity(cert, now); err != nil {
+				return fmt.Errorf(`certificate "%s" in key "%s" of secret "%s" %v`, cert.Subject.CommonName, key, secret.Name, err)
+			}
+			if cert != chain[0] {
+				intermediates.AddCert(cert)
+			}
+		}
+		_, err := chain[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf(`certificate "%s" in key "%s" of secret "%s" is not signed by the CA: %v`, chain[0].Subject.CommonName, key, secret.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkValidity(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("is not valid before %v", cert.NotBefore.UTC())
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("expired at %v", cert.NotAfter.UTC())
+	}
+	return nil
+}
+
+func parsePEMSecret(secret *core.Secret) (*Certificates, error) {
+	for _, key := range []string{CertificateKeyCA, core.TLSCertKey, core.TLSPrivateKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, fmt.Errorf(`secret "%s" is missing key %s`, secret.Name, key)
+		}
+	}
+
+	roots, err := parsePEMCertificates(secret.Data[CertificateKeyCA])
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse key %s of secret "%s": %v`, CertificateKeyCA, secret.Name, err)
+	}
+	chain, err := parsePEMCertificates(secret.Data[core.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse key %s of secret "%s": %v`, core.TLSCertKey, secret.Name, err)
+	}
+	if _, err := tls.X509KeyPair(secret.Data[core.TLSCertKey], secret.Data[core.TLSPrivateKeyKey]); err != nil {
+		return nil, fmt.Errorf(`key %s of secret "%s" doesn't match %s: %v`, core.TLSPrivateKeyKey, secret.Name, core.TLSCertKey, err)
+	}
+	return &Certificates{
+		Roots:  roots,
+		Chains: map[string][]*x509.Certificate{core.TLSCertKey: chain},
+	}, nil
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+func parseKeyStoreSecret(secret *core.Secret) (*Certificates, error) {
+	for _, key := range []string{CertificateKeyRootJKS, CertificateKeyNodeJKS, CertificateKeyPassword} {
+		if len(secret.Data[key]) == 0 {
+			return nil, fmt.Errorf(`secret "%s" is missing key %s`, secret.Name, key)
+		}
+	}
+	password := string(secret.Data[CertificateKeyPassword])
+
+	truststore, err := parseKeyStore(secret.Data[CertificateKeyRootJKS], password)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse key %s of secret "%s": %v`, CertificateKeyRootJKS, secret.Name, err)
+	}
+	certs := &Certificates{
+		Roots:  truststore.trusted,
+		Chains: map[string][]*x509.Certificate{},
+	}
+	for _, key := range []string{CertificateKeyNodeJKS, CertificateKeySgAdminJKS} {
+		if _, found := secret.Data[key]; !found {
+			continue
+		}
+		keystore, err := parseKeyStore(secret.Data[key], password)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse key %s of secret "%s": %v`, key, secret.Name, err)
+		}
+		if len(keystore.chains) == 0 {
+			return nil, fmt.Errorf(`key %s of secret "%s" has no private key entry`, key, secret.Name)
+		}
+		certs.Chains[key] = keystore.chains[0]
+	}
+	return certs, nil
+}
+
+const (
+	jksMagic   = 0xfeedfeed
+	jceksMagic = 0xcececece
+
+	jksPrivateKeyEntry  = 1
+	jksTrustedCertEntry = 2
+
+	// minimum sizes of a keystore entry (tag, alias and creation date) and of a certificate (length)
+	jksMinEntrySize       = 4 + 2 + 8
+	jksMinCertificateSize = 4
+
+	// caps on the size of a keystore, the number of its entries and the length of a certificate chain, checked
+	// before anything is allocated for them. A Secret holds at most 1MiB, and the keystores created by KubeDB
+	// operator have a single entry.
+	jksMaxSize        = 1 << 20
+	jksMaxEntries     = 256
+	jksMaxChainLength = 16
+
+	// whitener used by keytool to compute the integrity digest of a keystore
+	jksWhitener = "Mighty Aphrodite"
+)
+
+type keyStore struct {
+	trusted []*x509.Certificate
+	chains  [][]*x509.Certificate
+}
+
+// parseKeyStore reads the certificates of a JKS or JCEKS keystore, after checking its integrity with password.
+// Private keys are not decrypted.
+func parseKeyStore(data []byte, password string) (*keyStore, error) {
+	if len(data) < sha1.Size {
+		return nil, errors.New("keystore is too short")
+	}
+	if len(data) > jksMaxSize {
+		return nil, fmt.Errorf("keystore of %d bytes is larger than %d bytes", len(data), jksMaxSize)
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+
+	h := sha1.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte(jksWhitener))
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return nil, errors.New("keystore was tampered with, or password was incorrect")
+	}
+
+	r := &jksReader{r: bytes.NewReader(body)}
+	magic := r.uint32()
+	if r.err == nil && magic != jksMagic && magic != jceksMagic {
+		return nil, errors.New("not a Java KeyStore")
+	}
+	version := r.uint32()
+	if r.err == nil && version != 1 && version != 2 {
+		return nil, fmt.Errorf("unsupported keystore version %d", version)
+	}
+
+	ks := &keyStore{}
+	count := r.count(jksMinEntrySize, jksMaxEntries)
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		tag := r.uint32()
+		r.utf()    // alias
+		r.uint64() // creation date
+		switch tag {
+		case jksPrivateKeyEntry:
+			r.bytes() // encrypted private key
+			var chain []*x509.Certificate
+			n := r.count(jksMinCertificateSize, jksMaxChainLength)
+			for j := uint32(0); j < n && r.err == nil; j++ {
+				chain = append(chain, r.certificate(version))
+			}
+			ks.chains = append(ks.chains, chain)
+		case jksTrustedCertEntry:
+			ks.trusted = append(ks.trusted, r.certificate(version))
+		default:
+			if r.err == nil {
+				return nil, fmt.Errorf("unsupported keystore entry type %d", tag)
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("malformed keystore: %v", r.err)
+	}
+	return ks, nil
+}
+
+// jksReader reads the big-endian fields of a keystore, remembering the first error. Lengths and counts are
+// checked against the bytes left, so that a corrupted keystore can't make it allocate more than its own size.
+type jksReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (r *jksReader) read(n uint32) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if int64(n) > int64(r.r.Len()) {
+		r.err = fmt.Errorf("field of %d bytes overruns the %d bytes left", n, r.r.Len())
+		return nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		r.err = err
+		return nil
+	}
+	return buf
+}
+
+func (r *jksReader) uint32() uint32 {
+	if buf := r.read(4); buf != nil {
+		return binary.BigEndian.Uint32(buf)
+	}
+	return 0
+}
+
+func (r *jksReader) uint64() uint64 {
+	if buf := r.read(8); buf != nil {
+		return binary.BigEndian.Uint64(buf)
+	}
+	return 0
+}
+
+// count reads the number of items that follow, each of at least size bytes, and at most max of them.
+func (r *jksReader) count(size int, max uint32) uint32 {
+	n := r.uint32()
+	if r.err == nil && n > max {
+		r.err = fmt.Errorf("%d items are more than the %d allowed", n, max)
+	} else if r.err == nil && int64(n)*int64(size) > int64(r.r.Len()) {
+		r.err = fmt.Errorf("%d items don't fit in the %d bytes left", n, r.r.Len())
+	}
+	return n
+}
+
+func (r *jksReader) utf() string {
+	if buf := r.read(2); buf != nil {
+		return string(r.read(uint32(binary.BigEndian.Uint16(buf))))
+	}
+	return ""
+}
+
+func (r *jksReader) bytes() []byte {
+	return r.read(r.uint32())
+}
+
+func (r *jksReader) certificate(version uint32) *x509.Certificate {
+	if version == 2 {
+		if certType := r.utf(); r.err == nil && certType != "X.509" {
+			r.err = fmt.Errorf("unsupported certificate type %s", certType)
+		}
+	}
+	der := r.bytes()
+	if r.err != nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		r.err = err
+	}
+	return cert
+}
@@ -0,0 +1,378 @@
+package validator
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateCertificateSecret(t *testing.T) {
+	for _, c := range certificateCases() {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateCertificateSecret(c.secret, time.Now())
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+func TestCertificates_NotAfter(t *testing.T) {
+	ca := newCertificate(nil, time.Now().Add(365*24*time.Hour))
+	node := newCertificate(ca, time.Now().Add(24*time.Hour))
+
+	certs, err := ParseCertificateSecret(pemCertificateSecret(ca, node, node))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notAfter := certs.NotAfter(); !notAfter.Equal(node.cert.NotAfter) {
+		t.Errorf("expected %v, but got %v", node.cert.NotAfter, notAfter)
+	}
+}
+
+func TestParseKeyStore(t *testing.T) {
+	for _, c := range keyStoreCases() {
+		t.Run(c.testName, func(t *testing.T) {
+			_, err := parseKeyStore(c.data, "secret")
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+// TestParseKeyStore_Truncated checks that a keystore cut short at any byte is rejected, whether or not its
+// digest matches what is left.
+func TestParseKeyStore_Truncated(t *testing.T) {
+	ca := newCertificate(nil, time.Now().Add(365*24*time.Hour))
+	node := newCertificate(ca, time.Now().Add(24*time.Hour))
+
+	for _, body := range [][]byte{
+		keyStoreBody(jksTrustedCertEntry, ca.cert),
+		keyStoreBody(jksPrivateKeyEntry, node.cert, ca.cert),
+	} {
+		data := signKeyStore("secret", body)
+		for n := 0; n < len(body); n++ {
+			if _, err := parseKeyStore(signKeyStore("secret", body[:n]), "secret"); err == nil {
+				t.Errorf("expected error for keystore body truncated to %d of %d bytes", n, len(body))
+			}
+		}
+		for n := 0; n < len(data); n++ {
+			if _, err := parseKeyStore(data[:n], "secret"); err == nil {
+				t.Errorf("expected error for keystore truncated to %d of %d bytes", n, len(data))
+			}
+		}
+	}
+}
+
+type keyStoreCase struct {
+	testName string
+	data     []byte
+	result   bool
+}
+
+// Offsets of the fields of the keystores written by keyStoreBody
+const (
+	offsetMagic      = 0
+	offsetVersion    = 4
+	offsetCount      = 8
+	offsetAliasLen   = 16
+	offsetCertLen    = 38 // certificate length of a trusted certificate entry
+	offsetKeyLen     = 31 // encrypted key length of a private key entry
+	offsetChainLen   = 39 // chain length of a private key entry
+	offsetChainCert0 = 43 // certificate type of the first certificate of the chain of a private key entry
+)
+
+func keyStoreCases() []keyStoreCase {
+	ca := newCertificate(nil, time.Now().Add(365*24*time.Hour))
+	node := newCertificate(ca, time.Now().Add(24*time.Hour))
+	trusted := keyStoreBody(jksTrustedCertEntry, ca.cert)
+	private := keyStoreBody(jksPrivateKeyEntry, node.cert, ca.cert)
+
+	return []keyStoreCase{
+		{"Trusted certificate entry",
+			signKeyStore("secret", trusted),
+			true,
+		},
+		{"Private key entry",
+			signKeyStore("secret", private),
+			true,
+		},
+		{"Shorter than the digest",
+			[]byte{0xfe, 0xed, 0xfe, 0xed},
+			false,
+		},
+		{"Wrong magic",
+			signKeyStore("secret", editUint32(trusted, offsetMagic, 0xcafebabe)),
+			false,
+		},
+		{"Unsupported version",
+			signKeyStore("secret", editUint32(trusted, offsetVersion, 3)),
+			false,
+		},
+		{"Truncated in a certificate",
+			signKeyStore("secret", trusted[:len(trusted)-10]),
+			false,
+		},
+		{"Truncated after the entry count",
+			signKeyStore("secret", trusted[:offsetCount+4]),
+			false,
+		},
+		{"Huge entry count",
+			signKeyStore("secret", editUint32(trusted, offsetCount, 0xffffffff)),
+			false,
+		},
+		{"Huge alias length",
+			signKeyStore("secret", editUint16(trusted, offsetAliasLen, 0xffff)),
+			false,
+		},
+		{"Huge certificate length",
+			signKeyStore("secret", editUint32(trusted, offsetCertLen, 0x7fffffff)),
+			false,
+		},
+		{"Huge encrypted key length",
+			signKeyStore("secret", editUint32(private, offsetKeyLen, 0xffffffff)),
+			false,
+		},
+		{"Huge certificate chain length",
+			signKeyStore("secret", editUint32(private, offsetChainLen, 0xffffffff)),
+			false,
+		},
+		{"Too many entries",
+			signKeyStore("secret", editUint32(append(trusted, make([]byte, jksMinEntrySize*(jksMaxEntries+1))...), offsetCount, jksMaxEntries+1)),
+			false,
+		},
+		{"Too long certificate chain",
+			signKeyStore("secret", editUint32(append(private, make([]byte, jksMinCertificateSize*(jksMaxChainLength+1))...), offsetChainLen, jksMaxChainLength+1)),
+			false,
+		},
+		{"Larger than a secret",
+			signKeyStore("secret", append(trusted, make([]byte, jksMaxSize)...)),
+			false,
+		},
+		{"Huge certificate type length",
+			signKeyStore("secret", editUint16(private, offsetChainCert0, 0xffff)),
+			false,
+		},
+	}
+}
+
+type certificateCase struct {
+	testName string
+	secret   *core.Secret
+	result   bool
+}
+
+func certificateCases() []certificateCase {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	ca := newCertificate(nil, year)
+	node := newCertificate(ca, year)
+	expired := newCertificate(ca, time.Now().Add(-time.Hour))
+	otherCA := newCertificate(nil, year)
+	stranger := newCertificate(otherCA, year)
+
+	return []certificateCase{
+		{"Valid PEM secret",
+			pemCertificateSecret(ca, node, node),
+			true,
+		},
+		{"PEM secret without private key",
+			editDeleteKey(pemCertificateSecret(ca, node, node), core.TLSPrivateKeyKey),
+			false,
+		},
+		{"Malformed PEM CA certificate",
+			editSecretKey(pemCertificateSecret(ca, node, node), CertificateKeyCA, []byte("-----BEGIN CERTIFICATE-----\nfoo\n-----END CERTIFICATE-----\n")),
+			false,
+		},
+		{"Private key doesn't match certificate",
+			pemCertificateSecret(ca, node, stranger),
+			false,
+		},
+		{"Expired PEM certificate",
+			pemCertificateSecret(ca, expired, expired),
+			false,
+		},
+		{"PEM certificate signed by another CA",
+			pemCertificateSecret(ca, stranger, stranger),
+			false,
+		},
+		{"Valid JKS secret",
+			jksCertificateSecret(ca, node, "secret"),
+			true,
+		},
+		{"JKS secret without node keystore",
+			editDeleteKey(jksCertificateSecret(ca, node, "secret"), CertificateKeyNodeJKS),
+			false,
+		},
+		{"JKS secret with wrong password",
+			editSecretKey(jksCertificateSecret(ca, node, "secret"), CertificateKeyPassword, []byte("wrong")),
+			false,
+		},
+		{"Truncated JKS keystore",
+			editSecretKey(jksCertificateSecret(ca, node, "secret"), CertificateKeyNodeJKS, []byte{0xfe, 0xed, 0xfe, 0xed}),
+			false,
+		},
+		{"Expired JKS certificate",
+			jksCertificateSecret(ca, expired, "secret"),
+			false,
+		},
+		{"Secret without certificates",
+			&core.Secret{ObjectMeta: metaV1.ObjectMeta{Name: "foo-cert"}},
+			false,
+		},
+	}
+}
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var serial int64
+
+// newCertificate creates a certificate signed by parent. A nil parent creates a self-signed CA certificate.
+func newCertificate(parent *testCertificate, notAfter time.Time) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    notAfter.Add(-2 * 365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.Subject.CommonName = "ca"
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return &testCertificate{cert: cert, key: key}
+}
+
+func pemCertificateSecret(ca, node, key *testCertificate) *core.Secret {
+	keyDER, err := x509.MarshalECPrivateKey(key.key)
+	if err != nil {
+		panic(err)
+	}
+	return &core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{Name: "foo-cert"},
+		Data: map[string][]byte{
+			CertificateKeyCA:      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}),
+			core.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: node.cert.Raw}),
+			core.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func jksCertificateSecret(ca, node *testCertificate, password string) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{Name: "foo-cert"},
+		Data: map[string][]byte{
+			CertificateKeyRootJKS:  keyStoreBytes(password, jksTrustedCertEntry, ca.cert),
+			CertificateKeyNodeJKS:  keyStoreBytes(password, jksPrivateKeyEntry, node.cert, ca.cert),
+			CertificateKeyPassword: []byte(password),
+		},
+	}
+}
+
+// keyStoreBytes writes a version 2 JKS keystore with a single entry. Private key entries get a dummy key,
+// since their keys are never decrypted.
+func keyStoreBytes(password string, tag uint32, certs ...*x509.Certificate) []byte {
+	return signKeyStore(password, keyStoreBody(tag, certs...))
+}
+
+// keyStoreBody writes the body of a keystore written by keyStoreBytes, without the integrity digest.
+func keyStoreBody(tag uint32, certs ...*x509.Certificate) []byte {
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) { binary.Write(buf, binary.BigEndian, v) }
+	writeUTF := func(s string) {
+		write(uint16(len(s)))
+		buf.WriteString(s)
+	}
+	writeCert := func(cert *x509.Certificate) {
+		writeUTF("X.509")
+		write(uint32(len(cert.Raw)))
+		buf.Write(cert.Raw)
+	}
+
+	write(uint32(jksMagic))
+	write(uint32(2))
+	write(uint32(1))
+	write(tag)
+	writeUTF("alias")
+	write(time.Now().UnixNano() / int64(time.Millisecond))
+	if tag == jksPrivateKeyEntry {
+		write(uint32(4))
+		buf.WriteString("key!")
+		write(uint32(len(certs)))
+		for _, cert := range certs {
+			writeCert(cert)
+		}
+	} else {
+		writeCert(certs[0])
+	}
+	return buf.Bytes()
+}
+
+// signKeyStore appends the integrity digest of a keystore to body.
+func signKeyStore(password string, body []byte) []byte {
+	h := sha1.New()
+	for _, c := range password {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte(jksWhitener))
+	h.Write(body)
+	return append(append([]byte{}, body...), h.Sum(nil)...)
+}
+
+// editUint32 overwrites the big-endian uint32 at offset of a copy of body.
+func editUint32(body []byte, offset int, v uint32) []byte {
+	body = append([]byte{}, body...)
+	binary.BigEndian.PutUint32(body[offset:], v)
+	return body
+}
+
+// editUint16 overwrites the big-endian uint16 at offset of a copy of body.
+func editUint16(body []byte, offset int, v uint16) []byte {
+	body = append([]byte{}, body...)
+	binary.BigEndian.PutUint16(body[offset:], v)
+	return body
+}
+
+func editSecretKey(old *core.Secret, key string, value []byte) *core.Secret {
+	old.Data[key] = value
+	return old
+}
+
+func editDeleteKey(old *core.Secret, key string) *core.Secret {
+	delete(old.Data, key)
+	return old
+}
@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	mona "github.com/appscode/kube-mon/api"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+// durations accepted by Prometheus are a number followed by a single unit, eg: 30s or 5m
+var prometheusDuration = regexp.MustCompile(`^([0-9]+)(y|w|d|h|m|s|ms)$`)
+
+// ValidateMonitorSpec validates the monitoring agent of a database. The exporter port must not collide with the
+// ports the database listens on. For the CoreOS Prometheus operator, the namespace of the ServiceMonitor must exist,
+// and its labels and scrape interval must be valid.
+func ValidateMonitorSpec(client kubernetes.Interface, monitorSpec *mona.AgentSpec, fldPath *field.Path, databasePorts ...int32) error {
+	specData, err := json.Marshal(monitorSpec)
+	if err != nil {
+		return err
+	}
+
+	if monitorSpec.Agent == "" {
+		return fmt.Errorf(`object 'Agent' is missing in '%v'`, string(specData))
+	}
+
+	if monitorSpec.Agent != mona.AgentPrometheusBuiltin &&
+		(monitorSpec.Agent != mona.AgentCoreOSPrometheus || monitorSpec.Prometheus == nil) {
+		return fmt.Errorf(`invalid 'Agent' in '%v'`, string(specData))
+	}
+	if monitorSpec.Prometheus == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	promPath := fldPath.Child("prometheus")
+	prom := monitorSpec.Prometheus
+
+	port := prom.Port
+	if port == 0 {
+		port = api.PrometheusExporterPortNumber
+	}
+	if msgs := validation.IsValidPortNum(int(port)); len(msgs) > 0 {
+		for _, msg := range msgs {
+			errs = append(errs, field.Invalid(promPath.Child("port"), prom.Port, msg))
+		}
+	} else {
+		for _, dbPort := range databasePorts {
+			if port == dbPort {
+				errs = append(errs, field.Invalid(promPath.Child("port"), prom.Port,
+					fmt.Sprintf("collides with port %d of the database, use the default exporter port %d instead", dbPort, api.PrometheusExporterPortNumber)))
+			}
+		}
+	}
+
+	if monitorSpec.Agent == mona.AgentCoreOSPrometheus {
+		if prom.Namespace == "" {
+			errs = append(errs, field.Required(promPath.Child("namespace"), "namespace of ServiceMonitor is required"))
+		} else if _, err := client.CoreV1().Namespaces().Get(prom.Namespace, metav1.GetOptions{}); kerr.IsNotFound(err) {
+			errs = append(errs, field.NotFound(promPath.Child("namespace"), prom.Namespace))
+		} else if err != nil {
+			return err
+		}
+		errs = append(errs, metav1validation.ValidateLabels(prom.Labels, promPath.Child("labels"))...)
+		if prom.Interval != "" {
+			if match := prometheusDuration.FindStringSubmatch(prom.Interval); match == nil || strings.Trim(match[1], "0") == "" {
+				errs = append(errs, field.Invalid(promPath.Child("interval"), prom.Interval, `must be a positive duration like "30s", "1m" or "1h"`))
+			}
+		}
+	}
+	return errs.ToAggregate()
+}
@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"testing"
+
+	mona "github.com/appscode/kube-mon/api"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateMonitorSpec(t *testing.T) {
+	client := fake.NewSimpleClientset(&core.Namespace{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: "monitoring",
+		},
+	})
+
+	for _, c := range monitorCases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateMonitorSpec(client, &c.spec, field.NewPath("spec").Child("monitor"), 5432)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var monitorCases = []struct {
+	testName string
+	spec     mona.AgentSpec
+	result   bool
+}{
+	{"Builtin agent",
+		mona.AgentSpec{Agent: mona.AgentPrometheusBuiltin},
+		true,
+	},
+	{"Missing agent",
+		mona.AgentSpec{},
+		false,
+	},
+	{"Unknown agent",
+		mona.AgentSpec{Agent: "statsd.io/builtin"},
+		false,
+	},
+	{"Builtin agent with custom port",
+		mona.AgentSpec{Agent: mona.AgentPrometheusBuiltin, Prometheus: &mona.PrometheusSpec{Port: 9187}},
+		true,
+	},
+	{"Builtin agent with invalid port",
+		mona.AgentSpec{Agent: mona.AgentPrometheusBuiltin, Prometheus: &mona.PrometheusSpec{Port: 70000}},
+		false,
+	},
+	{"Builtin agent with database port",
+		mona.AgentSpec{Agent: mona.AgentPrometheusBuiltin, Prometheus: &mona.PrometheusSpec{Port: 5432}},
+		false,
+	},
+	{"CoreOS agent",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("monitoring", "30s", map[string]string{"app": "kubedb"})},
+		true,
+	},
+	{"CoreOS agent without Prometheus",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus},
+		false,
+	},
+	{"CoreOS agent without namespace",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("", "30s", nil)},
+		false,
+	},
+	{"CoreOS agent with missing namespace",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("monitorign", "30s", nil)},
+		false,
+	},
+	{"CoreOS agent with invalid labels",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("monitoring", "30s", map[string]string{"app name": "kubedb"})},
+		false,
+	},
+	{"CoreOS agent with invalid interval",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("monitoring", "30 seconds", nil)},
+		false,
+	},
+	{"CoreOS agent with zero interval",
+		mona.AgentSpec{Agent: mona.AgentCoreOSPrometheus, Prometheus: coreOSPrometheus("monitoring", "0s", nil)},
+		false,
+	},
+}
+
+func coreOSPrometheus(namespace, interval string, labels map[string]string) *mona.PrometheusSpec {
+	return &mona.PrometheusSpec{
+		Namespace: namespace,
+		Interval:  interval,
+		Labels:    labels,
+	}
+}
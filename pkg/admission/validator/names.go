@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	mona "github.com/appscode/kube-mon/api"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Kinds of the objects KubeDB operator creates for a database
+const (
+	KindStatefulSet    = "StatefulSet"
+	KindDeployment     = "Deployment"
+	KindService        = "Service"
+	KindSecret         = "Secret"
+	KindConfigMap      = "ConfigMap"
+	KindJob            = "Job"
+	KindServiceMonitor = "ServiceMonitor"
+)
+
+// maxStatefulSetNameLength is the maximum length of a StatefulSet name. StatefulSet controller labels the pods
+// with controller-revision-hash, whose value is the StatefulSet name followed by a dash and a 10 character hash,
+// and label values can't be longer than 63 characters.
+const maxStatefulSetNameLength = validation.DNS1123LabelMaxLength - 11
+
+// scheduledSnapshotSuffix is a suffix of the same length as the timestamp suffix of the name of the Snapshots
+// taken by a backup schedule
+const scheduledSnapshotSuffix = "-20060102-150405"
+
+// DerivedName is the name of an object KubeDB operator creates for a database.
+type DerivedName struct {
+	Kind string
+	Name string
+}
+
+// MonitorNames returns the names derived from the monitor spec of a database: the ServiceMonitor created for
+// CoreOS Prometheus operator.
+func MonitorNames(monitor *mona.AgentSpec, serviceMonitorName string) []DerivedName {
+	if monitor == nil || monitor.Agent != mona.AgentCoreOSPrometheus {
+		return nil
+	}
+	return []DerivedName{{Kind: KindServiceMonitor, Name: serviceMonitorName}}
+}
+
+// ScheduledSnapshotNames returns the names derived from the Snapshots a backup schedule takes of database name.
+func ScheduledSnapshotNames(name string) []DerivedName {
+	snapshot := api.Snapshot{ObjectMeta: metav1.ObjectMeta{Name: name + scheduledSnapshotSuffix}}
+	return SnapshotNames(&snapshot)
+}
+
+// SnapshotNames returns the names derived from snapshot: the Job that takes it, and the secret that holds the
+// credentials of its storage.
+func SnapshotNames(snapshot *api.Snapshot) []DerivedName {
+	return []DerivedName{
+		{Kind: KindJob, Name: snapshot.OffshootName()},
+		{Kind: KindSecret, Name: snapshot.OSMSecretName()},
+	}
+}
+
+// SnapshotLockNames returns the names derived from the Snapshots taken of the database of kind with name: the
+// ConfigMap that locks the database while a Snapshot is taken of it.
+func SnapshotLockNames(kind, name string) []DerivedName {
+	return []DerivedName{{Kind: KindConfigMap, Name: SnapshotLockName(kind, name)}}
+}
+
+// SnapshotLockKey is the annotation of the lock ConfigMap of a database that records the Snapshot being taken of it.
+// Only KubeDB operator and kubedb-server may write it.
+const SnapshotLockKey = api.SnapshotKey + "/lock"
+
+// snapshotLockSuffix ends the name of every lock ConfigMap.
+const snapshotLockSuffix = "-snapshot-lock"
+
+// SnapshotLockName returns the name of the lock ConfigMap of the database of kind with name.
+func SnapshotLockName(kind, name string) string {
+	return fmt.Sprintf("%s-%s%s", name, strings.ToLower(kind), snapshotLockSuffix)
+}
+
+// IsSnapshotLockName returns true if name can be the name of a lock ConfigMap. ConfigMaps with other names are
+// never locks.
+func IsSnapshotLockName(name string) bool {
+	return strings.HasSuffix(name, snapshotLockSuffix)
+}
+
+// ValidateDerivedNames checks that each derived name is a valid name of its kind. Services need DNS-1035 labels.
+// StatefulSets and Jobs need DNS-1123 labels, since their names are used as label values and pod hostnames.
+func ValidateDerivedNames(names []DerivedName) error {
+	for _, n := range names {
+		var msgs []string
+		switch n.Kind {
+		case KindService:
+			msgs = validation.IsDNS1035Label(n.Name)
+		case KindStatefulSet:
+			msgs = validation.IsDNS1123Label(n.Name)
+			if len(n.Name) > maxStatefulSetNameLength {
+				msgs = append(msgs, validation.MaxLenError(maxStatefulSetNameLength))
+			}
+		case KindJob:
+			msgs = validation.IsDNS1123Label(n.Name)
+		default:
+			msgs = validation.IsDNS1123Subdomain(n.Name)
+		}
+		if len(msgs) > 0 {
+			return fmt.Errorf(`%s name "%s" derived from the database name is invalid: %s`, n.Kind, n.Name, strings.Join(msgs, ", "))
+		}
+	}
+	return nil
+}
+
+// ValidateNames checks that the names KubeDB operator derives from the name of the database of kind and name are
+// valid, and not taken in namespace by objects KubeDB operator didn't create for the database. On update, ie: when
+// the database before the update is given as oldObject, the check is skipped, since the name of a database can't be
+// changed, and the objects KubeDB operator created for it would collide.
+func ValidateNames(client kubernetes.Interface, kind, name, namespace string, names []DerivedName, oldObject runtime.Object) error {
+	if oldObject != nil {
+		return nil
+	}
+	if err := ValidateDerivedNames(names); err != nil {
+		return err
+	}
+	return CheckNameCollisions(client, kind, name, namespace, names)
+}
+
+// CheckNameCollisions checks that no Service, Secret, ConfigMap or StatefulSet with a derived name already exists in
+// namespace, unless KubeDB created it for the database of kind and name. Objects created by KubeDB are labeled with
+// the database kind, and with the database name unless they are auth secrets.
+func CheckNameCollisions(client kubernetes.Interface, kind, name, namespace string, names []DerivedName) error {
+	for _, n := range names {
+		labels, err := getLabels(client, n, namespace)
+		if kerr.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if labels[api.LabelDatabaseKind] == kind {
+			if dbName, found := labels[api.LabelDatabaseName]; !found || dbName == name {
+				continue
+			}
+		}
+		return fmt.Errorf(`%s "%s" already exists in namespace "%s" and is not managed by KubeDB for %s "%s"`, n.Kind, n.Name, namespace, kind, name)
+	}
+	return nil
+}
+
+// getLabels returns the labels of the object with a derived name. It returns a NotFound error for the kinds
+// whose collisions are not checked.
+func getLabels(client kubernetes.Interface, n DerivedName, namespace string) (map[string]string, error) {
+	switch n.Kind {
+	case KindService:
+		obj, err := client.CoreV1().Services(namespace).Get(n.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return obj.Labels, nil
+	case KindSecret:
+		obj, err := client.CoreV1().Secrets(namespace).Get(n.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return obj.Labels, nil
+	case KindConfigMap:
+		obj, err := client.CoreV1().ConfigMaps(namespace).Get(n.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return obj.Labels, nil
+	case KindStatefulSet:
+		obj, err := client.AppsV1().StatefulSets(namespace).Get(n.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return obj.Labels, nil
+	}
+	return nil, kerr.NewNotFound(schema.GroupResource{Resource: strings.ToLower(n.Kind)}, n.Name)
+}
@@ -0,0 +1,189 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateDerivedNames(t *testing.T) {
+	for _, c := range derivedNameCases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateDerivedNames(c.names)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	client := fake.NewSimpleClientset(&core.Service{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+		},
+	})
+	names := []DerivedName{{Kind: KindService, Name: "web"}}
+	if err := ValidateNames(client, api.ResourceKindPostgres, "web", "default", names, nil); err == nil {
+		t.Error("expected name collision on create")
+	}
+	if err := ValidateNames(client, api.ResourceKindPostgres, "web", "default", names, samplePostgres()); err != nil {
+		t.Errorf("expected names to be skipped on update, but got error: %v", err)
+	}
+}
+
+var derivedNameCases = []struct {
+	testName string
+	names    []DerivedName
+	result   bool
+}{
+	{"Valid names",
+		[]DerivedName{{Kind: KindStatefulSet, Name: "foo"}, {Kind: KindService, Name: "foo"}, {Kind: KindSecret, Name: "foo-auth"}},
+		true,
+	},
+	{"Service name with dots",
+		[]DerivedName{{Kind: KindService, Name: "foo.bar"}},
+		false,
+	},
+	{"Secret name with dots",
+		[]DerivedName{{Kind: KindSecret, Name: "foo.bar-auth"}},
+		true,
+	},
+	{"Service name starting with a digit",
+		[]DerivedName{{Kind: KindService, Name: "1foo"}},
+		false,
+	},
+	{"StatefulSet name of maximum length",
+		[]DerivedName{{Kind: KindStatefulSet, Name: strings.Repeat("a", maxStatefulSetNameLength)}},
+		true,
+	},
+	{"StatefulSet name too long for controller revision hash",
+		[]DerivedName{{Kind: KindStatefulSet, Name: strings.Repeat("a", maxStatefulSetNameLength+1)}},
+		false,
+	},
+	{"Scheduled Snapshot names too long",
+		ScheduledSnapshotNames(strings.Repeat("a", 50)),
+		false,
+	},
+	{"Scheduled Snapshot names",
+		ScheduledSnapshotNames("foo"),
+		true,
+	},
+}
+
+func TestCheckNameCollisions(t *testing.T) {
+	for _, c := range collisionCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(
+				&core.Service{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "web",
+						Namespace: "default",
+					},
+				},
+				&core.Secret{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "foo-auth",
+						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindPostgres,
+						},
+					},
+				},
+				&core.ConfigMap{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "foo-postgres-snapshot-lock",
+						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindPostgres,
+							api.LabelDatabaseName: "foo",
+						},
+					},
+				},
+				&apps.StatefulSet{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+						Labels: map[string]string{
+							api.LabelDatabaseKind: api.ResourceKindPostgres,
+							api.LabelDatabaseName: "foo",
+						},
+					},
+				},
+			)
+			err := CheckNameCollisions(client, c.kind, c.name, "default", c.names)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var collisionCases = []struct {
+	testName string
+	kind     string
+	name     string
+	names    []DerivedName
+	result   bool
+}{
+	{"No existing objects",
+		api.ResourceKindPostgres,
+		"bar",
+		[]DerivedName{{Kind: KindStatefulSet, Name: "bar"}, {Kind: KindService, Name: "bar"}},
+		true,
+	},
+	{"Service not managed by KubeDB",
+		api.ResourceKindPostgres,
+		"web",
+		[]DerivedName{{Kind: KindService, Name: "web"}},
+		false,
+	},
+	{"Auth secret of the same kind",
+		api.ResourceKindPostgres,
+		"foo",
+		[]DerivedName{{Kind: KindSecret, Name: "foo-auth"}},
+		true,
+	},
+	{"Auth secret of another kind",
+		api.ResourceKindMySQL,
+		"foo",
+		[]DerivedName{{Kind: KindSecret, Name: "foo-auth"}},
+		false,
+	},
+	{"StatefulSet of the same database",
+		api.ResourceKindPostgres,
+		"foo",
+		[]DerivedName{{Kind: KindStatefulSet, Name: "foo"}},
+		true,
+	},
+	{"StatefulSet of another database",
+		api.ResourceKindPostgres,
+		"bar",
+		[]DerivedName{{Kind: KindStatefulSet, Name: "foo"}},
+		false,
+	},
+	{"Snapshot lock of the same database",
+		api.ResourceKindPostgres,
+		"foo",
+		SnapshotLockNames(api.ResourceKindPostgres, "foo"),
+		true,
+	},
+	{"Snapshot lock taken by another database",
+		api.ResourceKindPostgres,
+		"foo-postgres",
+		[]DerivedName{{Kind: KindConfigMap, Name: "foo-postgres-snapshot-lock"}},
+		false,
+	},
+	{"Unchecked kind",
+		api.ResourceKindPostgres,
+		"web",
+		[]DerivedName{{Kind: KindServiceMonitor, Name: "web"}},
+		true,
+	},
+}
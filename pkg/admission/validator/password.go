@@ -0,0 +1,114 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Namespace annotations setting the password policy for the auth secrets of databases in the namespace
+	PasswordMinLengthKey        = api.GenericKey + "/password-min-length"
+	PasswordCharacterClassesKey = api.GenericKey + "/password-character-classes"
+
+	CharacterClassLower  = "lower"
+	CharacterClassUpper  = "upper"
+	CharacterClassDigit  = "digit"
+	CharacterClassSymbol = "symbol"
+)
+
+var characterClasses = map[string]func(rune) bool{
+	CharacterClassLower: unicode.IsLower,
+	CharacterClassUpper: unicode.IsUpper,
+	CharacterClassDigit: unicode.IsDigit,
+	CharacterClassSymbol: func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	},
+}
+
+// PasswordPolicy is the set of rules the passwords of database auth secrets in a namespace must follow.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters of a password.
+	MinLength int
+	// CharacterClasses are the classes a password must have at least one character of.
+	CharacterClasses []string
+}
+
+// GetPasswordPolicy reads the password policy from the annotations of namespace. It returns nil if the namespace
+// has no password policy.
+func GetPasswordPolicy(client kubernetes.Interface, namespace string) (*PasswordPolicy, error) {
+	ns, err := client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var policy PasswordPolicy
+	if v, found := ns.Annotations[PasswordMinLengthKey]; found {
+		if policy.MinLength, err = strconv.Atoi(v); err != nil || policy.MinLength < 0 {
+			return nil, fmt.Errorf(`annotation %s of namespace "%s" must be a non-negative integer, found "%s"`, PasswordMinLengthKey, namespace, v)
+		}
+	}
+	if v, found := ns.Annotations[PasswordCharacterClassesKey]; found {
+		for _, class := range strings.Split(v, ",") {
+			class = strings.TrimSpace(class)
+			if class == "" {
+				continue
+			}
+			if _, found := characterClasses[class]; !found {
+				return nil, fmt.Errorf(`annotation %s of namespace "%s" has unknown character class "%s", must be one of %s, %s, %s or %s`,
+					PasswordCharacterClassesKey, namespace, class, CharacterClassLower, CharacterClassUpper, CharacterClassDigit, CharacterClassSymbol)
+			}
+			policy.CharacterClasses = append(policy.CharacterClasses, class)
+		}
+	}
+	if policy.MinLength == 0 && len(policy.CharacterClasses) == 0 {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// Check returns an error describing the first rule password breaks.
+func (p *PasswordPolicy) Check(password string) error {
+	if n := len([]rune(password)); n < p.MinLength {
+		return fmt.Errorf("must have at least %d characters, found %d", p.MinLength, n)
+	}
+	for _, class := range p.CharacterClasses {
+		if strings.IndexFunc(password, characterClasses[class]) < 0 {
+			return fmt.Errorf("must have at least one %s character", class)
+		}
+	}
+	return nil
+}
+
+// CheckPasswordStrength checks the passwords in the auth secret of a database of the given kind against the
+// password policy of namespace. On update or resume, ie: when the database before the update or the paused database
+// is given as oldObject, the check is skipped, since the auth secret can't be changed once set.
+func CheckPasswordStrength(client kubernetes.Interface, kind string, secretRef *core.SecretVolumeSource, namespace string, oldObject runtime.Object) error {
+	if secretRef == nil || oldObject != nil {
+		return nil
+	}
+	policy, err := GetPasswordPolicy(client, namespace)
+	if err != nil || policy == nil {
+		return err
+	}
+	secret, err := client.CoreV1().Secrets(namespace).Get(secretRef.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for _, key := range databasePasswordKeys[kind] {
+		if err := policy.Check(string(secret.Data[key])); err != nil {
+			return fmt.Errorf(`password in key %s of secret "%s" %v`, key, secret.Name, err)
+		}
+	}
+	return nil
+}
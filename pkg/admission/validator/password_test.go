@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"testing"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckPasswordStrength(t *testing.T) {
+	for _, c := range passwordCases {
+		t.Run(c.testName, func(t *testing.T) {
+			objects := []runtime.Object{
+				sampleSecret("foo-auth", core.SecretTypeOpaque, map[string][]byte{"user": []byte("root"), "password": []byte(c.password)}),
+			}
+			if c.annotations != nil {
+				objects = append(objects, &core.Namespace{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:        "default",
+						Annotations: c.annotations,
+					},
+				})
+			}
+			client := fake.NewSimpleClientset(objects...)
+
+			err := CheckPasswordStrength(client, api.ResourceKindMySQL, &core.SecretVolumeSource{SecretName: "foo-auth"}, "default", c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var passwordCases = []struct {
+	testName    string
+	annotations map[string]string
+	password    string
+	oldObject   runtime.Object
+	result      bool
+}{
+	{"No password policy",
+		nil,
+		"a",
+		nil,
+		true,
+	},
+	{"Password long enough",
+		map[string]string{PasswordMinLengthKey: "8"},
+		"abcdefgh",
+		nil,
+		true,
+	},
+	{"Password too short",
+		map[string]string{PasswordMinLengthKey: "8"},
+		"abcdefg",
+		nil,
+		false,
+	},
+	{"Password has all character classes",
+		map[string]string{PasswordCharacterClassesKey: "lower, upper,digit,symbol"},
+		"aB3$",
+		nil,
+		true,
+	},
+	{"Password without symbol",
+		map[string]string{PasswordCharacterClassesKey: "lower,upper,digit,symbol"},
+		"aB3d",
+		nil,
+		false,
+	},
+	{"Invalid minimum length",
+		map[string]string{PasswordMinLengthKey: "eight"},
+		"abcdefgh",
+		nil,
+		false,
+	},
+	{"Unknown character class",
+		map[string]string{PasswordCharacterClassesKey: "emoji"},
+		"abcdefgh",
+		nil,
+		false,
+	},
+	{"Password too short on update",
+		map[string]string{PasswordMinLengthKey: "8"},
+		"abcdefg",
+		&api.MySQL{},
+		true,
+	},
+}
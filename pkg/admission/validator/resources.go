@@ -0,0 +1,265 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	meta_util "github.com/appscode/kutil/meta"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The checks below follow k8s.io/kubernetes v1.9.0 (925c127ec6b946659ad0fd596fa959be43f0cc05), the version pinned
+// in glide.yaml. That package is not vendored, since its validation works on the internal API types and pulls in most
+// of the tree, so the checks are copied from:
+//   - pkg/apis/core/validation/validation.go: ValidateResourceRequirements, validateContainerResourceName,
+//     ValidateResourceQuantityValue
+//   - pkg/apis/core/helper/helpers.go: IsExtendedResourceName, IsDefaultNamespaceResource, IsHugePageResourceName,
+//     IsOvercommitAllowed
+//   - plugin/pkg/admission/limitranger/admission.go: defaultContainerResourceRequirements, minConstraint,
+//     maxConstraint, limitRequestRatioConstraint
+// Keep them in sync with that version when k8s.io/kubernetes is bumped.
+
+var standardContainerResources = sets.NewString(
+	string(core.ResourceCPU),
+	string(core.ResourceMemory),
+	string(core.ResourceEphemeralStorage),
+	string(core.ResourceNvidiaGPU),
+)
+
+// ValidateResources validates the compute resources of a container, the way kube-apiserver validates
+// the resources of the containers in the pods created by the operator. Then it checks them against the
+// Container limits of the LimitRanges in namespace, after applying the defaults of those LimitRanges. On update, the
+// LimitRanges are not checked when the resources are not changed from oldResources, so that a LimitRange created
+// later doesn't block edits of other fields.
+func ValidateResources(client kubernetes.Interface, resources core.ResourceRequirements, oldResources *core.ResourceRequirements, namespace string, fldPath *field.Path) error {
+	if errs := validateResourceRequirements(resources, fldPath); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	if oldResources != nil && meta_util.Equal(*oldResources, resources) {
+		return nil
+	}
+
+	limitRanges, err := client.CoreV1().LimitRanges(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(limitRanges.Items) == 0 {
+		return nil
+	}
+
+	// resources that are not set are defaulted before limits are enforced, like the LimitRanger admission plugin does
+	effective := resources.DeepCopy()
+	if effective.Requests == nil {
+		effective.Requests = core.ResourceList{}
+	}
+	if effective.Limits == nil {
+		effective.Limits = core.ResourceList{}
+	}
+	for name, limit := range effective.Limits {
+		if _, found := effective.Requests[name]; !found {
+			effective.Requests[name] = limit
+		}
+	}
+	for _, limitRange := range limitRanges.Items {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != core.LimitTypeContainer {
+				continue
+			}
+			setDefaults(effective.Limits, item.Default)
+			setDefaults(effective.Requests, item.DefaultRequest)
+		}
+	}
+	if errs := validateResourceRequirements(*effective, fldPath); len(errs) > 0 {
+		return fmt.Errorf("defaults of LimitRanges in namespace %s are invalid: %v", namespace, errs.ToAggregate())
+	}
+
+	var errs field.ErrorList
+	for _, limitRange := range limitRanges.Items {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != core.LimitTypeContainer {
+				continue
+			}
+			errs = append(errs, validateLimitRangeItem(limitRange.Name, item, *effective, fldPath)...)
+		}
+	}
+	return errs.ToAggregate()
+}
+
+// setDefaults sets the resources of defaults missing in list, like mergeContainerResources of the LimitRanger.
+func setDefaults(list, defaults core.ResourceList) {
+	for name, value := range defaults {
+		if _, found := list[name]; !found {
+			list[name] = value
+		}
+	}
+}
+
+// validateResourceRequirements is ValidateResourceRequirements of k8s.io/kubernetes v1.9.0.
+func validateResourceRequirements(resources core.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	limPath := fldPath.Child("limits")
+	reqPath := fldPath.Child("requests")
+
+	hugePages, cpuOrMemory := false, false
+	for _, name := range resourceNames(resources.Limits) {
+		quantity := resources.Limits[name]
+		errs = append(errs, validateResourceName(name, limPath.Key(string(name)))...)
+		errs = append(errs, validateResourceQuantity(name, quantity, limPath.Key(string(name)))...)
+		hugePages = hugePages || isHugePageResourceName(name)
+		cpuOrMemory = cpuOrMemory || name == core.ResourceCPU || name == core.ResourceMemory
+	}
+	for _, name := range resourceNames(resources.Requests) {
+		quantity := resources.Requests[name]
+		errs = append(errs, validateResourceName(name, reqPath.Key(string(name)))...)
+		errs = append(errs, validateResourceQuantity(name, quantity, reqPath.Key(string(name)))...)
+		hugePages = hugePages || isHugePageResourceName(name)
+		cpuOrMemory = cpuOrMemory || name == core.ResourceCPU || name == core.ResourceMemory
+
+		if limit, found := resources.Limits[name]; found {
+			if quantity.Cmp(limit) > 0 {
+				errs = append(errs, field.Invalid(reqPath.Key(string(name)), quantity.String(), fmt.Sprintf("must be less than or equal to %s limit", name)))
+			}
+		} else if !isOvercommitAllowed(name) {
+			errs = append(errs, field.Required(limPath.Key(string(name)), "Limit must be set for non overcommitable resources"))
+		}
+	}
+	if hugePages && !cpuOrMemory {
+		errs = append(errs, field.Forbidden(fldPath, "HugePages require cpu or memory"))
+	}
+	return errs
+}
+
+// validateResourceName is validateContainerResourceName of k8s.io/kubernetes v1.9.0.
+func validateResourceName(name core.ResourceName, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(string(name)) {
+		errs = append(errs, field.Invalid(fldPath, name, msg))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if !strings.Contains(string(name), "/") {
+		if !standardContainerResources.Has(string(name)) && !isHugePageResourceName(name) {
+			errs = append(errs, field.Invalid(fldPath, name, "must be a standard resource for containers"))
+		}
+	} else if !isNativeResource(name) && !isExtendedResourceName(name) {
+		errs = append(errs, field.Invalid(fldPath, name, "doesn't follow extended resource name standard"))
+	}
+	return errs
+}
+
+// validateResourceQuantity is ValidateResourceQuantityValue of k8s.io/kubernetes v1.9.0.
+func validateResourceQuantity(name core.ResourceName, quantity resource.Quantity, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if quantity.Cmp(resource.Quantity{}) < 0 {
+		errs = append(errs, field.Invalid(fldPath, quantity.String(), "must be greater than or equal to 0"))
+	}
+	if (name == core.ResourceNvidiaGPU || isExtendedResourceName(name)) && quantity.MilliValue()%1000 != 0 {
+		errs = append(errs, field.Invalid(fldPath, quantity.String(), "must be an integer"))
+	}
+	return errs
+}
+
+// validateLimitRangeItem checks resources against the min, max and maxLimitRequestRatio of a Container item of a
+// LimitRange, like minConstraint, maxConstraint and limitRequestRatioConstraint of the LimitRanger of k8s.io/kubernetes
+// v1.9.0, with the same messages.
+func validateLimitRangeItem(limitRange string, item core.LimitRangeItem, resources core.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	limPath := fldPath.Child("limits")
+	reqPath := fldPath.Child("requests")
+
+	for _, name := range resourceNames(item.Min) {
+		min := item.Min[name]
+		request, found := resources.Requests[name]
+		if !found {
+			errs = append(errs, field.Required(reqPath.Key(string(name)),
+				fmt.Sprintf("minimum %s usage per Container is %s in LimitRange %s", name, min.String(), limitRange)))
+			continue
+		}
+		if request.Cmp(min) < 0 {
+			errs = append(errs, field.Invalid(reqPath.Key(string(name)), request.String(),
+				fmt.Sprintf("minimum %s usage per Container is %s in LimitRange %s", name, min.String(), limitRange)))
+		}
+		if limit, found := resources.Limits[name]; found && limit.Cmp(min) < 0 {
+			errs = append(errs, field.Invalid(limPath.Key(string(name)), limit.String(),
+				fmt.Sprintf("minimum %s usage per Container is %s in LimitRange %s", name, min.String(), limitRange)))
+		}
+	}
+
+	for _, name := range resourceNames(item.Max) {
+		max := item.Max[name]
+		limit, found := resources.Limits[name]
+		if !found {
+			errs = append(errs, field.Required(limPath.Key(string(name)),
+				fmt.Sprintf("maximum %s usage per Container is %s in LimitRange %s", name, max.String(), limitRange)))
+			continue
+		}
+		if limit.Cmp(max) > 0 {
+			errs = append(errs, field.Invalid(limPath.Key(string(name)), limit.String(),
+				fmt.Sprintf("maximum %s usage per Container is %s in LimitRange %s", name, max.String(), limitRange)))
+		}
+		if request, found := resources.Requests[name]; found && request.Cmp(max) > 0 {
+			errs = append(errs, field.Invalid(reqPath.Key(string(name)), request.String(),
+				fmt.Sprintf("maximum %s usage per Container is %s in LimitRange %s", name, max.String(), limitRange)))
+		}
+	}
+
+	for _, name := range resourceNames(item.MaxLimitRequestRatio) {
+		ratio := item.MaxLimitRequestRatio[name]
+		request, reqFound := resources.Requests[name]
+		limit, limFound := resources.Limits[name]
+		if !reqFound || request.IsZero() || !limFound || limit.IsZero() {
+			errs = append(errs, field.Required(fldPath,
+				fmt.Sprintf("%s max limit to request ratio per Container is %s in LimitRange %s, but %s request or limit is not specified or is 0", name, ratio.String(), limitRange, name)))
+			continue
+		}
+		observed := float64(limit.MilliValue()) / float64(request.MilliValue())
+		if observed > float64(ratio.MilliValue())/1000 {
+			errs = append(errs, field.Invalid(limPath.Key(string(name)), limit.String(),
+				fmt.Sprintf("%s max limit to request ratio per Container is %s in LimitRange %s, but provided ratio is %f", name, ratio.String(), limitRange, observed)))
+		}
+	}
+	return errs
+}
+
+// isNativeResource returns true if the resource name is in the kubernetes.io domain, or has no domain.
+func isNativeResource(name core.ResourceName) bool {
+	return !strings.Contains(string(name), "/") || strings.Contains(string(name), core.ResourceDefaultNamespacePrefix)
+}
+
+// isExtendedResourceName returns true if the resource name is a fully-qualified name outside the kubernetes.io domain.
+func isExtendedResourceName(name core.ResourceName) bool {
+	if isNativeResource(name) || strings.HasPrefix(string(name), "requests.") {
+		return false
+	}
+	// resource quota tracks extended resources with the "requests." prefix
+	return len(validation.IsQualifiedName("requests."+string(name))) == 0
+}
+
+// isHugePageResourceName returns true if the resource name has the hugepages- prefix.
+func isHugePageResourceName(name core.ResourceName) bool {
+	return strings.HasPrefix(string(name), core.ResourceHugePagesPrefix)
+}
+
+// isOvercommitAllowed returns true if the request of the resource may be set without a limit.
+func isOvercommitAllowed(name core.ResourceName) bool {
+	return isNativeResource(name) && !isHugePageResourceName(name)
+}
+
+// resourceNames returns the names of the resources in the list in a stable order, so that errors are reported
+// in the same order every time.
+func resourceNames(list core.ResourceList) []core.ResourceName {
+	names := make([]core.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,208 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateResources(t *testing.T) {
+	for _, c := range resourceCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if c.limitRange != nil {
+				client = fake.NewSimpleClientset(&core.LimitRange{
+					ObjectMeta: metaV1.ObjectMeta{
+						Name:      "limits",
+						Namespace: "default",
+					},
+					Spec: core.LimitRangeSpec{
+						Limits: []core.LimitRangeItem{*c.limitRange},
+					},
+				})
+			}
+
+			err := ValidateResources(client, c.resources, c.oldResources, "default", field.NewPath("spec").Child("resources"))
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var resourceCases = []struct {
+	testName     string
+	resources    core.ResourceRequirements
+	oldResources *core.ResourceRequirements
+	limitRange   *core.LimitRangeItem
+	result       bool
+}{
+	{"No resources",
+		core.ResourceRequirements{},
+		nil,
+		nil,
+		true,
+	},
+	{"Valid resources",
+		resources("cpu=500m,memory=256Mi", "cpu=1,memory=512Mi"),
+		nil,
+		nil,
+		true,
+	},
+	{"Request above limit",
+		resources("memory=1Gi", "memory=512Mi"),
+		nil,
+		nil,
+		false,
+	},
+	{"Negative quantity",
+		resources("cpu=-1", ""),
+		nil,
+		nil,
+		false,
+	},
+	{"Unknown resource name",
+		resources("gpu=1", ""),
+		nil,
+		nil,
+		false,
+	},
+	{"Extended resource without limit",
+		resources("example.com/dongle=1", ""),
+		nil,
+		nil,
+		false,
+	},
+	{"Fractional extended resource",
+		resources("example.com/dongle=500m", "example.com/dongle=500m"),
+		nil,
+		nil,
+		false,
+	},
+	{"Hugepages without cpu or memory",
+		resources("", "hugepages-2Mi=64Mi"),
+		nil,
+		nil,
+		false,
+	},
+	{"Request below LimitRange minimum",
+		resources("memory=64Mi", ""),
+		nil,
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Min:  resourceList("memory=128Mi"),
+		},
+		false,
+	},
+	{"Request missing with LimitRange minimum",
+		core.ResourceRequirements{},
+		nil,
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Min:  resourceList("memory=128Mi"),
+		},
+		false,
+	},
+	{"Request defaulted from LimitRange",
+		core.ResourceRequirements{},
+		nil,
+		&core.LimitRangeItem{
+			Type:           core.LimitTypeContainer,
+			Min:            resourceList("memory=128Mi"),
+			Max:            resourceList("memory=1Gi"),
+			Default:        resourceList("memory=512Mi"),
+			DefaultRequest: resourceList("memory=256Mi"),
+		},
+		true,
+	},
+	{"Request defaulted from limit",
+		resources("", "memory=256Mi"),
+		nil,
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Min:  resourceList("memory=128Mi"),
+		},
+		true,
+	},
+	{"Limit above LimitRange maximum",
+		resources("cpu=1", "cpu=4"),
+		nil,
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Max:  resourceList("cpu=2"),
+		},
+		false,
+	},
+	{"Limit to request ratio above LimitRange maximum",
+		resources("cpu=250m", "cpu=1"),
+		nil,
+		&core.LimitRangeItem{
+			Type:                 core.LimitTypeContainer,
+			MaxLimitRequestRatio: resourceList("cpu=2"),
+		},
+		false,
+	},
+	{"Pod LimitRange",
+		resources("cpu=4", "cpu=4"),
+		nil,
+		&core.LimitRangeItem{
+			Type: core.LimitTypePod,
+			Max:  resourceList("cpu=2"),
+		},
+		true,
+	},
+	{"Unchanged request below LimitRange minimum",
+		resources("memory=64Mi", ""),
+		resourcesP("memory=64Mi", ""),
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Min:  resourceList("memory=128Mi"),
+		},
+		true,
+	},
+	{"Changed request below LimitRange minimum",
+		resources("memory=64Mi", ""),
+		resourcesP("memory=96Mi", ""),
+		&core.LimitRangeItem{
+			Type: core.LimitTypeContainer,
+			Min:  resourceList("memory=128Mi"),
+		},
+		false,
+	},
+	{"Unchanged request above limit",
+		resources("memory=1Gi", "memory=512Mi"),
+		resourcesP("memory=1Gi", "memory=512Mi"),
+		nil,
+		false,
+	},
+}
+
+func resourcesP(requests, limits string) *core.ResourceRequirements {
+	r := resources(requests, limits)
+	return &r
+}
+
+func resources(requests, limits string) core.ResourceRequirements {
+	return core.ResourceRequirements{
+		Requests: resourceList(requests),
+		Limits:   resourceList(limits),
+	}
+}
+
+// resourceList parses lists like "cpu=500m,memory=1Gi"
+func resourceList(list string) core.ResourceList {
+	if list == "" {
+		return nil
+	}
+	rl := core.ResourceList{}
+	for _, kv := range strings.Split(list, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		rl[core.ResourceName(parts[0])] = resource.MustParse(parts[1])
+	}
+	return rl
+}
@@ -0,0 +1,105 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	cron "gopkg.in/robfig/cron.v2"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// number of upcoming backups reported back to the user
+	nextScheduleCount = 3
+	// number of backups checked against the minimum interval
+	maxScheduleChecks = 10000
+)
+
+// ValidateBackupSchedule checks the BackupSchedule of a database: its cron expression, the resources of the backup
+// jobs, and the storage of the snapshots. Consecutive backups must be at least minInterval apart. It returns the
+// next few fire times. On update, the cron expression is not checked again when it is not changed from oldSpec.
+func ValidateBackupSchedule(client kubernetes.Interface, prober *bucket.Prober, spec, oldSpec *api.BackupScheduleSpec, namespace string, minInterval time.Duration, deadline time.Time) ([]time.Time, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	// CronExpression can't be empty
+	if strings.TrimSpace(spec.CronExpression) == "" {
+		return nil, fmt.Errorf("spec.backupSchedule.cronExpression is missing")
+	}
+
+	var next []time.Time
+	if oldSpec == nil || oldSpec.CronExpression != spec.CronExpression {
+		var err error
+		if next, err = nextBackups(spec.CronExpression, minInterval, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	var oldResources *core.ResourceRequirements
+	if oldSpec != nil {
+		oldResources = &oldSpec.Resources
+	}
+	if err := ValidateResources(client, spec.Resources, oldResources, namespace, field.NewPath("spec").Child("backupSchedule", "resources")); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateSnapshotSpec(client, prober, spec.SnapshotStorageSpec, namespace, deadline); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// BackupScheduleMessage returns the message that reports the upcoming backups of a database back to the user.
+func BackupScheduleMessage(next []time.Time) string {
+	if len(next) == 0 {
+		return ""
+	}
+	times := make([]string, 0, len(next))
+	for _, t := range next {
+		times = append(times, t.Format(time.RFC3339))
+	}
+	return "next backups are scheduled at " + strings.Join(times, ", ")
+}
+
+// nextBackups parses a cron expression the way KubeDB operator does: 5 or 6 fields, or descriptors like @daily or
+// @every 1h. It checks that consecutive backups are at least minInterval apart, and returns the next few fire times
+// after now.
+func nextBackups(cronExpression string, minInterval time.Duration, now time.Time) ([]time.Time, error) {
+	schedule, err := cron.Parse(cronExpression)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid spec.backupSchedule.cronExpression "%v": %v`, cronExpression, err)
+	}
+
+	next := make([]time.Time, 0, nextScheduleCount)
+	last := now
+	horizon := now.AddDate(1, 0, 0)
+	for i := 0; i < maxScheduleChecks; i++ {
+		t := schedule.Next(last)
+		if t.IsZero() {
+			if i == 0 {
+				return nil, fmt.Errorf(`spec.backupSchedule.cronExpression "%v" never fires`, cronExpression)
+			}
+			break
+		}
+		if i > 0 && t.Sub(last) < minInterval {
+			return nil, fmt.Errorf(`spec.backupSchedule.cronExpression "%v" runs backups %v apart at %v. Minimum interval between backups is %v`,
+				cronExpression, t.Sub(last), last.Format(time.RFC3339), minInterval)
+		}
+		if len(next) < nextScheduleCount {
+			next = append(next, t)
+		}
+		if minInterval <= 0 && len(next) == nextScheduleCount {
+			break
+		}
+		if t.After(horizon) {
+			break
+		}
+		last = t
+	}
+	return next, nil
+}
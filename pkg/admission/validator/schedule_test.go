@@ -0,0 +1,193 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateBackupSchedule(t *testing.T) {
+	for _, c := range backupScheduleCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			next, err := ValidateBackupSchedule(client, bucket.New(config.New()), c.spec, c.oldSpec, "default", time.Hour, time.Now().Add(time.Second))
+			if c.result != (err == nil) {
+				t.Fatalf("expected success: %v, but got error: %v", c.result, err)
+			}
+			if len(next) != c.next {
+				t.Errorf("expected %d next backups, but got: %v", c.next, next)
+			}
+		})
+	}
+}
+
+func TestNextBackups(t *testing.T) {
+	now := time.Date(2018, time.March, 1, 0, 0, 30, 0, time.Local)
+	for _, c := range scheduleCases {
+		t.Run(c.testName, func(t *testing.T) {
+			next, err := nextBackups(c.cronExpression, c.minInterval, now)
+			if c.result != (err == nil) {
+				t.Fatalf("expected success: %v, but got error: %v", c.result, err)
+			}
+			if len(next) != len(c.next) {
+				t.Fatalf("expected next backups: %v, but got: %v", c.next, next)
+			}
+			for i := range next {
+				if want := now.Add(c.next[i]); !next[i].Equal(want) {
+					t.Errorf("expected backup %d at %v, but got: %v", i, want, next[i])
+				}
+			}
+		})
+	}
+}
+
+func backupSchedule(cronExpression string) *api.BackupScheduleSpec {
+	return &api.BackupScheduleSpec{
+		CronExpression: cronExpression,
+		SnapshotStorageSpec: api.SnapshotStorageSpec{
+			Local: &api.LocalSpec{MountPath: "/repo"},
+		},
+	}
+}
+
+var backupScheduleCases = []struct {
+	testName string
+	spec     *api.BackupScheduleSpec
+	oldSpec  *api.BackupScheduleSpec
+	next     int
+	result   bool
+}{
+	{"No backup schedule",
+		nil,
+		nil,
+		0,
+		true,
+	},
+	{"Empty expression",
+		backupSchedule(" "),
+		nil,
+		0,
+		false,
+	},
+	{"Create with hourly schedule",
+		backupSchedule("@hourly"),
+		nil,
+		3,
+		true,
+	},
+	{"Create with schedule below minimum interval",
+		backupSchedule("@every 30m"),
+		nil,
+		0,
+		false,
+	},
+	{"Update with unchanged schedule below minimum interval",
+		backupSchedule("@every 30m"),
+		backupSchedule("@every 30m"),
+		0,
+		true,
+	},
+	{"Update with changed schedule below minimum interval",
+		backupSchedule("@every 30m"),
+		backupSchedule("@hourly"),
+		0,
+		false,
+	},
+	{"Update clearing the expression",
+		backupSchedule(""),
+		backupSchedule("@hourly"),
+		0,
+		false,
+	},
+}
+
+var scheduleCases = []struct {
+	testName       string
+	cronExpression string
+	minInterval    time.Duration
+	next           []time.Duration
+	result         bool
+}{
+	{"Hourly schedule at minimum interval",
+		"0 * * * *",
+		time.Hour,
+		[]time.Duration{time.Hour - 30*time.Second, 2*time.Hour - 30*time.Second, 3*time.Hour - 30*time.Second},
+		true,
+	},
+	{"Hourly schedule below minimum interval",
+		"0 * * * *",
+		time.Hour + time.Second,
+		nil,
+		false,
+	},
+	{"Schedule with seconds field",
+		"0 30 0 * * *",
+		time.Hour,
+		[]time.Duration{30*time.Minute - 30*time.Second, 24*time.Hour + 30*time.Minute - 30*time.Second, 48*time.Hour + 30*time.Minute - 30*time.Second},
+		true,
+	},
+	{"Schedule running twice a day close together",
+		"0 0,1 * * *",
+		2 * time.Hour,
+		nil,
+		false,
+	},
+	{"Every schedule at minimum interval",
+		"@every 6h",
+		6 * time.Hour,
+		[]time.Duration{6 * time.Hour, 12 * time.Hour, 18 * time.Hour},
+		true,
+	},
+	{"Every schedule below minimum interval",
+		"@every 30m",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Every schedule with invalid duration",
+		"@every 6 hours",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Daily descriptor",
+		"@daily",
+		time.Hour,
+		[]time.Duration{24*time.Hour - 30*time.Second, 48*time.Hour - 30*time.Second, 72*time.Hour - 30*time.Second},
+		true,
+	},
+	{"Unknown descriptor",
+		"@fortnightly",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Too few fields",
+		"0 * *",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Value out of range",
+		"0 25 * * *",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Inverted range",
+		"0 5-1 * * *",
+		time.Hour,
+		nil,
+		false,
+	},
+	{"Schedule that never fires",
+		"0 0 30 2 *",
+		time.Hour,
+		nil,
+		false,
+	},
+}
@@ -0,0 +1,290 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	meta_util "github.com/appscode/kutil/meta"
+	"github.com/kubedb/kubedb-server/pkg/admission/util"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The validation below follows pkg/apis/core/validation/validation.go of k8s.io/kubernetes v1.9.0
+// (925c127ec6b946659ad0fd596fa959be43f0cc05), the version pinned in glide.yaml: validateAffinity, ValidateNodeSelector,
+// ValidateNodeSelectorRequirement, validatePodAffinityTerm and ValidateTolerations. That package is not vendored,
+// since it works on the internal API types and pulls in most of the tree. Keep the copy in sync with that version
+// when k8s.io/kubernetes is bumped. Label and taint matching reuse k8s.io/apimachinery and k8s.io/api.
+
+// ValidateScheduling validates the node selector, affinity and tolerations of the database pods,
+// the way kube-apiserver validates them in a PodSpec.
+func ValidateScheduling(nodeSelector map[string]string, affinity *core.Affinity, tolerations []core.Toleration, fldPath *field.Path) error {
+	var errs field.ErrorList
+	errs = append(errs, metav1validation.ValidateLabels(nodeSelector, fldPath.Child("nodeSelector"))...)
+	errs = append(errs, validateAffinity(affinity, fldPath.Child("affinity"))...)
+	errs = append(errs, validateTolerations(tolerations, fldPath.Child("tolerations"))...)
+	return errs.ToAggregate()
+}
+
+func validateAffinity(affinity *core.Affinity, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if affinity == nil {
+		return errs
+	}
+	if na := affinity.NodeAffinity; na != nil {
+		naPath := fldPath.Child("nodeAffinity")
+		if na.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			errs = append(errs, validateNodeSelector(na.RequiredDuringSchedulingIgnoredDuringExecution, naPath.Child("requiredDuringSchedulingIgnoredDuringExecution"))...)
+		}
+		for i, term := range na.PreferredDuringSchedulingIgnoredDuringExecution {
+			termPath := naPath.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+			errs = append(errs, validateWeight(term.Weight, termPath.Child("weight"))...)
+			errs = append(errs, validateNodeSelectorTerm(term.Preference, termPath.Child("preference"))...)
+		}
+	}
+	if pa := affinity.PodAffinity; pa != nil {
+		errs = append(errs, validatePodAffinityTerms(pa.RequiredDuringSchedulingIgnoredDuringExecution, pa.PreferredDuringSchedulingIgnoredDuringExecution, fldPath.Child("podAffinity"))...)
+	}
+	if pa := affinity.PodAntiAffinity; pa != nil {
+		errs = append(errs, validatePodAffinityTerms(pa.RequiredDuringSchedulingIgnoredDuringExecution, pa.PreferredDuringSchedulingIgnoredDuringExecution, fldPath.Child("podAntiAffinity"))...)
+	}
+	return errs
+}
+
+func validateNodeSelector(selector *core.NodeSelector, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	termsPath := fldPath.Child("nodeSelectorTerms")
+	if len(selector.NodeSelectorTerms) == 0 {
+		return append(errs, field.Required(termsPath, "must have at least one node selector term"))
+	}
+	for i, term := range selector.NodeSelectorTerms {
+		errs = append(errs, validateNodeSelectorTerm(term, termsPath.Index(i))...)
+	}
+	return errs
+}
+
+func validateNodeSelectorTerm(term core.NodeSelectorTerm, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(term.MatchExpressions) == 0 {
+		return append(errs, field.Required(fldPath.Child("matchExpressions"), "must have at least one node selector requirement"))
+	}
+	for i, req := range term.MatchExpressions {
+		reqPath := fldPath.Child("matchExpressions").Index(i)
+		switch req.Operator {
+		case core.NodeSelectorOpIn, core.NodeSelectorOpNotIn:
+			if len(req.Values) == 0 {
+				errs = append(errs, field.Required(reqPath.Child("values"), "must be specified when `operator` is 'In' or 'NotIn'"))
+			}
+		case core.NodeSelectorOpExists, core.NodeSelectorOpDoesNotExist:
+			if len(req.Values) > 0 {
+				errs = append(errs, field.Forbidden(reqPath.Child("values"), "may not be specified when `operator` is 'Exists' or 'DoesNotExist'"))
+			}
+		case core.NodeSelectorOpGt, core.NodeSelectorOpLt:
+			if len(req.Values) != 1 {
+				errs = append(errs, field.Required(reqPath.Child("values"), "must be specified single value when `operator` is 'Lt' or 'Gt'"))
+			} else if _, err := strconv.ParseInt(req.Values[0], 10, 64); err != nil {
+				errs = append(errs, field.Invalid(reqPath.Child("values").Index(0), req.Values[0], "must be an integer when `operator` is 'Lt' or 'Gt'"))
+			}
+		default:
+			errs = append(errs, field.Invalid(reqPath.Child("operator"), req.Operator, "not a valid selector operator"))
+		}
+		errs = append(errs, metav1validation.ValidateLabelName(req.Key, reqPath.Child("key"))...)
+	}
+	return errs
+}
+
+func validatePodAffinityTerms(required []core.PodAffinityTerm, preferred []core.WeightedPodAffinityTerm, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, term := range required {
+		errs = append(errs, validatePodAffinityTerm(term, fldPath.Child("requiredDuringSchedulingIgnoredDuringExecution").Index(i))...)
+	}
+	for i, term := range preferred {
+		termPath := fldPath.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+		errs = append(errs, validateWeight(term.Weight, termPath.Child("weight"))...)
+		errs = append(errs, validatePodAffinityTerm(term.PodAffinityTerm, termPath.Child("podAffinityTerm"))...)
+	}
+	return errs
+}
+
+func validatePodAffinityTerm(term core.PodAffinityTerm, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, metav1validation.ValidateLabelSelector(term.LabelSelector, fldPath.Child("labelSelector"))...)
+	for _, ns := range term.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespaces"), ns, msg))
+		}
+	}
+	if term.TopologyKey == "" {
+		errs = append(errs, field.Required(fldPath.Child("topologyKey"), "can not be empty"))
+	} else {
+		errs = append(errs, metav1validation.ValidateLabelName(term.TopologyKey, fldPath.Child("topologyKey"))...)
+	}
+	return errs
+}
+
+func validateWeight(weight int32, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if weight <= 0 || weight > 100 {
+		errs = append(errs, field.Invalid(fldPath, weight, "must be in the range 1-100"))
+	}
+	return errs
+}
+
+func validateTolerations(tolerations []core.Toleration, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, toleration := range tolerations {
+		idxPath := fldPath.Index(i)
+		if toleration.Key != "" {
+			errs = append(errs, metav1validation.ValidateLabelName(toleration.Key, idxPath.Child("key"))...)
+		} else if toleration.Operator != core.TolerationOpExists {
+			errs = append(errs, field.Invalid(idxPath.Child("operator"), toleration.Operator, "operator must be Exists when `key` is empty, which means \"match all values and all keys\""))
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != core.TaintEffectNoExecute {
+			errs = append(errs, field.Invalid(idxPath.Child("effect"), toleration.Effect, "effect must be 'NoExecute' when `tolerationSeconds` is set"))
+		}
+
+		switch toleration.Operator {
+		case core.TolerationOpEqual, "":
+			for _, msg := range validation.IsValidLabelValue(toleration.Value) {
+				errs = append(errs, field.Invalid(idxPath.Child("value"), toleration.Value, msg))
+			}
+		case core.TolerationOpExists:
+			if toleration.Value != "" {
+				errs = append(errs, field.Invalid(idxPath.Child("operator"), toleration, "value must be empty when `operator` is 'Exists'"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(idxPath.Child("operator"), toleration.Operator, []string{string(core.TolerationOpEqual), string(core.TolerationOpExists)}))
+		}
+
+		switch toleration.Effect {
+		case "", core.TaintEffectNoSchedule, core.TaintEffectPreferNoSchedule, core.TaintEffectNoExecute:
+		default:
+			errs = append(errs, field.NotSupported(idxPath.Child("effect"), toleration.Effect,
+				[]string{string(core.TaintEffectNoSchedule), string(core.TaintEffectPreferNoSchedule), string(core.TaintEffectNoExecute)}))
+		}
+	}
+	return errs
+}
+
+// CheckSchedulable checks that at least one schedulable Node matches the node selector and the required node
+// affinity, and has no NoSchedule or NoExecute taint that is not tolerated. Pod affinity is not considered,
+// because it depends on the pods running at the time the database pods are scheduled. On update, ie: when the
+// database before the update is given as oldObject, the check is skipped when the scheduling constraints are not changed.
+func CheckSchedulable(client kubernetes.Interface, nodeSelector map[string]string, affinity *core.Affinity, tolerations []core.Toleration, oldObject runtime.Object) error {
+	if schedulingUnchanged(nodeSelector, affinity, tolerations, oldObject) {
+		return nil
+	}
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(nodes.Items) == 0 {
+		return errors.New("no nodes are available to schedule database pods")
+	}
+
+	reasons := map[string]int{}
+	for i := range nodes.Items {
+		if reason := nodeFitsScheduling(&nodes.Items[i], nodeSelector, affinity, tolerations); reason != "" {
+			reasons[reason]++
+		} else {
+			return nil
+		}
+	}
+
+	var msgs []string
+	for _, reason := range sets.StringKeySet(reasons).List() {
+		msgs = append(msgs, fmt.Sprintf("%d %s", reasons[reason], reason))
+	}
+	return fmt.Errorf("database pods can't be scheduled, 0/%d nodes are available: %s", len(nodes.Items), strings.Join(msgs, ", "))
+}
+
+// schedulingUnchanged reports whether oldObject is set and has the same node selector, affinity and tolerations.
+func schedulingUnchanged(nodeSelector map[string]string, affinity *core.Affinity, tolerations []core.Toleration, oldObject runtime.Object) bool {
+	return oldObject != nil &&
+		meta_util.Equal(util.GetNodeSelector(oldObject), nodeSelector) &&
+		meta_util.Equal(util.GetAffinity(oldObject), affinity) &&
+		meta_util.Equal(util.GetTolerations(oldObject), tolerations)
+}
+
+// nodeFitsScheduling returns the reason why database pods can't be scheduled on the node, or an empty string
+// if they can.
+func nodeFitsScheduling(node *core.Node, nodeSelector map[string]string, affinity *core.Affinity, tolerations []core.Toleration) string {
+	if node.Spec.Unschedulable {
+		return "node(s) were unschedulable"
+	}
+	if !labels.SelectorFromSet(nodeSelector).Matches(labels.Set(node.Labels)) {
+		return "node(s) didn't match node selector"
+	}
+	if affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		matched := false
+		for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			if matchNodeSelectorTerm(node.Labels, term) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "node(s) didn't match node affinity"
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect != core.TaintEffectNoSchedule && taint.Effect != core.TaintEffectNoExecute {
+			continue
+		}
+		if !toleratesTaint(tolerations, taint) {
+			return "node(s) had taints that the pod didn't tolerate"
+		}
+	}
+	return ""
+}
+
+// matchNodeSelectorTerm returns true if the labels match every requirement of term, like nodeMatchesNodeSelectorTerms
+// of the scheduler predicates of k8s.io/kubernetes v1.9.0. A term without requirements matches no node.
+func matchNodeSelectorTerm(nodeLabels map[string]string, term core.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 {
+		return false
+	}
+	selector := labels.NewSelector()
+	for _, req := range term.MatchExpressions {
+		op, found := nodeSelectorOperators[req.Operator]
+		if !found {
+			return false
+		}
+		r, err := labels.NewRequirement(req.Key, op, req.Values)
+		if err != nil {
+			return false
+		}
+		selector = selector.Add(*r)
+	}
+	return selector.Matches(labels.Set(nodeLabels))
+}
+
+// nodeSelectorOperators maps the operators of node selector requirements to label selector operators, like
+// NodeSelectorRequirementsAsSelector of k8s.io/kubernetes v1.9.0.
+var nodeSelectorOperators = map[core.NodeSelectorOperator]selection.Operator{
+	core.NodeSelectorOpIn:           selection.In,
+	core.NodeSelectorOpNotIn:        selection.NotIn,
+	core.NodeSelectorOpExists:       selection.Exists,
+	core.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	core.NodeSelectorOpGt:           selection.GreaterThan,
+	core.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func toleratesTaint(tolerations []core.Toleration, taint core.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
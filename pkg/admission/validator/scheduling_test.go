@@ -0,0 +1,261 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/appscode/go/types"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateScheduling(t *testing.T) {
+	for _, c := range schedulingCases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateScheduling(c.nodeSelector, c.affinity, c.tolerations, field.NewPath("spec"))
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+func TestCheckSchedulable(t *testing.T) {
+	for _, c := range schedulableCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(c.nodes...)
+			err := CheckSchedulable(client, c.nodeSelector, c.affinity, c.tolerations, c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var schedulingCases = []struct {
+	testName     string
+	nodeSelector map[string]string
+	affinity     *core.Affinity
+	tolerations  []core.Toleration
+	result       bool
+}{
+	{"No constraints",
+		nil,
+		nil,
+		nil,
+		true,
+	},
+	{"Valid constraints",
+		map[string]string{"disktype": "ssd"},
+		nodeAffinity(core.NodeSelectorRequirement{Key: "zone", Operator: core.NodeSelectorOpIn, Values: []string{"a", "b"}}),
+		[]core.Toleration{{Key: "dedicated", Operator: core.TolerationOpEqual, Value: "db", Effect: core.TaintEffectNoSchedule}},
+		true,
+	},
+	{"Invalid NodeSelector key",
+		map[string]string{"disk type": "ssd"},
+		nil,
+		nil,
+		false,
+	},
+	{"Node affinity without values",
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "zone", Operator: core.NodeSelectorOpIn}),
+		nil,
+		false,
+	},
+	{"Node affinity with invalid operator",
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "zone", Operator: "Equals", Values: []string{"a"}}),
+		nil,
+		false,
+	},
+	{"Node affinity with non integer Gt",
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "cores", Operator: core.NodeSelectorOpGt, Values: []string{"four"}}),
+		nil,
+		false,
+	},
+	{"Pod anti-affinity without topologyKey",
+		nil,
+		&core.Affinity{
+			PodAntiAffinity: &core.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []core.PodAffinityTerm{
+					{LabelSelector: &metaV1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+				},
+			},
+		},
+		nil,
+		false,
+	},
+	{"Toleration with empty key",
+		nil,
+		nil,
+		[]core.Toleration{{Operator: core.TolerationOpEqual, Value: "db"}},
+		false,
+	},
+	{"Toleration with value for Exists",
+		nil,
+		nil,
+		[]core.Toleration{{Key: "dedicated", Operator: core.TolerationOpExists, Value: "db"}},
+		false,
+	},
+	{"Toleration with tolerationSeconds for NoSchedule",
+		nil,
+		nil,
+		[]core.Toleration{{Key: "dedicated", Operator: core.TolerationOpExists, Effect: core.TaintEffectNoSchedule, TolerationSeconds: types.Int64P(60)}},
+		false,
+	},
+}
+
+var schedulableCases = []struct {
+	testName     string
+	nodes        []runtime.Object
+	nodeSelector map[string]string
+	affinity     *core.Affinity
+	tolerations  []core.Toleration
+	oldObject    runtime.Object
+	result       bool
+}{
+	{"No nodes",
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		false,
+	},
+	{"Any node",
+		[]runtime.Object{sampleNode("a", nil)},
+		nil,
+		nil,
+		nil,
+		nil,
+		true,
+	},
+	{"Unschedulable node",
+		[]runtime.Object{editUnschedulable(sampleNode("a", nil))},
+		nil,
+		nil,
+		nil,
+		nil,
+		false,
+	},
+	{"NodeSelector matches a node",
+		[]runtime.Object{sampleNode("a", nil), sampleNode("b", map[string]string{"disktype": "ssd"})},
+		map[string]string{"disktype": "ssd"},
+		nil,
+		nil,
+		nil,
+		true,
+	},
+	{"NodeSelector matches no node",
+		[]runtime.Object{sampleNode("a", map[string]string{"disktype": "hdd"})},
+		map[string]string{"disktype": "ssd"},
+		nil,
+		nil,
+		nil,
+		false,
+	},
+	{"Node affinity matches no node",
+		[]runtime.Object{sampleNode("a", map[string]string{"zone": "c"})},
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "zone", Operator: core.NodeSelectorOpIn, Values: []string{"a", "b"}}),
+		nil,
+		nil,
+		false,
+	},
+	{"Node affinity with Gt",
+		[]runtime.Object{sampleNode("a", map[string]string{"cores": "8"})},
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "cores", Operator: core.NodeSelectorOpGt, Values: []string{"4"}}),
+		nil,
+		nil,
+		true,
+	},
+	{"Taint is not tolerated",
+		[]runtime.Object{editTaint(sampleNode("a", nil), "dedicated", "db", core.TaintEffectNoSchedule)},
+		nil,
+		nil,
+		nil,
+		nil,
+		false,
+	},
+	{"Taint is tolerated",
+		[]runtime.Object{editTaint(sampleNode("a", nil), "dedicated", "db", core.TaintEffectNoSchedule)},
+		nil,
+		nil,
+		[]core.Toleration{{Key: "dedicated", Operator: core.TolerationOpEqual, Value: "db", Effect: core.TaintEffectNoSchedule}},
+		nil,
+		true,
+	},
+	{"PreferNoSchedule taint",
+		[]runtime.Object{editTaint(sampleNode("a", nil), "dedicated", "db", core.TaintEffectPreferNoSchedule)},
+		nil,
+		nil,
+		nil,
+		nil,
+		true,
+	},
+	{"Unchanged constraints on update",
+		[]runtime.Object{sampleNode("a", map[string]string{"disktype": "hdd"})},
+		map[string]string{"disktype": "ssd"},
+		nil,
+		nil,
+		editNodeSelector(samplePostgres(), map[string]string{"disktype": "ssd"}),
+		true,
+	},
+	{"Changed constraints on update",
+		[]runtime.Object{sampleNode("a", map[string]string{"disktype": "hdd"})},
+		map[string]string{"disktype": "ssd"},
+		nil,
+		nil,
+		samplePostgres(),
+		false,
+	},
+}
+
+func samplePostgres() *api.Postgres {
+	return &api.Postgres{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+}
+
+func editNodeSelector(old *api.Postgres, nodeSelector map[string]string) *api.Postgres {
+	old.Spec.NodeSelector = nodeSelector
+	return old
+}
+
+func nodeAffinity(requirements ...core.NodeSelectorRequirement) *core.Affinity {
+	return &core.Affinity{
+		NodeAffinity: &core.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &core.NodeSelector{
+				NodeSelectorTerms: []core.NodeSelectorTerm{{MatchExpressions: requirements}},
+			},
+		},
+	}
+}
+
+func sampleNode(name string, labels map[string]string) *core.Node {
+	return &core.Node{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func editUnschedulable(old *core.Node) *core.Node {
+	old.Spec.Unschedulable = true
+	return old
+}
+
+func editTaint(old *core.Node, key, value string, effect core.TaintEffect) *core.Node {
+	old.Spec.Taints = append(old.Spec.Taints, core.Taint{Key: key, Value: value, Effect: effect})
+	return old
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"fmt"
+
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ValidateImagePullSecrets checks that the image pull secrets exist in namespace and hold docker registry credentials.
+// On update, the check is skipped when the secrets are not changed from oldSecrets, so that deleting a secret the
+// pods no longer need doesn't block edits of the database.
+func ValidateImagePullSecrets(client kubernetes.Interface, secrets, oldSecrets []core.LocalObjectReference, namespace string, fldPath *field.Path) error {
+	if len(secrets) == 0 || meta_util.Equal(secrets, oldSecrets) {
+		return nil
+	}
+	var errs field.ErrorList
+	for i, ref := range secrets {
+		idxPath := fldPath.Index(i).Child("name")
+		if ref.Name == "" {
+			errs = append(errs, field.Required(idxPath, "image pull secret name is required"))
+			continue
+		}
+		secret, err := client.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			errs = append(errs, field.NotFound(idxPath, ref.Name))
+			continue
+		} else if err != nil {
+			return err
+		}
+		if secret.Type != core.SecretTypeDockerConfigJson && secret.Type != core.SecretTypeDockercfg {
+			errs = append(errs, field.Invalid(idxPath, ref.Name,
+				fmt.Sprintf("secret type is %s, but must be %s or %s", secret.Type, core.SecretTypeDockerConfigJson, core.SecretTypeDockercfg)))
+		}
+	}
+	return errs.ToAggregate()
+}
+
+// databaseSecretKeys are the keys KubeDB operator reads from the auth secret of each database kind.
+var databaseSecretKeys = map[string][]string{
+	api.ResourceKindPostgres:      {"POSTGRES_PASSWORD"},
+	api.ResourceKindMySQL:         {"user", "password"},
+	api.ResourceKindMongoDB:       {"user", "password"},
+	api.ResourceKindElasticsearch: {"ADMIN_PASSWORD", "READALL_PASSWORD"},
+}
+
+// databasePasswordKeys are the keys of the auth secret that hold passwords.
+var databasePasswordKeys = map[string][]string{
+	api.ResourceKindPostgres:      {"POSTGRES_PASSWORD"},
+	api.ResourceKindMySQL:         {"password"},
+	api.ResourceKindMongoDB:       {"password"},
+	api.ResourceKindElasticsearch: {"ADMIN_PASSWORD", "READALL_PASSWORD"},
+}
+
+// ValidateDatabaseSecret checks that the auth secret of a database of the given kind exists in namespace
+// and has a value for every key KubeDB operator reads from it.
+func ValidateDatabaseSecret(client kubernetes.Interface, kind string, secretRef *core.SecretVolumeSource, namespace string, fldPath *field.Path) error {
+	if secretRef == nil {
+		return nil
+	}
+	namePath := fldPath.Child("secretName")
+	if secretRef.SecretName == "" {
+		return field.Required(namePath, "auth secret name is required")
+	}
+	secret, err := client.CoreV1().Secrets(namespace).Get(secretRef.SecretName, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return field.NotFound(namePath, secretRef.SecretName)
+	} else if err != nil {
+		return err
+	}
+
+	var errs field.ErrorList
+	for _, key := range databaseSecretKeys[kind] {
+		if len(secret.Data[key]) == 0 {
+			errs = append(errs, field.Invalid(namePath, secretRef.SecretName, fmt.Sprintf("secret is missing key %s required by %s", key, kind)))
+		}
+	}
+	return errs.ToAggregate()
+}
@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"testing"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateImagePullSecrets(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		sampleSecret("registry", core.SecretTypeDockerConfigJson, nil),
+		sampleSecret("legacy-registry", core.SecretTypeDockercfg, nil),
+		sampleSecret("opaque", core.SecretTypeOpaque, nil),
+	)
+
+	for _, c := range imagePullSecretCases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateImagePullSecrets(client, c.secrets, c.oldSecrets, "default", field.NewPath("spec").Child("imagePullSecrets"))
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var imagePullSecretCases = []struct {
+	testName   string
+	secrets    []core.LocalObjectReference
+	oldSecrets []core.LocalObjectReference
+	result     bool
+}{
+	{"No secrets",
+		nil,
+		nil,
+		true,
+	},
+	{"Docker config secrets",
+		[]core.LocalObjectReference{{Name: "registry"}, {Name: "legacy-registry"}},
+		nil,
+		true,
+	},
+	{"Missing secret",
+		[]core.LocalObjectReference{{Name: "registry"}, {Name: "regsitry"}},
+		nil,
+		false,
+	},
+	{"Secret of wrong type",
+		[]core.LocalObjectReference{{Name: "opaque"}},
+		nil,
+		false,
+	},
+	{"Empty secret name",
+		[]core.LocalObjectReference{{}},
+		nil,
+		false,
+	},
+	{"Unchanged missing secret",
+		[]core.LocalObjectReference{{Name: "registry"}, {Name: "regsitry"}},
+		[]core.LocalObjectReference{{Name: "registry"}, {Name: "regsitry"}},
+		true,
+	},
+	{"Missing secret added",
+		[]core.LocalObjectReference{{Name: "registry"}, {Name: "regsitry"}},
+		[]core.LocalObjectReference{{Name: "registry"}},
+		false,
+	},
+}
+
+func TestValidateDatabaseSecret(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		sampleSecret("postgres-auth", core.SecretTypeOpaque, map[string][]byte{"POSTGRES_PASSWORD": []byte("secret")}),
+		sampleSecret("mysql-auth", core.SecretTypeOpaque, map[string][]byte{"user": []byte("root"), "password": []byte("secret")}),
+		sampleSecret("elasticsearch-auth", core.SecretTypeOpaque, map[string][]byte{"ADMIN_PASSWORD": []byte("secret")}),
+		sampleSecret("empty-password", core.SecretTypeOpaque, map[string][]byte{"POSTGRES_PASSWORD": nil}),
+	)
+
+	for _, c := range databaseSecretCases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := ValidateDatabaseSecret(client, c.kind, c.secret, "default", field.NewPath("spec").Child("databaseSecret"))
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var databaseSecretCases = []struct {
+	testName string
+	kind     string
+	secret   *core.SecretVolumeSource
+	result   bool
+}{
+	{"No secret",
+		api.ResourceKindPostgres,
+		nil,
+		true,
+	},
+	{"Postgres secret",
+		api.ResourceKindPostgres,
+		&core.SecretVolumeSource{SecretName: "postgres-auth"},
+		true,
+	},
+	{"MySQL secret",
+		api.ResourceKindMySQL,
+		&core.SecretVolumeSource{SecretName: "mysql-auth"},
+		true,
+	},
+	{"MongoDB secret with MySQL keys",
+		api.ResourceKindMongoDB,
+		&core.SecretVolumeSource{SecretName: "mysql-auth"},
+		true,
+	},
+	{"Postgres secret with MySQL keys",
+		api.ResourceKindPostgres,
+		&core.SecretVolumeSource{SecretName: "mysql-auth"},
+		false,
+	},
+	{"Elasticsearch secret without READALL_PASSWORD",
+		api.ResourceKindElasticsearch,
+		&core.SecretVolumeSource{SecretName: "elasticsearch-auth"},
+		false,
+	},
+	{"Empty password",
+		api.ResourceKindPostgres,
+		&core.SecretVolumeSource{SecretName: "empty-password"},
+		false,
+	},
+	{"Missing secret",
+		api.ResourceKindPostgres,
+		&core.SecretVolumeSource{SecretName: "foo-auth"},
+		false,
+	},
+}
+
+func sampleSecret(name string, secretType core.SecretType, data map[string][]byte) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Type: secretType,
+		Data: data,
+	}
+}
@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ValidateStorageSecret checks that the storage secret of a snapshot storage exists in namespace and holds the
+// credentials its provider needs. Local storage needs no secret.
+func ValidateStorageSecret(client kubernetes.Interface, spec api.SnapshotStorageSpec, namespace string) error {
+	if spec.Local != nil {
+		return nil
+	}
+	secret, err := client.CoreV1().Secrets(namespace).Get(spec.StorageSecretName, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return fmt.Errorf(`storage secret "%s" not found in namespace "%s"`, spec.StorageSecretName, namespace)
+	} else if err != nil {
+		return err
+	}
+	has := func(key string) bool {
+		return len(secret.Data[key]) > 0
+	}
+	missing := func(provider string, keys ...string) error {
+		var names []string
+		for _, key := range keys {
+			if !has(key) {
+				names = append(names, key)
+			}
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		return fmt.Errorf(`storage secret "%s" is missing key %s required by %s`, secret.Name, strings.Join(names, ", "), provider)
+	}
+
+	switch {
+	case spec.S3 != nil:
+		// without an access key, KubeDB falls back to the IAM role of the node, which only exists on AWS
+		if !has(api.AWS_ACCESS_KEY_ID) && !has(api.AWS_SECRET_ACCESS_KEY) && strings.HasSuffix(spec.S3.Endpoint, ".amazonaws.com") {
+			return nil
+		}
+		return missing("s3", api.AWS_ACCESS_KEY_ID, api.AWS_SECRET_ACCESS_KEY)
+	case spec.GCS != nil:
+		if err := missing("gcs", api.GOOGLE_PROJECT_ID, api.GOOGLE_SERVICE_ACCOUNT_JSON_KEY); err != nil {
+			return err
+		}
+		if err := validateServiceAccountKey(secret.Data[api.GOOGLE_SERVICE_ACCOUNT_JSON_KEY]); err != nil {
+			return fmt.Errorf(`key %s of storage secret "%s" %v`, api.GOOGLE_SERVICE_ACCOUNT_JSON_KEY, secret.Name, err)
+		}
+	case spec.Azure != nil:
+		return missing("azure", api.AZURE_ACCOUNT_NAME, api.AZURE_ACCOUNT_KEY)
+	case spec.Swift != nil:
+		// Swift supports v1 auth, manual auth with a token, and keystone v2/v3 auth
+		switch {
+		case has(api.ST_AUTH) || has(api.ST_USER) || has(api.ST_KEY):
+			return missing("swift v1 auth", api.ST_AUTH, api.ST_USER, api.ST_KEY)
+		case has(api.OS_STORAGE_URL) || has(api.OS_AUTH_TOKEN):
+			return missing("swift manual auth", api.OS_STORAGE_URL, api.OS_AUTH_TOKEN)
+		default:
+			return missing("swift", api.OS_AUTH_URL, api.OS_USERNAME, api.OS_PASSWORD)
+		}
+	}
+	return nil
+}
+
+// validateServiceAccountKey checks that data is the JSON key of a Google Cloud service account.
+func validateServiceAccountKey(data []byte) error {
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return fmt.Errorf("is not a valid service account JSON key: %v", err)
+	}
+	if key.Type != "service_account" {
+		return fmt.Errorf(`has type "%s", but must be a "service_account" JSON key`, key.Type)
+	}
+	if key.ClientEmail == "" {
+		return fmt.Errorf("is missing field client_email")
+	}
+	if key.PrivateKey == "" {
+		return fmt.Errorf("is missing field private_key")
+	}
+	return nil
+}
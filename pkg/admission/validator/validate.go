@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"fmt"
+	"time"
+
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ValidateSnapshotSpec checks a snapshot storage and its secret, and that prober can access the bucket by deadline.
+func ValidateSnapshotSpec(client kubernetes.Interface, prober *bucket.Prober, spec api.SnapshotStorageSpec, namespace string, deadline time.Time) error {
+	// BucketName can't be empty
+	if spec.S3 == nil && spec.GCS == nil && spec.Azure == nil && spec.Swift == nil && spec.Local == nil {
+		return errors.New("no storage provider is configured")
+	}
+
+	if spec.Local != nil {
+		return nil
+	}
+
+	// Need to provide Storage credential secret
+	if spec.StorageSecretName == "" {
+		return fmt.Errorf(`object 'SecretName' is missing in '%v'`, spec)
+	}
+
+	if err := ValidateStorageSecret(client, spec, namespace); err != nil {
+		return err
+	}
+
+	if err := prober.CheckBucketAccess(client, spec, namespace, deadline); err != nil {
+		return err
+	}
+
+	return nil
+}
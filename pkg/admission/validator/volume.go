@@ -0,0 +1,239 @@
+package validator
+
+import (
+	"fmt"
+
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	storage "k8s.io/api/storage/v1beta1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// EnvironmentKey is the namespace annotation naming the environment of the databases in the namespace
+	EnvironmentKey        = api.GenericKey + "/environment"
+	EnvironmentProduction = "production"
+
+	defaultStorageClassKey     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassKey = "storageclass.beta.kubernetes.io/is-default-class"
+
+	// noProvisioner is the provisioner of storage classes whose volumes are created by hand, eg: local volumes
+	noProvisioner = "kubernetes.io/no-provisioner"
+)
+
+// topologyKeys are the node labels that tie a pod to the place where a volume is provisioned
+var topologyKeys = []string{
+	"kubernetes.io/hostname",
+	"failure-domain.beta.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/region",
+}
+
+// ValidateStorage validates the PersistentVolumeClaimSpec the volumes of database pods are claimed with. Each
+// pod of a database gets its own claim, which the database writes to. The access modes are not checked when they
+// are not changed from oldSpec, so that a database created before the check can still be edited or resumed.
+func ValidateStorage(client kubernetes.Interface, spec, oldSpec *core.PersistentVolumeClaimSpec) error {
+	if spec == nil {
+		return nil
+	}
+
+	class, err := getStorageClass(client, spec)
+	if err != nil {
+		return err
+	}
+
+	if oldSpec == nil || !meta_util.Equal(oldSpec.AccessModes, spec.AccessModes) {
+		if err := validateAccessModes(spec.AccessModes); err != nil {
+			return err
+		}
+	}
+
+	if spec.VolumeMode != nil && *spec.VolumeMode != core.PersistentVolumeFilesystem {
+		return fmt.Errorf(`spec.storage.volumeMode "%s" invalid. Databases need a %s volume`, *spec.VolumeMode, core.PersistentVolumeFilesystem)
+	}
+
+	if val, found := spec.Resources.Requests[core.ResourceStorage]; found {
+		if val.Value() <= 0 {
+			return errors.New("invalid ResourceStorage request")
+		}
+	} else {
+		return errors.New("missing ResourceStorage request")
+	}
+
+	if spec.Selector != nil {
+		if errs := metav1validation.ValidateLabelSelector(spec.Selector, field.NewPath("spec", "storage", "selector")); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+	}
+
+	// claims with a selector, or of a storage class without provisioner, only bind to existing volumes
+	if spec.Selector != nil || (class != nil && class.Provisioner == noProvisioner) {
+		if err := checkExistingVolumes(client, spec, class); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAccessModes checks that access modes are known, and allow the database to write to its volume.
+// Access modes that are not set are defaulted to ReadWriteOnce by KubeDB operator.
+func validateAccessModes(modes []core.PersistentVolumeAccessMode) error {
+	if len(modes) == 0 {
+		return nil
+	}
+	writable := false
+	for _, mode := range modes {
+		switch mode {
+		case core.ReadWriteOnce, core.ReadWriteMany:
+			writable = true
+		case core.ReadOnlyMany:
+		default:
+			return fmt.Errorf(`spec.storage.accessModes "%s" invalid. Must be one of %s, %s or %s`, mode, core.ReadWriteOnce, core.ReadOnlyMany, core.ReadWriteMany)
+		}
+	}
+	if !writable {
+		return fmt.Errorf(`spec.storage.accessModes invalid. Databases write to their volumes, so %s or %s is required`, core.ReadWriteOnce, core.ReadWriteMany)
+	}
+	return nil
+}
+
+// checkExistingVolumes checks that a PersistentVolume exists for a claim that can't have its volume provisioned
+// dynamically.
+func checkExistingVolumes(client kubernetes.Interface, spec *core.PersistentVolumeClaimSpec, class *storage.StorageClass) error {
+	selector := labels.Everything()
+	if spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(spec.Selector); err != nil {
+			return err
+		}
+	}
+	className := ""
+	if class != nil {
+		className = class.Name
+	}
+
+	volumes, err := client.CoreV1().PersistentVolumes().List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	for _, pv := range volumes.Items {
+		if pv.Spec.StorageClassName == className {
+			return nil
+		}
+	}
+	if spec.Selector != nil {
+		return fmt.Errorf(`no PersistentVolume of storage class "%s" matches spec.storage.selector. Volumes of a claim with a selector are not provisioned dynamically`, className)
+	}
+	return fmt.Errorf(`no PersistentVolume of storage class "%s" exists. Storage class "%s" doesn't provision volumes dynamically`, className, className)
+}
+
+// getStorageClass returns the storage class of a claim. If no storage class is named, the default storage class
+// is returned. It returns nil if the claim has no storage class.
+func getStorageClass(client kubernetes.Interface, spec *core.PersistentVolumeClaimSpec) (*storage.StorageClass, error) {
+	if spec.StorageClassName != nil {
+		if *spec.StorageClassName == "" {
+			return nil, nil
+		}
+		class, err := client.StorageV1beta1().StorageClasses().Get(*spec.StorageClassName, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			return nil, fmt.Errorf(`spec.storage.storageClassName "%v" not found`, *spec.StorageClassName)
+		}
+		return class, err
+	}
+
+	classes, err := client.StorageV1beta1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i, class := range classes.Items {
+		if class.Annotations[defaultStorageClassKey] == "true" || class.Annotations[betaDefaultStorageClassKey] == "true" {
+			return &classes.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckStorageBinding checks that the volumes of the storage class are bound where the database pods can run.
+// Volumes that are bound immediately, before the pods are scheduled, may be provisioned in a zone, or on a node,
+// the node selector or the required node affinity of the pods doesn't allow. On update, ie: when the database
+// before the update is given as oldObject, the check is skipped, since the storage and the node selector of a
+// database can't be changed.
+func CheckStorageBinding(client kubernetes.Interface, spec *core.PersistentVolumeClaimSpec, nodeSelector map[string]string, affinity *core.Affinity, oldObject runtime.Object) error {
+	if spec == nil || oldObject != nil {
+		return nil
+	}
+	class, err := getStorageClass(client, spec)
+	if err != nil || class == nil {
+		return err
+	}
+	if class.VolumeBindingMode != nil && *class.VolumeBindingMode == storage.VolumeBindingWaitForFirstConsumer {
+		return nil
+	}
+
+	if class.Provisioner == noProvisioner {
+		return fmt.Errorf(`storage class "%s" binds volumes immediately, so database pods may be bound to volumes on nodes they can't run on. Use a storage class with volumeBindingMode %s`,
+			class.Name, storage.VolumeBindingWaitForFirstConsumer)
+	}
+	if key := topologyConstraint(nodeSelector, affinity); key != "" {
+		return fmt.Errorf(`storage class "%s" binds volumes immediately, but database pods are constrained by node label %s, so volumes may be provisioned where the pods can't run. Use a storage class with volumeBindingMode %s`,
+			class.Name, key, storage.VolumeBindingWaitForFirstConsumer)
+	}
+	return nil
+}
+
+// topologyConstraint returns the first topology key the node selector or the required node affinity constrains.
+func topologyConstraint(nodeSelector map[string]string, affinity *core.Affinity) string {
+	keys := sets.StringKeySet(nodeSelector)
+	if affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, req := range term.MatchExpressions {
+				keys.Insert(req.Key)
+			}
+		}
+	}
+	for _, key := range topologyKeys {
+		if keys.Has(key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// CheckReclaimPolicy checks that the volumes of a database in a production namespace are kept when their claims
+// are deleted. A namespace is a production namespace if its annotation EnvironmentKey is "production". On update,
+// ie: when the database before the update is given as oldObject, the check is skipped, since the storage of a
+// database can't be changed.
+func CheckReclaimPolicy(client kubernetes.Interface, spec *core.PersistentVolumeClaimSpec, namespace string, oldObject runtime.Object) error {
+	if spec == nil || oldObject != nil {
+		return nil
+	}
+	ns, err := client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ns.Annotations[EnvironmentKey] != EnvironmentProduction {
+		return nil
+	}
+
+	class, err := getStorageClass(client, spec)
+	if err != nil || class == nil {
+		return err
+	}
+	// storage classes without reclaim policy delete their volumes
+	if class.ReclaimPolicy == nil || *class.ReclaimPolicy == core.PersistentVolumeReclaimDelete {
+		return fmt.Errorf(`storage class "%s" deletes volumes when their claims are deleted. Use a storage class with reclaimPolicy %s to keep the data of a production database`,
+			class.Name, core.PersistentVolumeReclaimRetain)
+	}
+	return nil
+}
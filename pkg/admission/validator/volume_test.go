@@ -0,0 +1,353 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/appscode/go/types"
+	core "k8s.io/api/core/v1"
+	storage "k8s.io/api/storage/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func volumeObjects() []runtime.Object {
+	return []runtime.Object{
+		storageClass("standard", "kubernetes.io/gce-pd", nil, nil, true),
+		storageClass("retain", "kubernetes.io/gce-pd", reclaimPolicy(core.PersistentVolumeReclaimRetain), bindingMode(storage.VolumeBindingWaitForFirstConsumer), false),
+		storageClass("local", noProvisioner, nil, bindingMode(storage.VolumeBindingWaitForFirstConsumer), false),
+		storageClass("local-immediate", noProvisioner, nil, bindingMode(storage.VolumeBindingImmediate), false),
+		persistentVolume("local-1", "local", map[string]string{"disk": "ssd"}),
+		persistentVolume("standard-1", "standard", map[string]string{"tier": "db"}),
+		&core.Namespace{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: "prod",
+				Annotations: map[string]string{
+					EnvironmentKey: EnvironmentProduction,
+				},
+			},
+		},
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	for _, c := range storageCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(volumeObjects()...)
+			err := ValidateStorage(client, c.spec, c.oldSpec)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var storageCases = []struct {
+	testName string
+	spec     *core.PersistentVolumeClaimSpec
+	oldSpec  *core.PersistentVolumeClaimSpec
+	result   bool
+}{
+	{"Storage class",
+		claim("standard"),
+		nil,
+		true,
+	},
+	{"Default storage class",
+		claim(""),
+		nil,
+		true,
+	},
+	{"Unknown storage class",
+		claim("unknown"),
+		nil,
+		false,
+	},
+	{"Missing storage request",
+		editClaimRequest(claim("standard"), ""),
+		nil,
+		false,
+	},
+	{"Zero storage request",
+		editClaimRequest(claim("standard"), "0"),
+		nil,
+		false,
+	},
+	{"ReadWriteOnce access mode",
+		editClaimAccessModes(claim("standard"), core.ReadWriteOnce),
+		nil,
+		true,
+	},
+	{"Unknown access mode",
+		editClaimAccessModes(claim("standard"), "ReadWriteSometimes"),
+		nil,
+		false,
+	},
+	{"ReadOnlyMany access mode",
+		editClaimAccessModes(claim("standard"), core.ReadOnlyMany),
+		nil,
+		false,
+	},
+	{"Unchanged ReadOnlyMany access mode",
+		editClaimAccessModes(claim("standard"), core.ReadOnlyMany),
+		editClaimAccessModes(claim("standard"), core.ReadOnlyMany),
+		true,
+	},
+	{"Changed to ReadOnlyMany access mode",
+		editClaimAccessModes(claim("standard"), core.ReadOnlyMany),
+		editClaimAccessModes(claim("standard"), core.ReadWriteOnce),
+		false,
+	},
+	{"Filesystem volume mode",
+		editClaimVolumeMode(claim("standard"), core.PersistentVolumeFilesystem),
+		nil,
+		true,
+	},
+	{"Block volume mode",
+		editClaimVolumeMode(claim("standard"), core.PersistentVolumeBlock),
+		nil,
+		false,
+	},
+	{"Selector matching volume",
+		editClaimSelector(claim("standard"), metaV1.LabelSelectorOpIn, "tier", "db"),
+		nil,
+		true,
+	},
+	{"Selector matching no volume",
+		editClaimSelector(claim("standard"), metaV1.LabelSelectorOpIn, "tier", "cache"),
+		nil,
+		false,
+	},
+	{"Selector matching volume of other storage class",
+		editClaimSelector(claim("retain"), metaV1.LabelSelectorOpIn, "tier", "db"),
+		nil,
+		false,
+	},
+	{"Invalid selector",
+		editClaimSelector(claim("standard"), "Near", "tier", "db"),
+		nil,
+		false,
+	},
+	{"Storage class without provisioner",
+		claim("local"),
+		nil,
+		true,
+	},
+	{"Storage class without provisioner and volumes",
+		editClaimSelector(claim("local"), metaV1.LabelSelectorOpIn, "disk", "hdd"),
+		nil,
+		false,
+	},
+}
+
+func TestCheckStorageBinding(t *testing.T) {
+	for _, c := range bindingCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(volumeObjects()...)
+			err := CheckStorageBinding(client, c.spec, c.nodeSelector, c.affinity, c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var bindingCases = []struct {
+	testName     string
+	spec         *core.PersistentVolumeClaimSpec
+	nodeSelector map[string]string
+	affinity     *core.Affinity
+	oldObject    runtime.Object
+	result       bool
+}{
+	{"Immediate binding without constraints",
+		claim("standard"),
+		nil,
+		nil,
+		nil,
+		true,
+	},
+	{"Immediate binding with zone node selector",
+		claim("standard"),
+		map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-central1-a"},
+		nil,
+		nil,
+		false,
+	},
+	{"Immediate binding of default storage class with zone node affinity",
+		claim(""),
+		nil,
+		nodeAffinity(core.NodeSelectorRequirement{Key: "failure-domain.beta.kubernetes.io/zone", Operator: core.NodeSelectorOpIn, Values: []string{"us-central1-a"}}),
+		nil,
+		false,
+	},
+	{"Immediate binding with other node selector",
+		claim("standard"),
+		map[string]string{"disktype": "ssd"},
+		nil,
+		nil,
+		true,
+	},
+	{"Delayed binding with zone node selector",
+		claim("retain"),
+		map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-central1-a"},
+		nil,
+		nil,
+		true,
+	},
+	{"Immediate binding of local volumes",
+		claim("local-immediate"),
+		nil,
+		nil,
+		nil,
+		false,
+	},
+	{"Delayed binding of local volumes",
+		claim("local"),
+		map[string]string{"kubernetes.io/hostname": "node-1"},
+		nil,
+		nil,
+		true,
+	},
+	{"Immediate binding with zone node selector on update",
+		claim("standard"),
+		map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-central1-a"},
+		nil,
+		samplePostgres(),
+		true,
+	},
+}
+
+func TestCheckReclaimPolicy(t *testing.T) {
+	for _, c := range reclaimCases {
+		t.Run(c.testName, func(t *testing.T) {
+			client := fake.NewSimpleClientset(volumeObjects()...)
+			err := CheckReclaimPolicy(client, c.spec, c.namespace, c.oldObject)
+			if c.result != (err == nil) {
+				t.Errorf("expected success: %v, but got error: %v", c.result, err)
+			}
+		})
+	}
+}
+
+var reclaimCases = []struct {
+	testName  string
+	spec      *core.PersistentVolumeClaimSpec
+	namespace string
+	oldObject runtime.Object
+	result    bool
+}{
+	{"Delete reclaim policy",
+		claim("standard"),
+		"default",
+		nil,
+		true,
+	},
+	{"Delete reclaim policy in production",
+		claim("standard"),
+		"prod",
+		nil,
+		false,
+	},
+	{"Retain reclaim policy in production",
+		claim("retain"),
+		"prod",
+		nil,
+		true,
+	},
+	{"No storage class in production",
+		claim("-"),
+		"prod",
+		nil,
+		true,
+	},
+	{"Delete reclaim policy in production on update",
+		claim("standard"),
+		"prod",
+		samplePostgres(),
+		true,
+	},
+}
+
+// claim returns a claim of the storage class. An empty class means the default storage class, and "-" means
+// no storage class.
+func claim(class string) *core.PersistentVolumeClaimSpec {
+	spec := &core.PersistentVolumeClaimSpec{
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	switch class {
+	case "":
+	case "-":
+		spec.StorageClassName = types.StringP("")
+	default:
+		spec.StorageClassName = types.StringP(class)
+	}
+	return spec
+}
+
+func editClaimRequest(old *core.PersistentVolumeClaimSpec, request string) *core.PersistentVolumeClaimSpec {
+	old.Resources.Requests = core.ResourceList{}
+	if request != "" {
+		old.Resources.Requests[core.ResourceStorage] = resource.MustParse(request)
+	}
+	return old
+}
+
+func editClaimAccessModes(old *core.PersistentVolumeClaimSpec, modes ...core.PersistentVolumeAccessMode) *core.PersistentVolumeClaimSpec {
+	old.AccessModes = modes
+	return old
+}
+
+func editClaimVolumeMode(old *core.PersistentVolumeClaimSpec, mode core.PersistentVolumeMode) *core.PersistentVolumeClaimSpec {
+	old.VolumeMode = &mode
+	return old
+}
+
+func editClaimSelector(old *core.PersistentVolumeClaimSpec, operator metaV1.LabelSelectorOperator, key, value string) *core.PersistentVolumeClaimSpec {
+	old.Selector = &metaV1.LabelSelector{
+		MatchExpressions: []metaV1.LabelSelectorRequirement{
+			{Key: key, Operator: operator, Values: []string{value}},
+		},
+	}
+	return old
+}
+
+func storageClass(name, provisioner string, reclaim *core.PersistentVolumeReclaimPolicy, binding *storage.VolumeBindingMode, isDefault bool) *storage.StorageClass {
+	class := &storage.StorageClass{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Provisioner:       provisioner,
+		ReclaimPolicy:     reclaim,
+		VolumeBindingMode: binding,
+	}
+	if isDefault {
+		class.Annotations = map[string]string{defaultStorageClassKey: "true"}
+	}
+	return class
+}
+
+func reclaimPolicy(policy core.PersistentVolumeReclaimPolicy) *core.PersistentVolumeReclaimPolicy {
+	return &policy
+}
+
+func bindingMode(mode storage.VolumeBindingMode) *storage.VolumeBindingMode {
+	return &mode
+}
+
+func persistentVolume(name, class string, labels map[string]string) *core.PersistentVolume {
+	return &core.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: core.PersistentVolumeSpec{
+			StorageClassName: class,
+		},
+	}
+}
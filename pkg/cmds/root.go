@@ -10,15 +10,19 @@ import (
 	"github.com/appscode/kutil/tools/analytics"
 	"github.com/jpillora/go-ogle-analytics"
 	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
-	"github.com/kubedb/apimachinery/pkg/admission/dormantdatabase"
-	"github.com/kubedb/apimachinery/pkg/admission/snapshot"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/dormantdatabase"
 	"github.com/kubedb/kubedb-server/pkg/admission/plugin/elasticsearch"
 	"github.com/kubedb/kubedb-server/pkg/admission/plugin/memcached"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/mongodb"
 	"github.com/kubedb/kubedb-server/pkg/admission/plugin/mysql"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/offshoot"
 	"github.com/kubedb/kubedb-server/pkg/admission/plugin/postgres"
 	"github.com/kubedb/kubedb-server/pkg/admission/plugin/redis"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/snapshot"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/status"
 	"github.com/kubedb/kubedb-server/pkg/cmds/server"
-	mgAdmsn "github.com/kubedb/mongodb/pkg/admission"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	genericapiserver "k8s.io/apiserver/pkg/server"
@@ -60,16 +64,30 @@ func NewRootCmd(version string) *cobra.Command {
 	rootCmd.AddCommand(v.NewCmdVersion())
 
 	stopCh := genericapiserver.SetupSignalHandler()
-	cmd := server.NewCommandStartAdmissionServer(os.Stdout, os.Stderr, stopCh,
-		&elasticsearch.ElasticsearchValidator{},
-		&memcached.MemcachedValidator{},
-		&mgAdmsn.MongoDBValidator{},
-		&mgAdmsn.MongoDBMutator{},
-		&mysql.MySQLValidator{},
-		&postgres.PostgresValidator{},
-		&redis.RedisValidator{},
-		&snapshot.SnapshotValidator{},
-		&dormantdatabase.DormantDatabaseValidator{},
+	// the admission config is populated from the flags of the run command before the hooks are initialized
+	admissionConfig := config.New()
+	prober := bucket.New(admissionConfig)
+	cmd := server.NewCommandStartAdmissionServer(os.Stdout, os.Stderr, stopCh, admissionConfig,
+		elasticsearch.NewElasticsearchValidator(admissionConfig, prober),
+		&elasticsearch.ElasticsearchMutator{},
+		memcached.NewMemcachedValidator(admissionConfig),
+		memcached.NewMemcachedMutator(admissionConfig),
+		mongodb.NewMongoDBValidator(admissionConfig, prober),
+		&mongodb.MongoDBMutator{},
+		mysql.NewMySQLValidator(admissionConfig, prober),
+		&mysql.MySQLMutator{},
+		postgres.NewPostgresValidator(admissionConfig, prober),
+		&postgres.PostgresMutator{},
+		redis.NewRedisValidator(admissionConfig),
+		&redis.RedisMutator{},
+		snapshot.NewSnapshotValidator(admissionConfig, prober),
+		snapshot.NewSnapshotMutator(admissionConfig),
+		dormantdatabase.NewDormantDatabaseValidator(admissionConfig),
+		status.NewStatusValidator(admissionConfig),
+		offshoot.NewPersistentVolumeClaimValidator(admissionConfig),
+		offshoot.NewSecretValidator(admissionConfig),
+		offshoot.NewStatefulSetValidator(admissionConfig),
+		offshoot.NewConfigMapValidator(admissionConfig),
 	)
 	cmd.Use = "run"
 	cmd.Long = "Launch KubeDB server"
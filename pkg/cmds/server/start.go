@@ -5,6 +5,7 @@ import (
 	"net"
 
 	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
 	"github.com/kubedb/kubedb-server/pkg/server"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -17,6 +18,7 @@ const defaultEtcdPathPrefix = "/registry/online.openshift.io"
 
 type AdmissionServerOptions struct {
 	RecommendedOptions *genericoptions.RecommendedOptions
+	AdmissionConfig    *config.Config
 
 	AdmissionHooks []hookapi.AdmissionHook
 
@@ -24,10 +26,11 @@ type AdmissionServerOptions struct {
 	StdErr io.Writer
 }
 
-func NewAdmissionServerOptions(out, errOut io.Writer, admissionHooks ...hookapi.AdmissionHook) *AdmissionServerOptions {
+func NewAdmissionServerOptions(out, errOut io.Writer, admissionConfig *config.Config, admissionHooks ...hookapi.AdmissionHook) *AdmissionServerOptions {
 	o := &AdmissionServerOptions{
 		// TODO we will nil out the etcd storage options.  This requires a later level of k8s.io/apiserver
 		RecommendedOptions: genericoptions.NewRecommendedOptions(defaultEtcdPathPrefix, server.Codecs.LegacyCodec(admissionv1beta1.SchemeGroupVersion)),
+		AdmissionConfig:    admissionConfig,
 
 		AdmissionHooks: admissionHooks,
 
@@ -40,8 +43,8 @@ func NewAdmissionServerOptions(out, errOut io.Writer, admissionHooks ...hookapi.
 }
 
 // NewCommandStartMaster provides a CLI handler for 'start master' command
-func NewCommandStartAdmissionServer(out, errOut io.Writer, stopCh <-chan struct{}, admissionHooks ...hookapi.AdmissionHook) *cobra.Command {
-	o := NewAdmissionServerOptions(out, errOut, admissionHooks...)
+func NewCommandStartAdmissionServer(out, errOut io.Writer, stopCh <-chan struct{}, admissionConfig *config.Config, admissionHooks ...hookapi.AdmissionHook) *cobra.Command {
+	o := NewAdmissionServerOptions(out, errOut, admissionConfig, admissionHooks...)
 
 	cmd := &cobra.Command{
 		Short: "Launch a namespace reservation API server",
@@ -62,12 +65,13 @@ func NewCommandStartAdmissionServer(out, errOut io.Writer, stopCh <-chan struct{
 
 	flags := cmd.Flags()
 	o.RecommendedOptions.AddFlags(flags)
+	o.AdmissionConfig.AddFlags(flags)
 
 	return cmd
 }
 
 func (o AdmissionServerOptions) Validate(args []string) error {
-	return nil
+	return o.AdmissionConfig.Validate()
 }
 
 func (o *AdmissionServerOptions) Complete() error {
@@ -85,6 +89,11 @@ func (o AdmissionServerOptions) Config() (*server.Config, error) {
 		return nil, err
 	}
 
+	// Admission hooks read several objects per review. At the default client rate limit of 5 QPS, these reads
+	// alone could take most of the webhook timeout, leaving no time for bucket access checks.
+	serverConfig.ClientConfig.QPS = 100
+	serverConfig.ClientConfig.Burst = 200
+
 	config := &server.Config{
 		GenericConfig: serverConfig,
 		ExtraConfig: server.ExtraConfig{
@@ -0,0 +1,430 @@
+package e2e_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appscode/go/types"
+	meta_util "github.com/appscode/kutil/meta"
+	api "github.com/kubedb/apimachinery/apis/kubedb/v1alpha1"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/snapshot"
+	amv "github.com/kubedb/kubedb-server/pkg/admission/validator"
+	"github.com/kubedb/kubedb-server/test/e2e/framework"
+	admission "k8s.io/api/admission/v1beta1"
+	core "k8s.io/api/core/v1"
+	storageV1beta1 "k8s.io/api/storage/v1beta1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPostgresReview(t *testing.T) {
+	createStorageClass(t, "standard")
+	root.ObjectStore.CreateBucket("pg-backup", "pg-access-key")
+	createStorageSecret(t, "pg-backup", "pg-access-key")
+	createStorageSecret(t, "pg-backup-wrong", "wrong-access-key")
+
+	for _, c := range postgresCases() {
+		t.Run(c.testName, func(t *testing.T) {
+			puts := root.ObjectStore.Requests("PUT")
+
+			start := time.Now()
+			resp, err := root.Review("postgresreviews", newRequest(api.ResourceKindPostgres, admission.Create, c.object))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Allowed != c.result {
+				t.Errorf("expected allowed: %v, but got: %v, result: %v", c.result, resp.Allowed, resp.Result)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("review took %v", elapsed)
+			}
+			if writes := root.ObjectStore.Requests("PUT") - puts; writes != 0 {
+				t.Errorf("read-only bucket check wrote %v objects", writes)
+			}
+		})
+	}
+	if root.ObjectStore.Requests("GET") == 0 {
+		t.Errorf("bucket access was not checked against the object store")
+	}
+}
+
+func TestReviewAuthorization(t *testing.T) {
+	req := newRequest(api.ResourceKindPostgres, admission.Create, samplePostgres("auth"))
+	if _, err := root.Review("postgresreviews", req); err != nil {
+		t.Errorf("expected review as %s to be authorized, but got: %v", framework.RequesterUser, err)
+	}
+	if _, err := root.ReviewAs("alice", "postgresreviews", req); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected review as alice to be forbidden, but got: %v", err)
+	}
+}
+
+// the cases are built by a function, because the storage specs point to the object store of the running framework
+func postgresCases() []struct {
+	testName string
+	object   runtime.Object
+	result   bool
+} {
+	return []struct {
+		testName string
+		object   runtime.Object
+		result   bool
+	}{
+		{"Create Valid Postgres",
+			samplePostgres("foo"),
+			true,
+		},
+		{"Create Postgres with missing StorageClass",
+			editStorageClass(samplePostgres("bar"), "unknown"),
+			false,
+		},
+		{"Create Postgres with default StorageClass",
+			removeStorageClass(samplePostgres("corge")),
+			true,
+		},
+		{"Create Postgres with accessible backup bucket",
+			editBackupSchedule(samplePostgres("baz"), "pg-backup", "pg-backup"),
+			true,
+		},
+		{"Create Postgres with wrong bucket credentials",
+			editBackupSchedule(samplePostgres("qux"), "pg-backup-wrong", "pg-backup"),
+			false,
+		},
+		{"Create Postgres with missing bucket",
+			editBackupSchedule(samplePostgres("quux"), "pg-backup", "unknown"),
+			false,
+		},
+	}
+}
+
+func TestPostgresReviewSlowBucket(t *testing.T) {
+	createStorageClass(t, "standard")
+	root.ObjectStore.CreateBucket("pg-slow", "pg-slow-access-key")
+	createStorageSecret(t, "pg-slow", "pg-slow-access-key")
+
+	root.ObjectStore.SetDelay(5 * time.Second)
+	defer root.ObjectStore.SetDelay(0)
+
+	start := time.Now()
+	resp, err := root.Review("postgresreviews", newRequest(api.ResourceKindPostgres, admission.Create,
+		editBackupSchedule(samplePostgres("slow"), "pg-slow", "pg-slow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Allowed {
+		t.Errorf("expected slow bucket to be denied")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected bucket check to time out after %v, but review took %v", time.Second, elapsed)
+	}
+}
+
+func TestSnapshotReview(t *testing.T) {
+	root.ObjectStore.CreateBucket("snapshot", "snapshot-access-key")
+	createStorageSecret(t, "snapshot", "snapshot-access-key")
+
+	db := samplePostgres("snapshot-db")
+	db.Status.Phase = api.DatabasePhaseRunning
+	if _, err := root.ExtClient.KubedbV1alpha1().Postgreses(db.Namespace).Create(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range snapshotCases() {
+		t.Run(c.testName, func(t *testing.T) {
+			if c.running != nil {
+				if _, err := root.ExtClient.KubedbV1alpha1().Snapshots(c.running.Namespace).Create(c.running); err != nil {
+					t.Fatal(err)
+				}
+				defer root.ExtClient.KubedbV1alpha1().Snapshots(c.running.Namespace).Delete(c.running.Name, nil)
+			}
+
+			resp, err := root.Review("snapshotreviews", newRequest(api.ResourceKindSnapshot, admission.Create, c.object))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Allowed != c.result {
+				t.Errorf("expected allowed: %v, but got: %v, result: %v", c.result, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
+
+// TestSnapshotReviewConcurrent reviews Snapshots of the same database concurrently. kube-apiserver doesn't
+// serialize admission, so only the lock ConfigMap of the database lets one of them through.
+func TestSnapshotReviewConcurrent(t *testing.T) {
+	root.ObjectStore.CreateBucket("snapshot-lock", "snapshot-access-key")
+	createStorageSecret(t, "snapshot", "snapshot-access-key")
+
+	db := samplePostgres("lock-db")
+	db.Status.Phase = api.DatabasePhaseRunning
+	db, err := root.ExtClient.KubedbV1alpha1().Postgreses(db.Namespace).Create(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	allowed := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			resp, err := root.Review("snapshotreviews", newRequest(api.ResourceKindSnapshot, admission.Create,
+				sampleSnapshot(name, "lock-db", "snapshot-lock")))
+			if err != nil {
+				t.Error(err)
+			} else if resp.Allowed {
+				allowed <- name
+			}
+		}(fmt.Sprintf("lock-%d", i))
+	}
+	wg.Wait()
+	close(allowed)
+
+	var names []string
+	for name := range allowed {
+		names = append(names, name)
+	}
+	if len(names) != 1 {
+		t.Fatalf("expected one of %d concurrent Snapshots to be allowed, but got: %v", n, names)
+	}
+	lock, err := root.KubeClient.CoreV1().ConfigMaps(db.Namespace).Get(amv.SnapshotLockName(api.ResourceKindPostgres, db.Name), metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(lock.Annotations[snapshot.LockKey], names[0]) {
+		t.Errorf("expected lock held by %s, but got: %v", names[0], lock.Annotations)
+	}
+	if current, err := root.ExtClient.KubedbV1alpha1().Postgreses(db.Namespace).Get(db.Name, metaV1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	} else if current.ResourceVersion != db.ResourceVersion {
+		t.Errorf("expected the database to be unchanged by locking")
+	}
+
+	// the allowed Snapshot is created, but not labeled as running by KubeDB operator yet
+	first := sampleSnapshot(names[0], "lock-db", "snapshot-lock")
+	if first, err := root.ExtClient.KubedbV1alpha1().Snapshots(first.Namespace).Create(first); err != nil {
+		t.Fatal(err)
+	} else {
+		defer root.ExtClient.KubedbV1alpha1().Snapshots(first.Namespace).Delete(first.Name, nil)
+	}
+	// wait until a lock whose holder doesn't exist would be stale
+	time.Sleep(admissionConfig.WebhookTimeout)
+
+	resp, err := root.Review("snapshotreviews", newRequest(api.ResourceKindSnapshot, admission.Create,
+		sampleSnapshot("lock-pending", "lock-db", "snapshot-lock")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Allowed {
+		t.Errorf("expected Snapshot to be denied while %s is pending", names[0])
+	}
+
+	// KubeDB operator completes the first Snapshot
+	first, err = root.ExtClient.KubedbV1alpha1().Snapshots(first.Namespace).Get(first.Name, metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Status.Phase = api.SnapshotPhaseSucceeded
+	if _, err := root.ExtClient.KubedbV1alpha1().Snapshots(first.Namespace).Update(first); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = root.Review("snapshotreviews", newRequest(api.ResourceKindSnapshot, admission.Create,
+		sampleSnapshot("lock-next", "lock-db", "snapshot-lock")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Allowed {
+		t.Errorf("expected Snapshot to be allowed after %s succeeded, but got result: %v", names[0], resp.Result)
+	}
+}
+
+func snapshotCases() []struct {
+	testName string
+	object   *api.Snapshot
+	running  *api.Snapshot
+	result   bool
+} {
+	return []struct {
+		testName string
+		object   *api.Snapshot
+		running  *api.Snapshot
+		result   bool
+	}{
+		{"Create Valid Snapshot",
+			sampleSnapshot("first", "snapshot-db", "snapshot"),
+			nil,
+			true,
+		},
+		{"Create Snapshot of missing database",
+			sampleSnapshot("second", "unknown", "snapshot"),
+			nil,
+			false,
+		},
+		{"Create Snapshot with inaccessible bucket",
+			sampleSnapshot("third", "snapshot-db", "unknown"),
+			nil,
+			false,
+		},
+		{"Create Snapshot while another Snapshot is running",
+			sampleSnapshot("fourth", "snapshot-db", "snapshot"),
+			editSnapshotRunning(sampleSnapshot("running", "snapshot-db", "snapshot")),
+			false,
+		},
+	}
+}
+
+func newRequest(kind string, operation admission.Operation, obj runtime.Object) *admission.AdmissionRequest {
+	objJS, err := meta_util.MarshalToJson(obj, api.SchemeGroupVersion)
+	if err != nil {
+		panic(err)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		panic(err)
+	}
+
+	req := new(admission.AdmissionRequest)
+	req.Kind = metaV1.GroupVersionKind{
+		Group:   api.SchemeGroupVersion.Group,
+		Version: api.SchemeGroupVersion.Version,
+		Kind:    kind,
+	}
+	req.Name = accessor.GetName()
+	req.Namespace = accessor.GetNamespace()
+	req.Operation = operation
+	req.Object.Raw = objJS
+	return req
+}
+
+// createStorageClass creates a storage class of local volumes, and a volume of it.
+func createStorageClass(t *testing.T, name string) {
+	bindingMode := storageV1beta1.VolumeBindingWaitForFirstConsumer
+	_, err := root.KubeClient.StorageV1beta1().StorageClasses().Create(&storageV1beta1.StorageClass{
+		ObjectMeta:        metaV1.ObjectMeta{Name: name},
+		Provisioner:       "kubernetes.io/no-provisioner",
+		VolumeBindingMode: &bindingMode,
+	})
+	if err != nil && !kerr.IsAlreadyExists(err) {
+		t.Fatal(err)
+	}
+	_, err = root.KubeClient.CoreV1().PersistentVolumes().Create(&core.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{Name: name + "-1"},
+		Spec: core.PersistentVolumeSpec{
+			StorageClassName: name,
+			AccessModes:      []core.PersistentVolumeAccessMode{core.ReadWriteOnce},
+			Capacity: core.ResourceList{
+				core.ResourceStorage: resource.MustParse("1Gi"),
+			},
+			PersistentVolumeSource: core.PersistentVolumeSource{
+				Local: &core.LocalVolumeSource{Path: "/mnt/disks/" + name},
+			},
+		},
+	})
+	if err != nil && !kerr.IsAlreadyExists(err) {
+		t.Fatal(err)
+	}
+}
+
+func createStorageSecret(t *testing.T, name, accessKey string) {
+	_, err := root.KubeClient.CoreV1().Secrets("default").Create(&core.Secret{
+		ObjectMeta: metaV1.ObjectMeta{Name: name},
+		Data: map[string][]byte{
+			api.AWS_ACCESS_KEY_ID:     []byte(accessKey),
+			api.AWS_SECRET_ACCESS_KEY: []byte("secret-key"),
+		},
+	})
+	if err != nil && !kerr.IsAlreadyExists(err) {
+		t.Fatal(err)
+	}
+}
+
+func samplePostgres(name string) *api.Postgres {
+	return &api.Postgres{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindPostgres,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Spec: api.PostgresSpec{
+			Version: "9.6",
+			Storage: &core.PersistentVolumeClaimSpec{
+				StorageClassName: types.StringP("standard"),
+				Resources: core.ResourceRequirements{
+					Requests: core.ResourceList{
+						core.ResourceStorage: resource.MustParse("100Mi"),
+					},
+				},
+			},
+		},
+	}
+}
+
+func editStorageClass(old *api.Postgres, storageClass string) *api.Postgres {
+	old.Spec.Storage.StorageClassName = types.StringP(storageClass)
+	return old
+}
+
+// removeStorageClass leaves the storage class of old to the default storage class.
+func removeStorageClass(old *api.Postgres) *api.Postgres {
+	old.Spec.Storage.StorageClassName = nil
+	return old
+}
+
+func editBackupSchedule(old *api.Postgres, secretName, bucket string) *api.Postgres {
+	old.Spec.BackupSchedule = &api.BackupScheduleSpec{
+		CronExpression:      "@every 6h",
+		SnapshotStorageSpec: sampleStorageSpec(secretName, bucket),
+	}
+	return old
+}
+
+func sampleSnapshot(name, databaseName, bucket string) *api.Snapshot {
+	return &api.Snapshot{
+		TypeMeta: metaV1.TypeMeta{
+			Kind:       api.ResourceKindSnapshot,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				api.LabelDatabaseKind: api.ResourceKindPostgres,
+			},
+		},
+		Spec: api.SnapshotSpec{
+			DatabaseName:        databaseName,
+			SnapshotStorageSpec: sampleStorageSpec("snapshot", bucket),
+		},
+	}
+}
+
+func editSnapshotRunning(old *api.Snapshot) *api.Snapshot {
+	old.Labels[api.LabelDatabaseName] = old.Spec.DatabaseName
+	old.Labels[api.LabelSnapshotStatus] = string(api.SnapshotPhaseRunning)
+	old.Status.Phase = api.SnapshotPhaseRunning
+	return old
+}
+
+func sampleStorageSpec(secretName, bucket string) api.SnapshotStorageSpec {
+	return api.SnapshotStorageSpec{
+		StorageSecretName: secretName,
+		S3: &api.S3Spec{
+			Endpoint: root.ObjectStore.URL,
+			Bucket:   bucket,
+			Prefix:   "demo",
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package e2e_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	"github.com/kubedb/kubedb-server/pkg/admission/bucket"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/postgres"
+	"github.com/kubedb/kubedb-server/pkg/admission/plugin/snapshot"
+	"github.com/kubedb/kubedb-server/test/e2e/framework"
+	clientSetScheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func init() {
+	scheme.AddToScheme(clientSetScheme.Scheme)
+}
+
+var (
+	root            *framework.Framework
+	admissionConfig = config.New()
+)
+
+func TestMain(m *testing.M) {
+	// keep the bucket access checks short, so that slow buckets fail fast
+	admissionConfig.WebhookTimeout = 2 * time.Second
+	prober := bucket.New(admissionConfig)
+
+	var err error
+	root, err = framework.New(admissionConfig,
+		postgres.NewPostgresValidator(admissionConfig, prober),
+		snapshot.NewSnapshotValidator(admissionConfig, prober),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start kubedb-server:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	// requests of the admission hooks that the deployed RBAC rules don't allow
+	if forbidden := root.APIServer.Forbidden(); len(forbidden) != 0 {
+		fmt.Fprintln(os.Stderr, "kubedb-server was denied by RBAC:", forbidden)
+		code = 1
+	}
+	root.Stop()
+	os.Exit(code)
+}
@@ -0,0 +1,324 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kubedb/apimachinery/client/clientset/versioned/scheme"
+	authorization "k8s.io/api/authorization/v1beta1"
+	rbac "k8s.io/api/rbac/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/testing"
+)
+
+// APIServer is a stand-in for kube-apiserver. It serves get, list, create, update and delete requests
+// for the Kubernetes and KubeDB resources from memory. Updates are rejected with a conflict when the
+// resourceVersion of the object is stale, like kube-apiserver does. Requests of a restricted user are
+// authorized against the RBAC rules of the user; other requests are trusted. SubjectAccessReviews are answered
+// from the same rules, so that kubedb-server can delegate the authorization of admission reviews to it.
+type APIServer struct {
+	*httptest.Server
+
+	scheme    *runtime.Scheme
+	tracker   testing.ObjectTracker
+	resources map[schema.GroupVersionResource]schema.GroupVersionKind
+
+	lock            sync.Mutex
+	resourceVersion int
+	rules           map[string][]rbac.PolicyRule
+	forbidden       []string
+}
+
+func NewAPIServer() *APIServer {
+	s := &APIServer{
+		scheme:    runtime.NewScheme(),
+		resources: map[schema.GroupVersionResource]schema.GroupVersionKind{},
+		rules:     map[string][]rbac.PolicyRule{},
+	}
+	clientsetscheme.AddToScheme(s.scheme)
+	scheme.AddToScheme(s.scheme)
+	s.tracker = testing.NewObjectTracker(s.scheme, serializer.NewCodecFactory(s.scheme).UniversalDecoder())
+
+	for gvk := range s.scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal || strings.HasSuffix(gvk.Kind, "List") {
+			continue
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		s.resources[gvr] = gvk
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Restrict authorizes the requests of user against rules. Users are identified by the Impersonate-User
+// header, since client-go sends no credentials to a server without TLS.
+func (s *APIServer) Restrict(user string, rules []rbac.PolicyRule) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rules[user] = rules
+}
+
+// Forbidden returns the requests that were denied by RBAC, eg: "list storageclasses.storage.k8s.io".
+func (s *APIServer) Forbidden() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.forbidden...)
+}
+
+type request struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// parsePath parses the path of resource requests, eg: /api/v1/namespaces/default/secrets/foo or
+// /apis/kubedb.com/v1alpha1/namespaces/default/postgreses
+func parsePath(path string) (*request, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	req := &request{}
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		req.gvr.Version, parts = parts[1], parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		req.gvr.Group, req.gvr.Version, parts = parts[1], parts[2], parts[3:]
+	default:
+		return nil, false
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		req.namespace, parts = parts[1], parts[2:]
+	}
+	switch len(parts) {
+	case 1:
+		req.gvr.Resource = parts[0]
+	case 2:
+		req.gvr.Resource, req.name = parts[0], parts[1]
+	default:
+		return nil, false
+	}
+	return req, true
+}
+
+func (s *APIServer) serve(w http.ResponseWriter, r *http.Request) {
+	req, ok := parsePath(r.URL.Path)
+	if !ok {
+		s.writeError(w, kerr.NewNotFound(schema.GroupResource{}, r.URL.Path))
+		return
+	}
+	gvk, found := s.resources[req.gvr]
+	if !found {
+		s.writeError(w, kerr.NewNotFound(req.gvr.GroupResource(), req.name))
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	verb := requestVerb(r.Method, req.name)
+	if rules, restricted := s.rules[r.Header.Get("Impersonate-User")]; restricted && !allowed(rules, verb, req.gvr) {
+		resource := req.gvr.GroupResource()
+		s.forbidden = append(s.forbidden, verb+" "+resource.String())
+		s.writeError(w, kerr.NewForbidden(req.gvr.GroupResource(), req.name, errors.New("not allowed by RBAC")))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && req.gvr.GroupResource() == authorization.Resource("subjectaccessreviews"):
+		s.subjectAccessReview(w, r, gvk)
+	case r.Method == http.MethodGet && req.name == "":
+		s.list(w, r, req, gvk)
+	case r.Method == http.MethodGet:
+		obj, err := s.tracker.Get(req.gvr, req.namespace, req.name)
+		s.write(w, http.StatusOK, obj, gvk, err)
+	case r.Method == http.MethodPost:
+		obj, err := s.decode(r, gvk)
+		if err == nil {
+			err = s.create(req, obj)
+		}
+		s.write(w, http.StatusCreated, obj, gvk, err)
+	case r.Method == http.MethodPut:
+		obj, err := s.decode(r, gvk)
+		if err == nil {
+			err = s.update(req, obj)
+		}
+		s.write(w, http.StatusOK, obj, gvk, err)
+	case r.Method == http.MethodDelete:
+		if _, err := s.tracker.Get(req.gvr, req.namespace, req.name); err != nil {
+			s.writeError(w, err)
+			return
+		}
+		err := s.tracker.Delete(req.gvr, req.namespace, req.name)
+		s.write(w, http.StatusOK, &metav1.Status{Status: metav1.StatusSuccess}, metav1.SchemeGroupVersion.WithKind("Status"), err)
+	default:
+		s.writeError(w, kerr.NewMethodNotSupported(req.gvr.GroupResource(), r.Method))
+	}
+}
+
+// requestVerb returns the RBAC verb of a request with method for the object name.
+func requestVerb(method, name string) string {
+	switch method {
+	case http.MethodGet:
+		if name == "" {
+			return "list"
+		}
+		return "get"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return strings.ToLower(method)
+}
+
+// subjectAccessReview authorizes the user of a SubjectAccessReview. Unlike the requests made to the stand-in,
+// the reviews of users that are not restricted are denied. Non-resource URLs are authorized against the discovery
+// rules, which every user has.
+func (s *APIServer) subjectAccessReview(w http.ResponseWriter, r *http.Request, gvk schema.GroupVersionKind) {
+	obj, err := s.decode(r, gvk)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	review, ok := obj.(*authorization.SubjectAccessReview)
+	if !ok {
+		s.writeError(w, kerr.NewBadRequest("unsupported version of SubjectAccessReview "+gvk.Version))
+		return
+	}
+	if attrs := review.Spec.ResourceAttributes; attrs != nil {
+		resource := attrs.Resource
+		if attrs.Subresource != "" {
+			resource += "/" + attrs.Subresource
+		}
+		rules, restricted := s.rules[review.Spec.User]
+		review.Status.Allowed = restricted && allowed(rules, attrs.Verb, schema.GroupVersionResource{Group: attrs.Group, Resource: resource})
+	} else if attrs := review.Spec.NonResourceAttributes; attrs != nil {
+		review.Status.Allowed = allowedPath(discoveryRules, attrs.Verb, attrs.Path)
+	}
+	s.write(w, http.StatusCreated, review, gvk, nil)
+}
+
+func (s *APIServer) list(w http.ResponseWriter, r *http.Request, req *request, gvk schema.GroupVersionKind) {
+	list, err := s.tracker.List(req.gvr, gvk, req.namespace)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		s.writeError(w, kerr.NewBadRequest(err.Error()))
+		return
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	var matched []runtime.Object
+	for _, item := range items {
+		if obj, err := meta.Accessor(item); err == nil && selector.Matches(labels.Set(obj.GetLabels())) {
+			matched = append(matched, item)
+		}
+	}
+	if err := meta.SetList(list, matched); err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.write(w, http.StatusOK, list, gvk.GroupVersion().WithKind(gvk.Kind+"List"), nil)
+}
+
+func (s *APIServer) create(req *request, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	accessor.SetNamespace(req.namespace)
+	accessor.SetCreationTimestamp(metav1.Now())
+	accessor.SetResourceVersion(s.nextResourceVersion())
+	return s.tracker.Create(req.gvr, obj, req.namespace)
+}
+
+func (s *APIServer) update(req *request, obj runtime.Object) error {
+	old, err := s.tracker.Get(req.gvr, req.namespace, req.name)
+	if err != nil {
+		return err
+	}
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return err
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if rv := objMeta.GetResourceVersion(); rv != "" && rv != oldMeta.GetResourceVersion() {
+		return kerr.NewConflict(req.gvr.GroupResource(), req.name,
+			errors.New("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+	objMeta.SetCreationTimestamp(oldMeta.GetCreationTimestamp())
+	objMeta.SetResourceVersion(s.nextResourceVersion())
+	return s.tracker.Update(req.gvr, obj, req.namespace)
+}
+
+func (s *APIServer) nextResourceVersion() string {
+	s.resourceVersion++
+	return strconv.Itoa(s.resourceVersion)
+}
+
+func (s *APIServer) decode(r *http.Request, gvk schema.GroupVersionKind) (runtime.Object, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, kerr.NewBadRequest(err.Error())
+	}
+	obj, err := s.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, kerr.NewBadRequest(err.Error())
+	}
+	return obj, nil
+}
+
+func (s *APIServer) write(w http.ResponseWriter, code int, obj runtime.Object, gvk schema.GroupVersionKind, err error) {
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	data, err := json.Marshal(obj)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func (s *APIServer) writeError(w http.ResponseWriter, err error) {
+	status := kerr.NewInternalError(err).Status()
+	if e, ok := err.(kerr.APIStatus); ok {
+		status = e.Status()
+	}
+	status.Kind = "Status"
+	status.APIVersion = "v1"
+	data, _ := json.Marshal(status)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status.Code))
+	w.Write(data)
+}
@@ -0,0 +1,84 @@
+package framework
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/cert"
+)
+
+// frontProxyUser is the common name of the client certificate the front proxy of kube-apiserver presents to
+// aggregated apiservers.
+const frontProxyUser = "front-proxy-client"
+
+// frontProxy stands in for the front proxy of kube-apiserver. kube-apiserver forwards requests to aggregated
+// apiservers with a client certificate signed by the requestheader CA, and names the user in X-Remote-* headers.
+type frontProxy struct {
+	clientCA        []byte
+	requestHeaderCA []byte
+	cert            tls.Certificate
+}
+
+func newFrontProxy() (*frontProxy, error) {
+	// the client CA signs nothing, but the delegated authentication of kubedb-server requires one
+	clientCA, _, err := newCA("client-ca")
+	if err != nil {
+		return nil, err
+	}
+	requestHeaderCA, requestHeaderKey, err := newCA("front-proxy-ca")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cert.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	crt, err := cert.NewSignedCert(cert.Config{
+		CommonName: frontProxyUser,
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, key, requestHeaderCA, requestHeaderKey)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(cert.EncodeCertPEM(crt), cert.EncodePrivateKeyPEM(key))
+	if err != nil {
+		return nil, err
+	}
+	return &frontProxy{
+		clientCA:        cert.EncodeCertPEM(clientCA),
+		requestHeaderCA: cert.EncodeCertPEM(requestHeaderCA),
+		cert:            pair,
+	}, nil
+}
+
+func newCA(name string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	key, err := cert.NewPrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := cert.NewSelfSignedCACert(cert.Config{CommonName: name}, key)
+	return crt, key, err
+}
+
+// authenticationConfigMap returns the ConfigMap that kube-apiserver publishes for the delegated authentication of
+// aggregated apiservers.
+func (p *frontProxy) authenticationConfigMap() *core.ConfigMap {
+	return &core.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "extension-apiserver-authentication",
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: map[string]string{
+			"client-ca-file":                     string(p.clientCA),
+			"requestheader-client-ca-file":       string(p.requestHeaderCA),
+			"requestheader-username-headers":     `["X-Remote-User"]`,
+			"requestheader-group-headers":        `["X-Remote-Group"]`,
+			"requestheader-extra-headers-prefix": `["X-Remote-Extra-"]`,
+			"requestheader-allowed-names":        `["` + frontProxyUser + `"]`,
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package framework
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	hookapi "github.com/appscode/kubernetes-webhook-util/admission/v1beta1"
+	cs "github.com/kubedb/apimachinery/client/clientset/versioned"
+	"github.com/kubedb/kubedb-server/pkg/admission/config"
+	"github.com/kubedb/kubedb-server/pkg/cmds/server"
+	"github.com/pkg/errors"
+	admission "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// serverUser is the user kubedb-server acts as towards the stand-in kube-apiserver.
+const serverUser = "system:serviceaccount:kube-system:kubedb-server"
+
+// RequesterUser is the user admission reviews are sent as. It is granted the kubedb:server-requester ClusterRole.
+const RequesterUser = "kube-apiserver"
+
+// Framework runs kubedb-server in-process, on a random port with self-signed certificates. The admission
+// hooks talk to a stand-in kube-apiserver and the bucket access checks to a stand-in object store. kubedb-server
+// delegates authentication and authorization to the stand-in kube-apiserver, and the admission reviews are sent
+// through a stand-in of its front proxy.
+type Framework struct {
+	APIServer   *APIServer
+	ObjectStore *ObjectStore
+	KubeClient  kubernetes.Interface
+	ExtClient   cs.Interface
+
+	dir        string
+	serverURL  string
+	frontProxy *frontProxy
+	httpClient *http.Client
+	stopCh     chan struct{}
+}
+
+// New starts kubedb-server with the given admission config and hooks, and waits until the hooks are initialized.
+func New(admissionConfig *config.Config, admissionHooks ...hookapi.AdmissionHook) (*Framework, error) {
+	dir, err := ioutil.TempDir("", "kubedb-server-e2e")
+	if err != nil {
+		return nil, err
+	}
+	f := &Framework{
+		APIServer:   NewAPIServer(),
+		ObjectStore: NewObjectStore(),
+		dir:         dir,
+		stopCh:      make(chan struct{}),
+	}
+	if err := f.start(admissionConfig, admissionHooks); err != nil {
+		f.Stop()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Framework) start(admissionConfig *config.Config, admissionHooks []hookapi.AdmissionHook) error {
+	// the admission hooks get the permissions kubedb-server is deployed with, the test clients get all
+	rules, err := loadClusterRole(rbacManifest(), serverRole)
+	if err != nil {
+		return err
+	}
+	f.APIServer.Restrict(serverUser, append(rules, authDelegatorRules...))
+	requesterRules, err := loadClusterRole(rbacManifest(), requesterRole)
+	if err != nil {
+		return err
+	}
+	f.APIServer.Restrict(RequesterUser, requesterRules)
+
+	config := &rest.Config{Host: f.APIServer.URL}
+	if f.KubeClient, err = kubernetes.NewForConfig(config); err != nil {
+		return err
+	}
+	if f.ExtClient, err = cs.NewForConfig(config); err != nil {
+		return err
+	}
+
+	if f.frontProxy, err = newFrontProxy(); err != nil {
+		return err
+	}
+	if _, err := f.KubeClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Create(f.frontProxy.authenticationConfigMap()); err != nil {
+		return err
+	}
+
+	kubeconfig := filepath.Join(f.dir, "kubeconfig")
+	if err := clientcmd.WriteToFile(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"e2e": {Server: f.APIServer.URL}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"e2e": {Impersonate: serverUser}},
+		Contexts:       map[string]*clientcmdapi.Context{"e2e": {Cluster: "e2e", AuthInfo: "e2e"}},
+		CurrentContext: "e2e",
+	}, kubeconfig); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	o := server.NewAdmissionServerOptions(ioutil.Discard, ioutil.Discard, admissionConfig, admissionHooks...)
+	o.RecommendedOptions.SecureServing.Listener = listener
+	o.RecommendedOptions.SecureServing.BindAddress = net.ParseIP("127.0.0.1")
+	o.RecommendedOptions.SecureServing.BindPort = listener.Addr().(*net.TCPAddr).Port
+	o.RecommendedOptions.SecureServing.ServerCert.CertDirectory = f.dir
+	o.RecommendedOptions.CoreAPI.CoreAPIKubeconfigPath = kubeconfig
+	o.RecommendedOptions.Authentication.RemoteKubeConfigFile = kubeconfig
+	o.RecommendedOptions.Authorization.RemoteKubeConfigFile = kubeconfig
+	f.serverURL = "https://" + listener.Addr().String()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- o.RunAdmissionServer(f.stopCh)
+	}()
+
+	// wait until the server is serving and the post-start hooks have initialized the admission hooks. The health
+	// is checked without credentials, as anonymous.
+	var healthClient *http.Client
+	return wait.PollImmediate(100*time.Millisecond, time.Minute, func() (bool, error) {
+		select {
+		case err := <-errCh:
+			return false, errors.Wrap(err, "kubedb-server stopped")
+		default:
+		}
+		if f.httpClient == nil {
+			caCert, err := ioutil.ReadFile(filepath.Join(f.dir, o.RecommendedOptions.SecureServing.ServerCert.PairName+".crt"))
+			if err != nil {
+				return false, nil
+			}
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(caCert)
+			healthClient = &http.Client{
+				Timeout:   time.Minute,
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+			}
+			f.httpClient = &http.Client{
+				Timeout:   time.Minute,
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{f.frontProxy.cert}}},
+			}
+		}
+		resp, err := healthClient.Get(f.serverURL + "/healthz")
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+}
+
+// Stop shuts down kubedb-server and the stand-in servers.
+func (f *Framework) Stop() {
+	close(f.stopCh)
+	f.APIServer.Close()
+	f.ObjectStore.Close()
+	os.RemoveAll(f.dir)
+}
+
+// Review posts an AdmissionReview for the request to /apis/admission.kubedb.com/v1alpha1/<resource>
+// as RequesterUser and returns the response of kubedb-server.
+func (f *Framework) Review(resource string, req *admission.AdmissionRequest) (*admission.AdmissionResponse, error) {
+	return f.ReviewAs(RequesterUser, resource, req)
+}
+
+// ReviewAs posts an AdmissionReview for the request as user, through the front proxy, and returns the response of
+// kubedb-server.
+func (f *Framework) ReviewAs(user, resource string, req *admission.AdmissionRequest) (*admission.AdmissionResponse, error) {
+	if req.UID == "" {
+		req.UID = uuid.NewUUID()
+	}
+	review := &admission.AdmissionReview{Request: req}
+	review.APIVersion = admission.SchemeGroupVersion.String()
+	review.Kind = "AdmissionReview"
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequest(http.MethodPost, f.serverURL+"/apis/admission.kubedb.com/v1alpha1/"+resource, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Remote-User", user)
+	r.Header.Set("X-Remote-Group", "system:authenticated")
+	resp, err := f.httpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected response from kubedb-server, status: %v, body: %s", resp.Status, data)
+	}
+	result := &admission.AdmissionReview{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	if result.Response == nil {
+		return nil, fmt.Errorf("kubedb-server returned no response: %s", data)
+	}
+	return result.Response, nil
+}
@@ -0,0 +1,172 @@
+package framework
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectStore is a stand-in for an S3 compatible object store, good enough for the bucket access checks.
+// Buckets only accept requests made with their access key. Signatures are not verified.
+type ObjectStore struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	buckets  map[string]*bucket
+	delay    time.Duration
+	requests map[string]int
+}
+
+type bucket struct {
+	accessKey string
+	objects   map[string][]byte
+}
+
+var credentialRegexp = regexp.MustCompile(`Credential=([^/]+)/`)
+
+func NewObjectStore() *ObjectStore {
+	s := &ObjectStore{
+		buckets:  map[string]*bucket{},
+		requests: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// CreateBucket creates a bucket which can be accessed with the given access key.
+func (s *ObjectStore) CreateBucket(name, accessKey string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.buckets[name] = &bucket{accessKey: accessKey, objects: map[string][]byte{}}
+}
+
+// SetDelay makes the object store wait before answering requests, to simulate a slow endpoint.
+func (s *ObjectStore) SetDelay(delay time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.delay = delay
+}
+
+// Requests returns the number of requests made with the given http method.
+func (s *ObjectStore) Requests(method string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[method]
+}
+
+// Objects returns the keys of the objects stored in a bucket.
+func (s *ObjectStore) Objects(name string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var keys []string
+	if b, found := s.buckets[name]; found {
+		for key := range b.objects {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *ObjectStore) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests[r.Method]++
+	delay := s.delay
+	s.lock.Unlock()
+	time.Sleep(delay)
+
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	name, key := path[0], ""
+	if len(path) == 2 {
+		key = path[1]
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, found := s.buckets[name]
+	if !found {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", fmt.Sprintf("bucket %v does not exist", name))
+		return
+	}
+	if match := credentialRegexp.FindStringSubmatch(r.Header.Get("Authorization")); match == nil || match[1] != b.accessKey {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("location") != "":
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+		}{})
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		result := listBucketResult{Name: name, Prefix: prefix}
+		for k, v := range b.objects {
+			if strings.HasPrefix(k, prefix) {
+				result.Contents = append(result.Contents, object{Key: k, ETag: `"etag"`, Size: len(v), LastModified: time.Now().UTC()})
+			}
+		}
+		writeXML(w, result)
+	case r.Method == http.MethodPut && key != "":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		b.objects[key] = data
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete && key != "":
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", fmt.Sprintf("%v %v is not implemented", r.Method, r.URL))
+	}
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name        string
+	Prefix      string
+	IsTruncated bool
+	Contents    []object
+}
+
+type object struct {
+	Key          string
+	ETag         string
+	Size         int
+	LastModified time.Time
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		writeS3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+func writeS3Error(w http.ResponseWriter, code int, errCode, message string) {
+	data, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: errCode, Message: message})
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
@@ -0,0 +1,99 @@
+package framework
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// serverRole is the ClusterRole of kubedb-server in hack/deploy/rbac-list.yaml. The stand-in kube-apiserver
+// enforces it on the requests of the admission hooks, so that a hook needing a permission the deployment
+// doesn't grant fails the e2e tests instead of a real cluster.
+const serverRole = "kubedb:server"
+
+// requesterRole is the ClusterRole in hack/deploy/rbac-list.yaml that lets admission reviews in. The stand-in
+// kube-apiserver grants it to the user the reviews are sent as, and authorizes them when kubedb-server asks.
+const requesterRole = "kubedb:server-requester"
+
+// authDelegatorRules are the rules of system:auth-delegator, the ClusterRole of Kubernetes that
+// hack/deploy/rbac-list.yaml binds to kubedb-server to delegate authentication and authorization.
+var authDelegatorRules = []rbac.PolicyRule{
+	{APIGroups: []string{"authentication.k8s.io"}, Resources: []string{"tokenreviews"}, Verbs: []string{"create"}},
+	{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"subjectaccessreviews"}, Verbs: []string{"create"}},
+}
+
+// discoveryRules are the rules of system:discovery, the ClusterRole of Kubernetes that every user has. It lets
+// the health of kubedb-server be checked without credentials.
+var discoveryRules = []rbac.PolicyRule{
+	{NonResourceURLs: []string{"/healthz", "/version", "/version/", "/api", "/api/*", "/apis", "/apis/*"}, Verbs: []string{"get"}},
+}
+
+// loadClusterRole reads the rules of the ClusterRole name from the manifests in path.
+func loadClusterRole(path, name string) ([]rbac.PolicyRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		role := rbac.ClusterRole{}
+		if err := decoder.Decode(&role); err == io.EOF {
+			return nil, errors.Errorf(`ClusterRole "%s" not found in %s`, name, path)
+		} else if err != nil {
+			return nil, err
+		}
+		if role.Kind == "ClusterRole" && role.Name == name {
+			return role.Rules, nil
+		}
+	}
+}
+
+// rbacManifest returns the path of hack/deploy/rbac-list.yaml.
+func rbacManifest() string {
+	_, file, _, _ := goruntime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "hack", "deploy", "rbac-list.yaml")
+}
+
+// allowed reports whether rules grant verb on the resource gvr.
+func allowed(rules []rbac.PolicyRule, verb string, gvr schema.GroupVersionResource) bool {
+	for _, rule := range rules {
+		if matches(rule.Verbs, verb) && matches(rule.APIGroups, gvr.Group) && matches(rule.Resources, gvr.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedPath reports whether rules grant verb on the non-resource URL path.
+func allowedPath(rules []rbac.PolicyRule, verb, path string) bool {
+	for _, rule := range rules {
+		if !matches(rule.Verbs, verb) {
+			continue
+		}
+		for _, url := range rule.NonResourceURLs {
+			if url == rbac.NonResourceAll || url == path ||
+				(strings.HasSuffix(url, "*") && strings.HasPrefix(path, strings.TrimSuffix(url, "*"))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbac.VerbAll || v == value {
+			return true
+		}
+	}
+	return false
+}